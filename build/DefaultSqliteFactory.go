@@ -4,15 +4,20 @@ import (
 	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
 	cbuild "github.com/pip-services3-go/pip-services3-components-go/build"
 	sliteconn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+	slitepersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
 )
 
 // Creates Sqlite components by their descriptors.
 // See [[Factory]]
 // See [[SqliteConnection]]
+// See [[SqliteChangeWatcher]]
+// See [[SqliteOutbox]]
 type DefaultSqliteFactory struct {
 	cbuild.Factory
-	Descriptor                 *cref.Descriptor
-	SqliteConnectionDescriptor *cref.Descriptor
+	Descriptor                    *cref.Descriptor
+	SqliteConnectionDescriptor    *cref.Descriptor
+	SqliteChangeWatcherDescriptor *cref.Descriptor
+	SqliteOutboxDescriptor        *cref.Descriptor
 }
 
 //	Create a new instance of the factory.
@@ -20,9 +25,13 @@ func NewDefaultSqliteFactory() *DefaultSqliteFactory {
 
 	c := &DefaultSqliteFactory{
 
-		Descriptor:                 cref.NewDescriptor("pip-services", "factory", "sqlite", "default", "1.0"),
-		SqliteConnectionDescriptor: cref.NewDescriptor("pip-services", "connection", "sqlite", "*", "1.0"),
+		Descriptor:                    cref.NewDescriptor("pip-services", "factory", "sqlite", "default", "1.0"),
+		SqliteConnectionDescriptor:    cref.NewDescriptor("pip-services", "connection", "sqlite", "*", "1.0"),
+		SqliteChangeWatcherDescriptor: cref.NewDescriptor("pip-services", "change-watcher", "sqlite", "*", "1.0"),
+		SqliteOutboxDescriptor:        cref.NewDescriptor("pip-services", "outbox", "sqlite", "*", "1.0"),
 	}
 	c.RegisterType(c.SqliteConnectionDescriptor, sliteconn.NewSqliteConnection)
+	c.RegisterType(c.SqliteChangeWatcherDescriptor, sliteconn.NewSqliteChangeWatcher)
+	c.RegisterType(c.SqliteOutboxDescriptor, slitepersist.NewSqliteOutbox)
 	return c
 }
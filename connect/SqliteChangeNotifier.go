@@ -0,0 +1,421 @@
+package connect
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Operations of data changes reported by SQLite.
+const (
+	SqliteChangeInsert = "insert"
+	SqliteChangeUpdate = "update"
+	SqliteChangeDelete = "delete"
+)
+
+// Describes a committed change of a table row.
+type SqliteChangeEvent struct {
+	// The change operation: insert, update or delete.
+	Operation string
+	// The database name, "main" for the main database.
+	Database string
+	// The table name.
+	Table string
+	// The rowid of the changed row.
+	RowId int64
+}
+
+// Callback function that receives committed changes.
+type SqliteChangeListener func(event *SqliteChangeEvent)
+
+// Subscription to changes of a table.
+type SqliteChangeSubscription struct {
+	notifier  *sqliteChangeNotifier
+	table     string
+	operation string
+	listener  SqliteChangeListener
+}
+
+// Cancels the subscription. Events that are already being delivered can still reach the listener.
+func (c *SqliteChangeSubscription) Unsubscribe() {
+	c.notifier.unsubscribe(c)
+}
+
+// Checks if an event matches the subscription.
+func (c *SqliteChangeSubscription) matches(event *SqliteChangeEvent) bool {
+	return (c.table == "" || c.table == event.Table) &&
+		(c.operation == "" || c.operation == event.Operation)
+}
+
+/*
+Collects changes reported by update hooks of SQLite connections and delivers
+them to subscribers after the changes are committed.
+
+Changes are buffered per connection until the transaction ends.
+Changes of failed statements and of savepoints undone by ROLLBACK TO are dropped
+as soon as the statement completes. On commit the changes are queued for delivery
+after COMMIT succeeds, on rollback they are dropped.
+Savepoints are tracked when SAVEPOINT, RELEASE and ROLLBACK TO are executed
+as separate statements. When a script of several statements fails,
+changes made by all its statements are dropped.
+Events are delivered in commit order by a single goroutine,
+so listeners can safely access the database.
+*/
+type sqliteChangeNotifier struct {
+	lock          sync.Mutex
+	subscriptions []*SqliteChangeSubscription
+	queue         []*SqliteChangeEvent
+	signal        chan struct{}
+	done          chan struct{}
+}
+
+func newSqliteChangeNotifier() *sqliteChangeNotifier {
+	return &sqliteChangeNotifier{
+		subscriptions: make([]*SqliteChangeSubscription, 0),
+		queue:         make([]*SqliteChangeEvent, 0),
+		signal:        make(chan struct{}, 1),
+	}
+}
+
+// Starts delivery of events.
+func (c *sqliteChangeNotifier) start() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.done == nil {
+		c.done = make(chan struct{})
+		go c.dispatch(c.done)
+	}
+}
+
+// Stops delivery of events. Queued events are dropped, subscriptions are kept.
+func (c *sqliteChangeNotifier) stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+		c.queue = make([]*SqliteChangeEvent, 0)
+	}
+}
+
+// Registers update, commit and rollback hooks on a new driver connection
+// and wraps it to learn which statements complete.
+func (c *sqliteChangeNotifier) wrapConnection(conn *sqlite3.SQLiteConn) *sqliteNotifyingConn {
+	changes := &sqliteConnChanges{notifier: c}
+
+	conn.RegisterUpdateHook(func(op int, database string, table string, rowId int64) {
+		operation := SqliteChangeUpdate
+		switch op {
+		case sqlite3.SQLITE_INSERT:
+			operation = SqliteChangeInsert
+		case sqlite3.SQLITE_DELETE:
+			operation = SqliteChangeDelete
+		}
+		changes.statement = append(changes.statement, &SqliteChangeEvent{
+			Operation: operation,
+			Database:  database,
+			Table:     table,
+			RowId:     rowId,
+		})
+	})
+	conn.RegisterCommitHook(func() int {
+		changes.commit()
+		return 0
+	})
+	conn.RegisterRollbackHook(func() {
+		changes.rollback()
+	})
+	return &sqliteNotifyingConn{SQLiteConn: conn, changes: changes}
+}
+
+func (c *sqliteChangeNotifier) subscribe(table string, operation string, listener SqliteChangeListener) *SqliteChangeSubscription {
+	subscription := &SqliteChangeSubscription{
+		notifier:  c,
+		table:     table,
+		operation: operation,
+		listener:  listener,
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.subscriptions = append(c.subscriptions, subscription)
+	return subscription
+}
+
+func (c *sqliteChangeNotifier) unsubscribe(subscription *SqliteChangeSubscription) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for index, existing := range c.subscriptions {
+		if existing == subscription {
+			c.subscriptions = append(c.subscriptions[:index:index], c.subscriptions[index+1:]...)
+			return
+		}
+	}
+}
+
+// Queues committed events without blocking the committing connection.
+func (c *sqliteChangeNotifier) enqueue(events []*SqliteChangeEvent) {
+	c.lock.Lock()
+	c.queue = append(c.queue, events...)
+	c.lock.Unlock()
+
+	select {
+	case c.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (c *sqliteChangeNotifier) dispatch(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-c.signal:
+		}
+
+		c.lock.Lock()
+		events := c.queue
+		c.queue = make([]*SqliteChangeEvent, 0)
+		subscriptions := c.subscriptions
+		c.lock.Unlock()
+
+		for _, event := range events {
+			for _, subscription := range subscriptions {
+				if subscription.matches(event) {
+					subscription.listener(event)
+				}
+			}
+		}
+	}
+}
+
+/*
+Changes of one connection kept until they are durably committed.
+Hooks and statements of one connection are called sequentially by the goroutine that uses it.
+*/
+type sqliteConnChanges struct {
+	notifier *sqliteChangeNotifier
+	// Changes made by the running statement.
+	statement []*SqliteChangeEvent
+	// Changes of completed statements in the open transaction.
+	transaction []*SqliteChangeEvent
+	// Savepoints of the open transaction.
+	savepoints []*sqliteSavepoint
+	// Changes of the transaction being committed.
+	committed []*SqliteChangeEvent
+}
+
+// Savepoint with the number of transaction changes made before it.
+type sqliteSavepoint struct {
+	name    string
+	changes int
+}
+
+// Called by the commit hook before the commit is durable.
+func (c *sqliteConnChanges) commit() {
+	c.committed = append(c.transaction, c.statement...)
+	c.transaction = nil
+	c.statement = nil
+	c.savepoints = nil
+}
+
+// Called by the rollback hook and when a transaction ends without commit.
+func (c *sqliteConnChanges) rollback() {
+	c.statement = nil
+	c.transaction = nil
+	c.savepoints = nil
+	c.committed = nil
+}
+
+// Completes a statement: keeps or drops its changes and delivers committed ones.
+//   - query     the statement text.
+//   - err       an error of the statement or nil when it succeeded.
+func (c *sqliteConnChanges) complete(query string, err error) {
+	if err != nil {
+		// Changes of the failed statement are undone by SQLite
+		c.statement = nil
+		// Failed COMMIT keeps the transaction open
+		if c.committed != nil {
+			c.transaction = c.committed
+			c.committed = nil
+		}
+		return
+	}
+
+	c.transaction = append(c.transaction, c.statement...)
+	c.statement = nil
+	c.applySavepoint(query)
+	if len(c.committed) > 0 {
+		c.notifier.enqueue(c.committed)
+	}
+	c.committed = nil
+}
+
+// Tracks SAVEPOINT, RELEASE and ROLLBACK TO statements.
+func (c *sqliteConnChanges) applySavepoint(query string) {
+	if index := strings.IndexByte(query, ';'); index >= 0 {
+		query = query[:index]
+	}
+	words := strings.Fields(strings.ToUpper(query))
+	if len(words) < 2 {
+		return
+	}
+	name := strings.Trim(words[len(words)-1], "\"'`[]")
+
+	switch {
+	case words[0] == "SAVEPOINT":
+		c.savepoints = append(c.savepoints, &sqliteSavepoint{name: name, changes: len(c.transaction)})
+	case words[0] == "RELEASE":
+		if index := c.findSavepoint(name); index >= 0 {
+			c.savepoints = c.savepoints[:index]
+		}
+	case words[0] == "ROLLBACK" && (words[1] == "TO" || len(words) > 2 && words[2] == "TO"):
+		// The savepoint stays open after ROLLBACK TO
+		if index := c.findSavepoint(name); index >= 0 {
+			c.transaction = c.transaction[:c.savepoints[index].changes]
+			c.savepoints = c.savepoints[:index+1]
+		}
+	}
+}
+
+// Finds the most recent savepoint with a given name or returns -1.
+func (c *sqliteConnChanges) findSavepoint(name string) int {
+	for index := len(c.savepoints) - 1; index >= 0; index-- {
+		if c.savepoints[index].name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// Driver connection that reports completed statements to its changes.
+type sqliteNotifyingConn struct {
+	*sqlite3.SQLiteConn
+	changes *sqliteConnChanges
+}
+
+func (c *sqliteNotifyingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	c.changes.complete(query, err)
+	return result, err
+}
+
+func (c *sqliteNotifyingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	return c.changes.wrapRows(query, rows, err)
+}
+
+func (c *sqliteNotifyingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if sqliteStmt, ok := stmt.(*sqlite3.SQLiteStmt); ok && err == nil {
+		return &sqliteNotifyingStmt{SQLiteStmt: sqliteStmt, changes: c.changes, query: query}, nil
+	}
+	return stmt, err
+}
+
+func (c *sqliteNotifyingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.SQLiteConn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteNotifyingTx{Tx: tx, changes: c.changes}, nil
+}
+
+// Prepared statement that reports its executions to changes of the connection.
+type sqliteNotifyingStmt struct {
+	*sqlite3.SQLiteStmt
+	changes *sqliteConnChanges
+	query   string
+}
+
+func (c *sqliteNotifyingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.SQLiteStmt.ExecContext(ctx, args)
+	c.changes.complete(c.query, err)
+	return result, err
+}
+
+func (c *sqliteNotifyingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.SQLiteStmt.QueryContext(ctx, args)
+	return c.changes.wrapRows(c.query, rows, err)
+}
+
+// Wraps rows of a query, so the statement completes when they are closed.
+func (c *sqliteConnChanges) wrapRows(query string, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		c.complete(query, err)
+		return nil, err
+	}
+	if sqliteRows, ok := rows.(*sqlite3.SQLiteRows); ok {
+		return &sqliteNotifyingRows{SQLiteRows: sqliteRows, changes: c, query: query}, nil
+	}
+	c.complete(query, nil)
+	return rows, nil
+}
+
+// Query rows that complete the statement when they are closed.
+type sqliteNotifyingRows struct {
+	*sqlite3.SQLiteRows
+	changes *sqliteConnChanges
+	query   string
+	err     error
+}
+
+func (c *sqliteNotifyingRows) Next(dest []driver.Value) error {
+	err := c.SQLiteRows.Next(dest)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return err
+}
+
+func (c *sqliteNotifyingRows) Close() error {
+	err := c.SQLiteRows.Close()
+	c.changes.complete(c.query, c.err)
+	return err
+}
+
+// Transaction that delivers its changes only after a successful commit.
+type sqliteNotifyingTx struct {
+	driver.Tx
+	changes *sqliteConnChanges
+}
+
+func (c *sqliteNotifyingTx) Commit() error {
+	err := c.Tx.Commit()
+	if err != nil {
+		// The driver rolls back transactions that failed to commit
+		c.changes.rollback()
+		return err
+	}
+	c.changes.complete("", nil)
+	return nil
+}
+
+func (c *sqliteNotifyingTx) Rollback() error {
+	err := c.Tx.Rollback()
+	c.changes.rollback()
+	return err
+}
+
+// Opens driver connections with hooks of the notifier.
+type sqliteHookConnector struct {
+	driver   *sqlite3.SQLiteDriver
+	notifier *sqliteChangeNotifier
+	dsn      string
+}
+
+func (c *sqliteHookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.notifier.wrapConnection(conn.(*sqlite3.SQLiteConn)), nil
+}
+
+func (c *sqliteHookConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,400 @@
+package connect
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+)
+
+// Describes a change of a table detected by SqliteChangeWatcher.
+type SqliteTableChangeEvent struct {
+	// The changed table or "" when the watcher doesn't track tables and the whole database changed.
+	Table string
+	// The change counter of the table or the data version of the database.
+	Version int64
+}
+
+// Callback function that receives detected changes of tables.
+type SqliteTableChangeListener func(event *SqliteTableChangeEvent)
+
+// Subscription to changes detected by SqliteChangeWatcher.
+type SqliteTableChangeSubscription struct {
+	watcher  *SqliteChangeWatcher
+	table    string
+	listener SqliteTableChangeListener
+}
+
+// Cancels the subscription.
+func (c *SqliteTableChangeSubscription) Unsubscribe() {
+	c.watcher.unsubscribe(c)
+}
+
+/*
+Detects changes committed to a SQLite database by other connections and processes.
+
+Unlike update hooks, which only see writes of the current process, the watcher
+polls PRAGMA data_version on a dedicated connection it opens to the same database,
+so it doesn't take connections from the pool shared with persistences. When tables are configured,
+it also keeps per-table change counters updated by triggers, so it can tell
+which tables were changed. Otherwise it reports that the whole database changed.
+
+### Configuration parameters ###
+
+- connection(s):
+  - discovery_key:             (optional) a key to retrieve the connection from [[IDiscovery]]
+  - database:                  path to database file
+
+- options:
+  - interval:                  (optional) polling interval in milliseconds (default: 1000)
+  - tables:                    (optional) comma-separated list of tables to track
+  - counters_table:            (optional) name of the table with change counters (default: change_counters)
+
+### References ###
+
+- \*:logger:\*:\*:1.0           (optional) [[ILogger]] components to pass log messages
+- \*:connection:sqlite:\*:1.0   (optional) shared SqliteConnection, a local one is created when missing
+
+### Example ###
+
+	watcher := connect.NewSqliteChangeWatcher()
+	watcher.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", "./data/app.db",
+		"options.tables", "orders,customers",
+	))
+	watcher.Subscribe("orders", func(event *connect.SqliteTableChangeEvent) {
+		cache.Invalidate()
+	})
+	watcher.Open("123")
+*/
+type SqliteChangeWatcher struct {
+	defaultConfig *cconf.ConfigParams
+	config        *cconf.ConfigParams
+	references    cref.IReferences
+	// The dependency resolver.
+	DependencyResolver *cref.DependencyResolver
+	// The logger.
+	Logger *clog.CompositeLogger
+	// The SQLite connection to watch.
+	Connection *SqliteConnection
+	// The polling interval in milliseconds.
+	Interval int
+	// The tables to track by change counters.
+	Tables []string
+	// The name of the table with change counters.
+	CountersTableName string
+
+	localConnection bool
+	lock            sync.Mutex
+	subscriptions   []*SqliteTableChangeSubscription
+	timer           *crun.FixedRateTimer
+	db              *sql.DB
+	conn            *sql.Conn
+	dataVersion     int64
+	counters        map[string]int64
+}
+
+// Creates a new instance of the watcher.
+func NewSqliteChangeWatcher() *SqliteChangeWatcher {
+	c := &SqliteChangeWatcher{
+		defaultConfig: cconf.NewConfigParamsFromTuples(
+			"dependencies.connection", "*:connection:sqlite:*:1.0",
+		),
+		Logger:            clog.NewCompositeLogger(),
+		Interval:          1000,
+		Tables:            make([]string, 0),
+		CountersTableName: "change_counters",
+		subscriptions:     make([]*SqliteTableChangeSubscription, 0),
+		counters:          map[string]int64{},
+	}
+	c.DependencyResolver = cref.NewDependencyResolver()
+	c.DependencyResolver.Configure(c.defaultConfig)
+	return c
+}
+
+// Configures component by passing configuration parameters.
+//   - config    configuration parameters to be set.
+func (c *SqliteChangeWatcher) Configure(config *cconf.ConfigParams) {
+	config = config.SetDefaults(c.defaultConfig)
+	c.config = config
+	c.DependencyResolver.Configure(config)
+
+	c.Interval = config.GetAsIntegerWithDefault("options.interval", c.Interval)
+	c.CountersTableName = config.GetAsStringWithDefault("options.counters_table", c.CountersTableName)
+	if tables := config.GetAsString("options.tables"); tables != "" {
+		c.Tables = make([]string, 0)
+		for _, table := range strings.Split(tables, ",") {
+			if table = strings.TrimSpace(table); table != "" {
+				c.Tables = append(c.Tables, table)
+			}
+		}
+	}
+}
+
+// Sets references to dependent components.
+//   - references 	references to locate the component dependencies.
+func (c *SqliteChangeWatcher) SetReferences(references cref.IReferences) {
+	c.references = references
+	c.Logger.SetReferences(references)
+
+	c.DependencyResolver.SetReferences(references)
+	if dep, ok := c.DependencyResolver.GetOneOptional("connection").(*SqliteConnection); ok {
+		c.Connection = dep
+		c.localConnection = false
+	}
+}
+
+// Checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *SqliteChangeWatcher) IsOpen() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.conn != nil
+}
+
+// Opens the component: installs change counters and starts polling.
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+//
+// Returns 			error or nil no errors occured.
+func (c *SqliteChangeWatcher) Open(correlationId string) (err error) {
+	if c.IsOpen() {
+		return nil
+	}
+
+	if c.Connection == nil {
+		c.Connection = NewSqliteConnection()
+		if c.config != nil {
+			c.Connection.Configure(c.config)
+		}
+		if c.references != nil {
+			c.Connection.SetReferences(c.references)
+		}
+		c.localConnection = true
+	}
+	if c.localConnection {
+		if err = c.Connection.Open(correlationId); err != nil {
+			return err
+		}
+	}
+	if !c.Connection.IsOpen() {
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "SQLite connection is not opened")
+	}
+
+	if err = c.installCounters(); err != nil {
+		c.closeConnection(correlationId)
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to install change counters").WithCause(err)
+	}
+
+	// data_version is reported per connection, so it's always read from the same one.
+	// The connection is opened separately, pinning one from the shared pool
+	// would block other queries when the pool is limited to a single connection
+	db, err := sql.Open("sqlite3", c.Connection.GetDatabaseName())
+	var conn *sql.Conn
+	if err == nil {
+		if conn, err = db.Conn(context.Background()); err != nil {
+			db.Close()
+		}
+	}
+	if err != nil {
+		c.closeConnection(correlationId)
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to open watching connection").WithCause(err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.db = db
+	c.conn = conn
+	if c.dataVersion, err = c.readDataVersion(); err == nil {
+		c.counters, err = c.readCounters()
+	}
+	if err != nil {
+		c.closeWatchingConnection()
+		c.closeConnection(correlationId)
+		return err
+	}
+
+	c.timer = crun.NewFixedRateTimerFromCallback(func() { c.poll(correlationId) }, c.Interval, c.Interval)
+	c.timer.Start()
+	c.Logger.Debug(correlationId, "Started watching sqlite database %s", c.Connection.GetDatabaseName())
+	return nil
+}
+
+// Closes component and stops polling.
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+//
+// Returns 			error or nil no errors occured.
+func (c *SqliteChangeWatcher) Close(correlationId string) (err error) {
+	c.lock.Lock()
+	if c.conn == nil {
+		c.lock.Unlock()
+		return nil
+	}
+	c.timer.Stop()
+	c.timer = nil
+	err = c.closeWatchingConnection()
+	c.lock.Unlock()
+
+	if closeErr := c.closeConnection(correlationId); err == nil {
+		err = closeErr
+	}
+	c.Logger.Debug(correlationId, "Stopped watching sqlite database")
+	return err
+}
+
+func (c *SqliteChangeWatcher) closeWatchingConnection() error {
+	err := c.conn.Close()
+	if dbErr := c.db.Close(); err == nil {
+		err = dbErr
+	}
+	c.conn = nil
+	c.db = nil
+	return err
+}
+
+func (c *SqliteChangeWatcher) closeConnection(correlationId string) error {
+	if c.localConnection && c.Connection != nil {
+		return c.Connection.Close(correlationId)
+	}
+	return nil
+}
+
+// Subscribes a listener to detected changes.
+// Listeners are called one by one from the polling goroutine.
+//   - table         	(optional) a table name, "" subscribes to all changes.
+//   - listener      	a function that receives changes.
+//
+// Returns a subscription.
+func (c *SqliteChangeWatcher) Subscribe(table string, listener SqliteTableChangeListener) *SqliteTableChangeSubscription {
+	subscription := &SqliteTableChangeSubscription{
+		watcher:  c,
+		table:    table,
+		listener: listener,
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.subscriptions = append(c.subscriptions, subscription)
+	return subscription
+}
+
+func (c *SqliteChangeWatcher) unsubscribe(subscription *SqliteTableChangeSubscription) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for index, existing := range c.subscriptions {
+		if existing == subscription {
+			c.subscriptions = append(c.subscriptions[:index:index], c.subscriptions[index+1:]...)
+			return
+		}
+	}
+}
+
+// Creates the change counters table and triggers that count changes of tracked tables.
+func (c *SqliteChangeWatcher) installCounters() error {
+	if len(c.Tables) == 0 {
+		return nil
+	}
+
+	counters := QuoteIdentifier(c.CountersTableName)
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + counters + " (\"table_name\" TEXT PRIMARY KEY, \"counter\" INTEGER NOT NULL)",
+	}
+	for _, table := range c.Tables {
+		name := strings.ReplaceAll(table, "'", "''")
+		statements = append(statements,
+			"INSERT OR IGNORE INTO "+counters+" (\"table_name\", \"counter\") VALUES ('"+name+"', 0)")
+		for _, operation := range []string{"INSERT", "UPDATE", "DELETE"} {
+			statements = append(statements, "CREATE TRIGGER IF NOT EXISTS "+
+				QuoteIdentifier(table+"_change_counter_"+strings.ToLower(operation))+
+				" AFTER "+operation+" ON "+QuoteIdentifier(table)+
+				" BEGIN UPDATE "+counters+" SET \"counter\"=\"counter\"+1 WHERE \"table_name\"='"+name+"'; END")
+		}
+	}
+
+	tx, err := c.Connection.GetConnection().Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (c *SqliteChangeWatcher) readDataVersion() (version int64, err error) {
+	err = c.conn.QueryRowContext(context.Background(), "PRAGMA data_version").Scan(&version)
+	return version, err
+}
+
+func (c *SqliteChangeWatcher) readCounters() (map[string]int64, error) {
+	counters := map[string]int64{}
+	if len(c.Tables) == 0 {
+		return counters, nil
+	}
+
+	rows, err := c.conn.QueryContext(context.Background(),
+		"SELECT \"table_name\", \"counter\" FROM "+QuoteIdentifier(c.CountersTableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		var counter int64
+		if err = rows.Scan(&table, &counter); err != nil {
+			return nil, err
+		}
+		counters[table] = counter
+	}
+	return counters, rows.Err()
+}
+
+// Checks the data version and notifies subscribers about changed tables.
+func (c *SqliteChangeWatcher) poll(correlationId string) {
+	c.lock.Lock()
+	if c.conn == nil {
+		c.lock.Unlock()
+		return
+	}
+
+	events := make([]*SqliteTableChangeEvent, 0)
+	version, err := c.readDataVersion()
+	if err == nil && version != c.dataVersion {
+		c.dataVersion = version
+		if len(c.Tables) == 0 {
+			events = append(events, &SqliteTableChangeEvent{Version: version})
+		} else {
+			var counters map[string]int64
+			if counters, err = c.readCounters(); err == nil {
+				for _, table := range c.Tables {
+					if counter, ok := counters[table]; ok && counter != c.counters[table] {
+						events = append(events, &SqliteTableChangeEvent{Table: table, Version: counter})
+					}
+				}
+				c.counters = counters
+			}
+		}
+	}
+	subscriptions := c.subscriptions
+	c.lock.Unlock()
+
+	if err != nil {
+		c.Logger.Error(correlationId, err, "Failed to check changes of sqlite database")
+		return
+	}
+	for _, event := range events {
+		for _, subscription := range subscriptions {
+			if subscription.table == "" || subscription.table == event.Table {
+				subscription.listener(event)
+			}
+		}
+	}
+}
@@ -3,7 +3,7 @@ package connect
 import (
 	"database/sql"
 
-	_ "github.com/mattn/go-sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
 	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
 	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
 	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
@@ -22,6 +22,8 @@ import (
  *   - discovery_key:             (optional) a key to retrieve the connection from [[IDiscovery]]
  *   - database:                  path to database file
  *   - uri:                       resource URI or connection string with all parameters in it
+ * - options:
+ *   - notifications:             (optional) registers SQLite hooks to notify subscribers about committed changes (default: false)
  * ### References ###
  *
  * - \*:logger:\*:\*:1.0           (optional) [[ILogger]] components to pass log messages
@@ -41,6 +43,10 @@ type SqliteConnection struct {
 	Connection *sql.DB
 	// The SQLite database name.
 	DatabaseName string
+	// The flag to notify subscribers about committed changes.
+	Notifications bool
+
+	notifier *sqliteChangeNotifier
 }
 
 // NewSqliteConnection creates a new instance of the connection component.
@@ -50,6 +56,7 @@ func NewSqliteConnection() *SqliteConnection {
 		Logger:             clog.NewCompositeLogger(),
 		ConnectionResolver: NewSqliteConnectionResolver(),
 		Options:            cconf.NewEmptyConfigParams(),
+		notifier:           newSqliteChangeNotifier(),
 	}
 	return c
 }
@@ -60,6 +67,7 @@ func (c *SqliteConnection) Configure(config *cconf.ConfigParams) {
 	config = config.SetDefaults(c.defaultConfig)
 	c.ConnectionResolver.Configure(config)
 	c.Options = c.Options.Override(config.GetSection("options"))
+	c.Notifications = c.Options.GetAsBooleanWithDefault("notifications", c.Notifications)
 }
 
 // Sets references to dependent components.
@@ -94,7 +102,18 @@ func (c *SqliteConnection) Open(correlationId string) error {
 
 	c.Logger.Debug(correlationId, "Connecting to sqlite")
 
-	con, err := sql.Open("sqlite3", database)
+	var con *sql.DB
+	if c.Notifications {
+		// Every new connection of the pool gets the hooks
+		con = sql.OpenDB(&sqliteHookConnector{
+			driver:   &sqlite3.SQLiteDriver{},
+			notifier: c.notifier,
+			dsn:      database,
+		})
+		c.notifier.start()
+	} else {
+		con, err = sql.Open("sqlite3", database)
+	}
 
 	if err != nil || con == nil {
 		err = cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Connection to sqlite failed").WithCause(err)
@@ -118,6 +137,7 @@ func (c *SqliteConnection) Close(correlationId string) error {
 		c.Logger.Error(correlationId, err, "Error while closing SQLite database %s", c.DatabaseName)
 		return err
 	}
+	c.notifier.stop()
 	c.Logger.Debug(correlationId, "Disconnected from sqlite database %s", c.DatabaseName)
 	c.Connection = nil
 	c.DatabaseName = ""
@@ -131,3 +151,37 @@ func (c *SqliteConnection) GetConnection() *sql.DB {
 func (c *SqliteConnection) GetDatabaseName() string {
 	return c.DatabaseName
 }
+
+// Subscribes a listener to committed changes.
+// Listeners are called one by one from a separate goroutine in the order of commits.
+// Changes of rolled back transactions, failed statements and savepoints undone by ROLLBACK TO
+// are never delivered. Savepoints are tracked when they are handled by separate statements.
+// SQLite doesn't report rows removed by DELETE statements without WHERE clause.
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+//  - table         	(optional) a table name, "" subscribes to all tables.
+//  - operation     	(optional) insert, update or delete, "" subscribes to all operations.
+//  - listener      	a function that receives changes.
+// Returns a subscription or error when notifications are disabled.
+func (c *SqliteConnection) Subscribe(correlationId string, table string, operation string,
+	listener SqliteChangeListener) (*SqliteChangeSubscription, error) {
+	if !c.Notifications {
+		return nil, cerr.NewInvalidStateError(correlationId, "NOTIFICATIONS_DISABLED",
+			"Change notifications are disabled, set options.notifications to enable them")
+	}
+	return c.notifier.subscribe(table, operation, listener), nil
+}
+
+// Subscribes a channel to committed changes.
+// Sends to the channel block delivery of further changes, so it shall have a buffer
+// or be read continuously.
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+//  - table         	(optional) a table name, "" subscribes to all tables.
+//  - operation     	(optional) insert, update or delete, "" subscribes to all operations.
+//  - channel       	a channel that receives changes.
+// Returns a subscription or error when notifications are disabled.
+func (c *SqliteConnection) SubscribeChannel(correlationId string, table string, operation string,
+	channel chan<- *SqliteChangeEvent) (*SqliteChangeSubscription, error) {
+	return c.Subscribe(correlationId, table, operation, func(event *SqliteChangeEvent) {
+		channel <- event
+	})
+}
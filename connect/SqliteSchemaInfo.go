@@ -0,0 +1,350 @@
+package connect
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Describes a table stored in SQLite database.
+type SqliteTableInfo struct {
+	// The table name.
+	Name string
+	// The SQL statement that created the table.
+	Sql string
+	// True if the table was created WITHOUT ROWID.
+	WithoutRowId bool
+	// True if the table was created as STRICT.
+	Strict bool
+}
+
+// Describes a view stored in SQLite database.
+type SqliteViewInfo struct {
+	// The view name.
+	Name string
+	// The SQL statement that created the view.
+	Sql string
+}
+
+// Describes a column of a table or a view.
+type SqliteColumnInfo struct {
+	// The column position starting from 0.
+	Position int
+	// The column name.
+	Name string
+	// The declared column type.
+	Type string
+	// True if the column has NOT NULL constraint.
+	NotNull bool
+	// The default value expression or nil when the column has no default.
+	DefaultValue *string
+	// The position of the column in the primary key starting from 1, or 0 for non-key columns.
+	PrimaryKey int
+	// True if the column is generated (GENERATED ALWAYS AS).
+	Generated bool
+	// True if the column is hidden (hidden columns of virtual tables and generated columns).
+	Hidden bool
+}
+
+// Describes a column of an index.
+type SqliteIndexColumnInfo struct {
+	// The column name or empty string for expressions and rowid.
+	Name string
+	// True if the column is sorted in descending order.
+	Desc bool
+	// The collating sequence.
+	Collation string
+}
+
+// Describes an index of a table.
+type SqliteIndexInfo struct {
+	// The index name.
+	Name string
+	// The indexed table name.
+	Table string
+	// True if the index is unique.
+	Unique bool
+	// The index origin: "c" for CREATE INDEX, "u" for UNIQUE constraint, "pk" for PRIMARY KEY.
+	Origin string
+	// True if the index is partial.
+	Partial bool
+	// The WHERE predicate of a partial index.
+	Predicate string
+	// The indexed columns in key order.
+	Columns []*SqliteIndexColumnInfo
+	// The SQL statement that created the index (empty for automatic indexes).
+	Sql string
+}
+
+// Describes a trigger stored in SQLite database.
+type SqliteTriggerInfo struct {
+	// The trigger name.
+	Name string
+	// The table or view the trigger is attached to.
+	Table string
+	// The SQL statement that created the trigger.
+	Sql string
+}
+
+// Describes a foreign key of a table.
+type SqliteForeignKeyInfo struct {
+	// The foreign key id within the table.
+	Id int
+	// The referenced table name.
+	Table string
+	// The referencing columns.
+	From []string
+	// The referenced columns (empty entries refer to the primary key).
+	To []string
+	// The ON UPDATE action.
+	OnUpdate string
+	// The ON DELETE action.
+	OnDelete string
+	// The MATCH clause.
+	Match string
+}
+
+var indexPredicatePattern = regexp.MustCompile(`(?is)\)\s*WHERE\s+(.+)$`)
+
+func (c *SqliteConnection) checkOpened(correlationId string) error {
+	if c.Connection == nil {
+		return cerr.NewInvalidStateError(correlationId, "NO_CONNECTION", "SQLite connection is not opened")
+	}
+	return nil
+}
+
+// Gets a list of user tables in the database.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Return tables ordered by name or error.
+func (c *SqliteConnection) GetTables(correlationId string) ([]*SqliteTableInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT s.name, COALESCE(s.sql, ''), COALESCE(t.wr, 0), COALESCE(t.strict, 0)" +
+		" FROM sqlite_schema AS s LEFT JOIN pragma_table_list AS t ON t.schema='main' AND t.name=s.name" +
+		" WHERE s.type='table' AND s.name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY s.name"
+	rows, err := c.Connection.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]*SqliteTableInfo, 0)
+	for rows.Next() {
+		table := &SqliteTableInfo{}
+		if err := rows.Scan(&table.Name, &table.Sql, &table.WithoutRowId, &table.Strict); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// Gets a list of views in the database.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Return views ordered by name or error.
+func (c *SqliteConnection) GetViews(correlationId string) ([]*SqliteViewInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT name, COALESCE(sql, '') FROM sqlite_schema WHERE type='view' ORDER BY name"
+	rows, err := c.Connection.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]*SqliteViewInfo, 0)
+	for rows.Next() {
+		view := &SqliteViewInfo{}
+		if err := rows.Scan(&view.Name, &view.Sql); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// Gets columns of a table or a view including generated and hidden columns.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// - table             a table or view name.
+// Return columns ordered by position or error. The list is empty when the table does not exist.
+func (c *SqliteConnection) GetColumns(correlationId string, table string) ([]*SqliteColumnInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT cid, name, type, \"notnull\", dflt_value, pk, hidden FROM pragma_table_xinfo(?) ORDER BY cid"
+	rows, err := c.Connection.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]*SqliteColumnInfo, 0)
+	for rows.Next() {
+		column := &SqliteColumnInfo{}
+		var defaultValue sql.NullString
+		var hidden int
+		err := rows.Scan(&column.Position, &column.Name, &column.Type, &column.NotNull,
+			&defaultValue, &column.PrimaryKey, &hidden)
+		if err != nil {
+			return nil, err
+		}
+		if defaultValue.Valid {
+			column.DefaultValue = &defaultValue.String
+		}
+		// 1 - hidden column of a virtual table, 2 and 3 - generated columns
+		column.Hidden = hidden != 0
+		column.Generated = hidden == 2 || hidden == 3
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// Gets indexes of a table including automatic indexes created for constraints.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// - table             a table name.
+// Return indexes ordered by name or error.
+func (c *SqliteConnection) GetIndexes(correlationId string, table string) ([]*SqliteIndexInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT il.name, il.\"unique\", il.origin, il.partial, COALESCE(s.sql, '')" +
+		" FROM pragma_index_list(?) AS il LEFT JOIN sqlite_schema AS s ON s.type='index' AND s.name=il.name" +
+		" ORDER BY il.name"
+	rows, err := c.Connection.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]*SqliteIndexInfo, 0)
+	for rows.Next() {
+		index := &SqliteIndexInfo{Table: table}
+		if err := rows.Scan(&index.Name, &index.Unique, &index.Origin, &index.Partial, &index.Sql); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if index.Partial {
+			if match := indexPredicatePattern.FindStringSubmatch(index.Sql); match != nil {
+				index.Predicate = strings.TrimSpace(match[1])
+			}
+		}
+		indexes = append(indexes, index)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		index.Columns, err = c.readIndexColumns(index.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+func (c *SqliteConnection) readIndexColumns(index string) ([]*SqliteIndexColumnInfo, error) {
+	query := "SELECT COALESCE(name, ''), \"desc\", COALESCE(coll, '') FROM pragma_index_xinfo(?) WHERE key=1 ORDER BY seqno"
+	rows, err := c.Connection.Query(query, index)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]*SqliteIndexColumnInfo, 0)
+	for rows.Next() {
+		column := &SqliteIndexColumnInfo{}
+		if err := rows.Scan(&column.Name, &column.Desc, &column.Collation); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// Gets triggers attached to a table or all triggers in the database.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// - table             (optional) a table or view name. Empty string returns all triggers.
+// Return triggers ordered by name or error.
+func (c *SqliteConnection) GetTriggers(correlationId string, table string) ([]*SqliteTriggerInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT name, tbl_name, COALESCE(sql, '') FROM sqlite_schema WHERE type='trigger' AND (?1='' OR tbl_name=?1) ORDER BY name"
+	rows, err := c.Connection.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggers := make([]*SqliteTriggerInfo, 0)
+	for rows.Next() {
+		trigger := &SqliteTriggerInfo{}
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &trigger.Sql); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, rows.Err()
+}
+
+// Gets foreign keys of a table.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// - table             a table name.
+// Return foreign keys ordered by id or error.
+func (c *SqliteConnection) GetForeignKeys(correlationId string, table string) ([]*SqliteForeignKeyInfo, error) {
+	if err := c.checkOpened(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT id, \"table\", \"from\", COALESCE(\"to\", ''), on_update, on_delete, \"match\"" +
+		" FROM pragma_foreign_key_list(?) ORDER BY id, seq"
+	rows, err := c.Connection.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*SqliteForeignKeyInfo, 0)
+	var key *SqliteForeignKeyInfo
+	for rows.Next() {
+		var id int
+		var refTable, from, to, onUpdate, onDelete, match string
+		if err := rows.Scan(&id, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, err
+		}
+		// Compound keys are returned as multiple rows with the same id
+		if key == nil || key.Id != id {
+			key = &SqliteForeignKeyInfo{
+				Id:       id,
+				Table:    refTable,
+				From:     make([]string, 0),
+				To:       make([]string, 0),
+				OnUpdate: onUpdate,
+				OnDelete: onDelete,
+				Match:    match,
+			}
+			keys = append(keys, key)
+		}
+		key.From = append(key.From, from)
+		key.To = append(key.To, to)
+	}
+	return keys, rows.Err()
+}
+
+// Quotes an SQL identifier like a table or column name, double quotes inside it are escaped.
+// - value     an identifier to quote.
+// Returns the quoted identifier.
+func QuoteIdentifier(value string) string {
+	return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+}
@@ -1,6 +1,6 @@
 module github.com/pip-services3-go/pip-services3-sqlite-go
 
-go 1.16
+go 1.18
 
 require (
 	github.com/mattn/go-sqlite3 v1.14.16
@@ -9,3 +9,11 @@ require (
 	github.com/pip-services3-go/pip-services3-data-go v1.1.11
 	github.com/stretchr/testify v1.8.1
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/jinzhu/copier v0.3.5 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -4,4 +4,5 @@ import (
 	_ "github.com/pip-services3-go/pip-services3-sqlite-go/build"
 	_ "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
 	_ "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	_ "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
 )
@@ -0,0 +1,43 @@
+package persistence
+
+import "strings"
+
+// Interface for components that identify who performs data changes.
+type ActorProvider interface {
+	// Gets the actor who performs an operation.
+	// - correlationId     (optional) transaction id to trace execution through call chain.
+	// Returns the actor id or empty string when the actor is unknown.
+	GetActor(correlationId string) string
+}
+
+/*
+Provides actors from correlation ids.
+
+When Separator is set, correlation ids are expected in "<actor><separator><trace id>" format
+and the actor is taken from the part before the separator.
+Correlation ids without the separator are used as actors as is.
+*/
+type CorrelationActorProvider struct {
+	// The separator between the actor and the trace id.
+	Separator string
+}
+
+// Creates a new instance of the provider.
+// - separator     (optional) a separator between the actor and the trace id.
+func NewCorrelationActorProvider(separator string) *CorrelationActorProvider {
+	return &CorrelationActorProvider{
+		Separator: separator,
+	}
+}
+
+// Gets the actor from a correlation id.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns the actor id or empty string when the correlation id is empty.
+func (c *CorrelationActorProvider) GetActor(correlationId string) string {
+	if c.Separator != "" {
+		if index := strings.Index(correlationId, c.Separator); index >= 0 {
+			return correlationId[:index]
+		}
+	}
+	return correlationId
+}
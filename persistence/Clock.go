@@ -0,0 +1,23 @@
+package persistence
+
+import "time"
+
+// Interface for components that provide current time.
+// Persistence components use it to stamp data items, so tests can make stamps deterministic.
+type Clock interface {
+	// Gets the current time.
+	Now() time.Time
+}
+
+// Clock that returns the system time.
+type SystemClock struct{}
+
+// Creates a new instance of the clock.
+func NewSystemClock() *SystemClock {
+	return &SystemClock{}
+}
+
+// Gets the current system time.
+func (c *SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,338 @@
+package persistence
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Abstract persistence component that stores data in SQLite
+and implements a number of CRUD operations over data items
+identified by a composite key that spans several columns.
+
+Operations by key receive key tuples with values
+in the order of the key columns passed to the constructor.
+
+### Configuration parameters ###
+
+- collection:                  (optional) SQLite collection name
+- connection(s):
+  - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+  - database:                  path to database file
+  - uri:                       resource URI or connection string with all parameters in it
+
+- options:
+  - soft_delete:               (optional) marks deleted items with a timestamp instead of removing them (default: false)
+  - deleted_column:            (optional) name of the column that keeps the time of soft deletion (default: deleted_at)
+
+### References ###
+
+- \*:logger:\*:\*:1.0           (optional) ILogger components to pass log messages
+- \*:discovery:\*:\*:1.0        (optional) IDiscovery services
+- \*:credential-store:\*:\*:1.0 (optional) Credential stores to resolve credentials
+
+### Example ###
+
+	type MemberSqlitePersistence struct {
+		persist.CompositeKeySqlitePersistence
+	}
+
+	func NewMemberSqlitePersistence() *MemberSqlitePersistence {
+		c := &MemberSqlitePersistence{}
+		c.CompositeKeySqlitePersistence = *persist.InheritCompositeKeySqlitePersistence(c,
+			reflect.TypeOf(Member{}), "members", "group_id", "user_id")
+		return c
+	}
+
+	func (c *MemberSqlitePersistence) DefineSchema() {
+		c.ClearSchema()
+		c.EnsureSchema("CREATE TABLE \"members\" (\"group_id\" TEXT, \"user_id\" TEXT, \"role\" TEXT," +
+			" PRIMARY KEY (\"group_id\", \"user_id\"))")
+	}
+
+	item, err := persistence.GetOneByKey("123", []interface{}{"group1", "user1"})
+*/
+type CompositeKeySqlitePersistence struct {
+	*SqlitePersistence
+
+	//The names of the key columns.
+	KeyColumns []string
+}
+
+// Creates a new instance of the persistence component.
+// - overrides  a references to child class that overrides virtual methods
+// - proto      a type of data items.
+// - tableName  a table name.
+// - keyColumns names of the key columns.
+func InheritCompositeKeySqlitePersistence(overrides ISqlitePersistenceOverrides, proto reflect.Type,
+	tableName string, keyColumns ...string) *CompositeKeySqlitePersistence {
+	if tableName == "" {
+		panic("Table name could not be empty")
+	}
+	if len(keyColumns) == 0 {
+		panic("Key columns could not be empty")
+	}
+
+	c := &CompositeKeySqlitePersistence{
+		KeyColumns: keyColumns,
+	}
+	c.SqlitePersistence = InheritSqlitePersistence(overrides, proto, tableName)
+	return c
+}
+
+// Composes a condition that matches a single key: "k1"=?1 AND "k2"=?2
+func (c *CompositeKeySqlitePersistence) composeKeyCondition(offset int) string {
+	conditions := make([]string, len(c.KeyColumns))
+	for index, column := range c.KeyColumns {
+		conditions[index] = c.QuoteIdentifier(column) + "=?" + strconv.Itoa(offset+index+1)
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Composes a list of quoted key columns: "k1","k2"
+func (c *CompositeKeySqlitePersistence) composeKeyColumns() string {
+	columns := make([]string, len(c.KeyColumns))
+	for index, column := range c.KeyColumns {
+		columns[index] = c.QuoteIdentifier(column)
+	}
+	return strings.Join(columns, ",")
+}
+
+func (c *CompositeKeySqlitePersistence) validateKey(correlationId string, key []interface{}) error {
+	if len(key) != len(c.KeyColumns) {
+		return cerr.NewBadRequestError(correlationId, "WRONG_KEY",
+			"Key must have "+strconv.Itoa(len(c.KeyColumns))+" values").
+			WithDetails("key", key)
+	}
+	return nil
+}
+
+// Gets the key tuple of a data item from its converted row.
+func (c *CompositeKeySqlitePersistence) getObjectKey(row map[string]interface{}) []interface{} {
+	key := make([]interface{}, len(c.KeyColumns))
+	for index, column := range c.KeyColumns {
+		key[index] = row[column]
+	}
+	return key
+}
+
+// Gets a data item by its composite key.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - key               key values in the order of key columns.
+// Returns           data item or error.
+func (c *CompositeKeySqlitePersistence) GetOneByKey(correlationId string, key []interface{}) (item interface{}, err error) {
+	if err := c.validateKey(correlationId, key); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + c.composeActiveFilter(c.composeKeyCondition(0))
+	qResult, qErr := c.Client.Query(query, key...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+	if !qResult.Next() {
+		c.Logger.Trace(correlationId, "Nothing found from %s with key = %v", c.TableName, key)
+		return nil, qResult.Err()
+	}
+
+	item = c.Overrides.ConvertToPublic(qResult)
+	c.Logger.Trace(correlationId, "Retrieved from %s with key = %v", c.TableName, key)
+	return item, nil
+}
+
+// Gets a list of data items retrieved by given composite keys.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - keys              keys of data items to be retrieved.
+// Returns          a data list or error.
+func (c *CompositeKeySqlitePersistence) GetListByKeys(correlationId string, keys [][]interface{}) (items []interface{}, err error) {
+	items = make([]interface{}, 0)
+	if len(keys) == 0 {
+		return items, nil
+	}
+
+	rows := make([]string, len(keys))
+	values := make([]interface{}, 0, len(keys)*len(c.KeyColumns))
+	for index, key := range keys {
+		if err := c.validateKey(correlationId, key); err != nil {
+			return nil, err
+		}
+		params := make([]string, len(key))
+		for position := range key {
+			params[position] = "?" + strconv.Itoa(len(values)+position+1)
+		}
+		rows[index] = "(" + strings.Join(params, ",") + ")"
+		values = append(values, key...)
+	}
+
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) +
+		" WHERE " + c.composeActiveFilter("("+c.composeKeyColumns()+") IN (VALUES "+strings.Join(rows, ",")+")")
+	qResult, qErr := c.Client.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+	for qResult.Next() {
+		item := c.Overrides.ConvertToPublic(qResult)
+		items = append(items, item)
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d from %s", len(items), c.TableName)
+	return items, qResult.Err()
+}
+
+// Sets a data item. If the data item exists it updates it,
+// otherwise it create a new data item.
+// - correlation_id    (optional) transaction id to trace execution through call chain.
+// - item              a item to be set.
+// Returns          (optional)  updated item or error.
+func (c *CompositeKeySqlitePersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(item))
+	key := c.getObjectKey(row)
+	params := c.GenerateParameters(row)
+	setParams, columns := c.GenerateSetParameters(row)
+	values := c.GenerateValues(columns, row)
+
+	query := "INSERT INTO " + c.QuoteIdentifier(c.TableName) + " (" + columns + ")" +
+		" VALUES (" + params + ")" +
+		" ON CONFLICT (" + c.composeKeyColumns() + ") DO UPDATE SET " + setParams
+	if c.SoftDelete {
+		// Setting a soft-deleted item restores it
+		query += "," + c.QuoteIdentifier(c.DeletedColumn) + "=NULL"
+	}
+
+	_, qErr := c.Client.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	c.Logger.Trace(correlationId, "Set in %s with key = %v", c.TableName, key)
+	return c.GetOneByKey(correlationId, key)
+}
+
+// Updates a data item. The key is taken from the item.
+// - correlation_id    (optional) transaction id to trace execution through call chain.
+// - item              an item to be updated.
+// Returns          (optional)  updated item or error.
+func (c *CompositeKeySqlitePersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(item))
+	key := c.getObjectKey(row)
+	params, columns := c.GenerateSetParameters(row)
+	values := c.GenerateValues(columns, row)
+
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) +
+		" SET " + params + " WHERE " + c.composeKeyCondition(len(values)) + c.composeActiveCondition()
+	values = append(values, key...)
+
+	_, qErr := c.Client.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	c.Logger.Trace(correlationId, "Updated in %s with key = %v", c.TableName, key)
+	return c.GetOneByKey(correlationId, key)
+}
+
+// Updates only few selected fields in a data item.
+// - correlation_id    (optional) transaction id to trace execution through call chain.
+// - key               key values in the order of key columns.
+// - data              a map with fields to be updated.
+// Returns           updated item or error.
+func (c *CompositeKeySqlitePersistence) UpdatePartially(correlationId string, key []interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
+	if data == nil {
+		return nil, nil
+	}
+	if err := c.validateKey(correlationId, key); err != nil {
+		return nil, err
+	}
+
+	row := c.Overrides.ConvertFromPublicPartial(data.Value())
+	params, columns := c.GenerateSetParameters(row)
+	values := c.GenerateValues(columns, row)
+
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) +
+		" SET " + params + " WHERE " + c.composeKeyCondition(len(values)) + c.composeActiveCondition()
+	values = append(values, key...)
+
+	_, qErr := c.Client.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	c.Logger.Trace(correlationId, "Updated partially in %s with key = %v", c.TableName, key)
+	return c.GetOneByKey(correlationId, key)
+}
+
+// Deleted a data item by its composite key. Soft-deleted items are marked with the time of deletion instead.
+// - correlation_id    (optional) transaction id to trace execution through call chain.
+// - key               key values in the order of key columns.
+// Returns          (optional)  deleted item or error.
+func (c *CompositeKeySqlitePersistence) DeleteByKey(correlationId string, key []interface{}) (result interface{}, err error) {
+	result, err = c.GetOneByKey(correlationId, key)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + c.composeKeyCondition(0)
+	values := key
+	if c.SoftDelete {
+		query = c.composeSoftDelete(c.composeKeyCondition(0), len(key)+1)
+		values = append(append(make([]interface{}, 0, len(key)+1), key...), FormatSqliteTimestamp(c.now()))
+	}
+	_, qErr := c.Client.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	c.Logger.Trace(correlationId, "Deleted from %s with key = %v", c.TableName, key)
+	return result, nil
+}
+
+// Deletes multiple data items by their composite keys. Soft-deleted items are marked with the time of deletion instead.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - keys              keys of data items to be deleted.
+// Returns          a number of deleted items or error.
+func (c *CompositeKeySqlitePersistence) DeleteByKeys(correlationId string, keys [][]interface{}) (count int64, err error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	conditions := make([]string, len(keys))
+	values := make([]interface{}, 0, len(keys)*len(c.KeyColumns))
+	for index, key := range keys {
+		if err := c.validateKey(correlationId, key); err != nil {
+			return 0, err
+		}
+		conditions[index] = "(" + c.composeKeyCondition(len(values)) + ")"
+		values = append(values, key...)
+	}
+
+	condition := strings.Join(conditions, " OR ")
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + condition
+	if c.SoftDelete {
+		values = append(values, FormatSqliteTimestamp(c.now()))
+		query = c.composeSoftDelete("("+condition+")", len(values))
+	}
+	qResult, qErr := c.Client.Exec(query, values...)
+	if qErr != nil {
+		return 0, qErr
+	}
+
+	count, err = qResult.RowsAffected()
+	if count != 0 {
+		c.Logger.Trace(correlationId, "Deleted %d items from %s", count, c.TableName)
+	}
+	return count, err
+}
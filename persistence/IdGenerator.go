@@ -0,0 +1,37 @@
+package persistence
+
+import (
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+Interface for components that generate unique ids for new data items.
+
+Built-in generators are:
+- ObjectIdGenerator    random ids (default)
+- UuidV7IdGenerator    time-ordered UUID version 7
+- UlidIdGenerator      time-ordered ULID
+- SnowflakeIdGenerator time-ordered 64-bit integers
+- SequenceIdGenerator  human-readable ids from named sequences stored in SQLite
+*/
+type IdGenerator interface {
+	// Generates a new unique id.
+	// - correlationId     (optional) transaction id to trace execution through call chain.
+	// Returns a generated id or error.
+	NextId(correlationId string) (interface{}, error)
+}
+
+// Generates random ids with cdata.IdGenerator.
+type ObjectIdGenerator struct{}
+
+// Creates a new instance of the generator.
+func NewObjectIdGenerator() *ObjectIdGenerator {
+	return &ObjectIdGenerator{}
+}
+
+// Generates a new random id.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a generated id or error.
+func (c *ObjectIdGenerator) NextId(correlationId string) (interface{}, error) {
+	return cdata.IdGenerator.NextLong(), nil
+}
@@ -4,9 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"reflect"
+	"strconv"
 
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
 	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
-	cmpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
 )
 
 ///*
@@ -113,18 +114,41 @@ func InheritIdentifiableJsonSqlitePersistence(overrides ISqlitePersistenceOverri
 }
 
 // Adds DML statement to automatically create JSON(B) table
-// - idType type of the id column (default: TEXT)
-// - dataType type of the data column (default: JSONB)
+// - idType type of the id column (default: VARCHAR(32) or INTEGER when ids are assigned by SQLite)
+// - dataType type of the data column (default: JSON or BLOB for codecs that don't store JSON text)
 func (c *IdentifiableJsonSqlitePersistence) EnsureTable(idType string, dataType string) {
 	if idType == "" {
 		idType = "VARCHAR(32)"
+		if c.AutoIncrementId {
+			idType = "INTEGER"
+		}
 	}
 	if dataType == "" {
 		dataType = "JSON"
+		if !c.isJsonCodec() {
+			dataType = "BLOB"
+		}
 	}
 
 	query := "CREATE TABLE IF NOT EXISTS " + c.QuoteIdentifier(c.TableName) +
-		" (id " + idType + " PRIMARY KEY, data " + dataType + ")"
+		" (" + c.QuoteIdentifier(c.IdColumn) + " " + idType + " PRIMARY KEY, data " + dataType
+	if c.VersionColumn != "" {
+		query += ", " + c.QuoteIdentifier(c.VersionColumn) + " INTEGER NOT NULL DEFAULT 1"
+	}
+	if c.DocVersionColumn != "" {
+		query += ", " + c.QuoteIdentifier(c.DocVersionColumn) + " INTEGER"
+	}
+	if !c.StampsInData {
+		for _, column := range []string{c.CreatedAtColumn, c.UpdatedAtColumn, c.CreatedByColumn, c.UpdatedByColumn} {
+			if column != "" {
+				query += ", " + c.QuoteIdentifier(column) + " TEXT"
+			}
+		}
+	}
+	if c.SoftDelete {
+		query += ", " + c.QuoteIdentifier(c.DeletedColumn) + " TEXT"
+	}
+	query += ")"
 
 	c.EnsureSchema(query)
 }
@@ -157,12 +181,55 @@ func (c *IdentifiableJsonSqlitePersistence) ConvertToPublic(rows *sql.Rows) inte
 
 	data, ok := buf["data"]
 	if !ok {
-		data = buf
+		// Projections without the data column are mapped by column names
+		delete(buf, c.DocVersionColumn)
+		for column, value := range buf {
+			if value, ok := value.([]byte); ok {
+				buf[column] = string(value)
+			}
+		}
+		docBuf, _ := json.Marshal(buf)
+		data = string(docBuf)
+	} else if data != nil && !c.isJsonCodec() {
+		// Opaque documents are decoded to JSON to upcast them and merge columns
+		version, versioned := buf[c.DocVersionColumn]
+		outdated := versioned && c.DocVersionColumn != "" && docVersionOf(version) < c.DocVersion()
+		text, err := c.decodeDocument(data, outdated)
+		if err != nil {
+			c.Logger.Error("", err, "Failed to decode document in %s", c.TableName)
+			return nil
+		}
+		data = text
 	}
 
 	docPointer := c.NewObjectByPrototype()
 	jsonBuf, ok := data.(string)
 	if ok {
+		// Outdated documents are brought to the current shape
+		if version, ok := buf[c.DocVersionColumn]; ok && c.DocVersionColumn != "" {
+			if upcasted, err := c.upcastDocument(jsonBuf, docVersionOf(version)); err == nil {
+				jsonBuf = upcasted
+			} else {
+				c.Logger.Error("", err, "Failed to upcast document in %s", c.TableName)
+			}
+		}
+
+		// Version and stamps can be kept outside of the data column
+		doc := map[string]interface{}{}
+		merged := false
+		for _, column := range c.outsideDataColumns() {
+			if value, ok := buf[column]; ok && value != nil {
+				if !merged {
+					json.Unmarshal(([]byte)(jsonBuf), &doc)
+					merged = true
+				}
+				doc[column] = value
+			}
+		}
+		if merged {
+			docBuf, _ := json.Marshal(doc)
+			jsonBuf = (string)(docBuf)
+		}
 		json.Unmarshal(([]byte)(jsonBuf), docPointer.Interface())
 		return c.DereferenceObject(docPointer)
 	}
@@ -177,13 +244,42 @@ func (c *IdentifiableJsonSqlitePersistence) ConvertFromPublic(value interface{})
 	if value == nil {
 		return nil
 	}
-	id := cmpersist.GetObjectId(value)
-
-	json, _ := json.Marshal(value)
+	id := c.getObjectId(value)
 
 	result := map[string]interface{}{
-		"id":   id,
-		"data": (string)(json),
+		c.IdColumn: id,
+	}
+
+	// JSON fields of opaque documents are kept in columns
+	if c.storedJsonFields && len(c.jsonFields) > 0 {
+		doc := c.convertToMap(value)
+		for _, field := range c.jsonFields {
+			result[field.column] = extractJsonPath(doc, field.path)
+		}
+	}
+
+	// Version and stamps can be kept outside of the data column
+	if columns := c.outsideDataColumns(); len(columns) > 0 {
+		doc := c.convertToMap(value)
+		for _, column := range columns {
+			if field, ok := doc[column]; ok {
+				result[column] = field
+				delete(doc, column)
+			}
+		}
+		// Opaque documents keep the type of public objects to be decoded back
+		if c.isJsonCodec() {
+			value = doc
+		}
+	}
+
+	data, err := c.documentCodec().Encode(value)
+	if err != nil {
+		c.Logger.Error("", err, "Failed to encode document in %s", c.TableName)
+	}
+	result["data"] = data
+	if c.DocVersionColumn != "" {
+		result[c.DocVersionColumn] = c.DocVersion()
 	}
 	return result
 }
@@ -204,32 +300,195 @@ func (c *IdentifiableJsonSqlitePersistence) UpdatePartially(correlationId string
 	if data == nil {
 		return nil, nil
 	}
+	// Patches are written in the current shape
+	if err = c.upgradeDocument(correlationId, id); err != nil {
+		return nil, err
+	}
 
-	query := "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET data=JSON_PATCH(data,?) WHERE id=?"
-	jsonBuf, err := json.Marshal(data.Value())
+	patch := make(map[string]interface{}, len(data.Value()))
+	for key, value := range data.Value() {
+		patch[key] = value
+	}
+	version, checkVersion := patch[c.VersionColumn]
+	checkVersion = checkVersion && c.VersionColumn != ""
+	delete(patch, c.VersionColumn)
+
+	if !c.isJsonCodec() {
+		result, err = c.updatePartiallyDecoded(correlationId, id, patch, version, checkVersion)
+		if err == nil && result != nil {
+			c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.TableName, id)
+		}
+		return result, err
+	}
+
+	stamps := c.composeStamps(correlationId, false)
+	if c.StampsInData {
+		for field, value := range stamps {
+			patch[field] = value
+		}
+	}
+
+	jsonBuf, err := json.Marshal(patch)
 	if err != nil {
 		return nil, err
 	}
-	values := []interface{}{(string)(jsonBuf), id}
+	values := []interface{}{(string)(jsonBuf)}
+	params := "data=JSON_PATCH(data,?1)"
+	if !c.StampsInData {
+		for column, value := range stamps {
+			values = append(values, value)
+			params += "," + c.QuoteIdentifier(column) + "=?" + strconv.Itoa(len(values))
+		}
+	}
+	values = append(values, id)
 
-	_, qErr := c.Client.Exec(query, values...)
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + params + c.composeVersionIncrement() +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(values)) + c.composeActiveCondition()
+	if checkVersion {
+		values = append(values, version)
+		query += " AND " + c.QuoteIdentifier(c.VersionColumn) + "=?" + strconv.Itoa(len(values))
+	}
 
-	if qErr != nil {
-		return nil, qErr
+	result, err = c.updateRow(correlationId, AuditOperationUpdatePartially, id, query, values, version, checkVersion)
+	if err == nil && result != nil {
+		c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.TableName, id)
 	}
+	return result, err
+}
 
-	query = "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE id=$1"
-	qResult2, qErr2 := c.Client.Query(query, id)
-	if qErr2 != nil {
-		return nil, qErr2
+// Gets columns that are kept outside of the data column.
+func (c *IdentifiableJsonSqlitePersistence) outsideDataColumns() []string {
+	columns := make([]string, 0, 5)
+	if c.VersionColumn != "" {
+		columns = append(columns, c.VersionColumn)
+	}
+	if !c.StampsInData {
+		for _, column := range []string{c.CreatedAtColumn, c.UpdatedAtColumn, c.CreatedByColumn, c.UpdatedByColumn} {
+			if column != "" {
+				columns = append(columns, column)
+			}
+		}
 	}
-	defer qResult2.Close()
-	if !qResult2.Next() {
-		return nil, qResult2.Err()
+	return columns
+}
+
+// Gets the codec of documents.
+func (c *IdentifiableSqlitePersistence) documentCodec() SqliteDocumentCodec {
+	if c.DocumentCodec == nil {
+		return NewJsonDocumentCodec()
 	}
+	return c.DocumentCodec
+}
+
+// Checks if documents are stored as JSON text that SQL JSON functions can query.
+func (c *IdentifiableSqlitePersistence) isJsonCodec() bool {
+	return c.DocumentCodec == nil || c.DocumentCodec.IsJson()
+}
 
-	result = c.Overrides.ConvertToPublic(qResult2)
-	c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.TableName, id)
+// Decodes an opaque document to JSON text. Outdated documents are decoded as maps
+// to keep fields that are no longer in the prototype.
+func (c *IdentifiableSqlitePersistence) decodeDocument(data interface{}, outdated bool) (string, error) {
+	var doc interface{}
+	if outdated {
+		fields := map[string]interface{}{}
+		if err := c.documentCodec().Decode(data, &fields); err != nil {
+			return "", err
+		}
+		doc = fields
+	} else {
+		docPointer := c.NewObjectByPrototype()
+		if err := c.documentCodec().Decode(data, docPointer.Interface()); err != nil {
+			return "", err
+		}
+		doc = docPointer.Interface()
+	}
+	buf, err := json.Marshal(doc)
+	return string(buf), err
+}
+
+// Encodes a document given as JSON text with the codec of the persistence.
+// Opaque documents are encoded from objects of the prototype to be decoded back.
+func (c *IdentifiableSqlitePersistence) encodeDocument(text string) (interface{}, error) {
+	if c.isJsonCodec() {
+		return c.documentCodec().Encode(json.RawMessage(text))
+	}
+	docPointer := c.NewObjectByPrototype()
+	if err := json.Unmarshal([]byte(text), docPointer.Interface()); err != nil {
+		return nil, err
+	}
+	return c.documentCodec().Encode(c.DereferenceObject(docPointer))
+}
+
+// Updates fields of an opaque document: the document is read, merged with the patch
+// and written back in one transaction.
+func (c *IdentifiableJsonSqlitePersistence) updatePartiallyDecoded(correlationId string, id interface{},
+	patch map[string]interface{}, version interface{}, checkVersion bool) (result interface{}, err error) {
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	before, err := c.readOneById(tx, id, false)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	doc := c.convertToMap(before)
+	if checkVersion && cconv.LongConverter.ToLong(doc[c.VersionColumn]) != cconv.LongConverter.ToLong(version) {
+		return nil, c.checkVersionConflict(tx, correlationId, id, version, nil)
+	}
+	mergeJsonPatch(doc, c.convertToMap(patch))
+
+	docBuf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	docPointer := c.NewObjectByPrototype()
+	if err = json.Unmarshal(docBuf, docPointer.Interface()); err != nil {
+		return nil, err
+	}
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(c.DereferenceObject(docPointer)))
+	delete(row, c.IdColumn)
+	c.applyStamps(row, c.composeStamps(correlationId, false))
+	params, columns := c.GenerateSetParameters(row)
+	values := c.GenerateValues(columns, row)
+	values = append(values, id)
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + params + c.composeVersionIncrement() +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(values)) + c.composeActiveCondition()
+	if _, err = tx.Exec(query, values...); err != nil {
+		return nil, err
+	}
+
+	result, err = c.readOneById(tx, id, false)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	if err = c.recordChange(tx, correlationId, AuditOperationUpdatePartially, id, before, result); err != nil {
+		return nil, err
+	}
 	return result, nil
+}
 
+// Applies an RFC 7396 merge patch: null values remove fields and objects are merged recursively.
+func mergeJsonPatch(doc map[string]interface{}, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			if docObject, ok := doc[key].(map[string]interface{}); ok {
+				mergeJsonPatch(docObject, patchObject)
+				continue
+			}
+			object := map[string]interface{}{}
+			mergeJsonPatch(object, patchObject)
+			value = object
+		}
+		doc[key] = value
+	}
 }
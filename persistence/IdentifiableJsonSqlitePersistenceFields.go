@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Definition of a generated column that exposes a field of JSON documents.
+type sqliteJsonField struct {
+	path    string
+	column  string
+	sqlType string
+	indexed bool
+	unique  bool
+}
+
+// Adds a virtual generated column that extracts a field from JSON documents to create it on opening.
+// The column is named after the path with dots replaced by underscores, for instance $.key becomes "key"
+// and $.address.city becomes "address_city", so filters, sorts and projections can reference the field by name and use its index.
+// Columns are added to existing tables as well. To change the path of an existing column
+// drop it in a migration. Documents of codecs that don't store JSON text can't be read by SQL,
+// so their fields are kept in regular columns filled on writes.
+// - path        a JSON path of the field like $.key.
+// - sqlType     (optional) SQL type of the column like TEXT or INTEGER.
+// - indexed     true to create an index on the column.
+// - unique      true to create a unique index on the column.
+func (c *IdentifiableJsonSqlitePersistence) EnsureJsonField(path string, sqlType string, indexed bool, unique bool) {
+	c.jsonFields = append(c.jsonFields, &sqliteJsonField{
+		path:    path,
+		column:  jsonPathColumnName(path),
+		sqlType: sqlType,
+		indexed: indexed || unique,
+		unique:  unique,
+	})
+}
+
+// Converts a JSON path to a column name: $.address.city becomes address_city.
+func jsonPathColumnName(path string) string {
+	name := strings.TrimLeft(strings.TrimPrefix(path, "$"), ".")
+	return strings.NewReplacer(".", "_", "[", "_", "]", "", "\"", "").Replace(name)
+}
+
+// Checks if a column is generated from a declared JSON field.
+func (c *SqlitePersistence) isJsonFieldColumn(column string) bool {
+	for _, field := range c.jsonFields {
+		if strings.EqualFold(field.column, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if an index is created for a declared JSON field.
+func (c *SqlitePersistence) isJsonFieldIndex(index string) bool {
+	for _, field := range c.jsonFields {
+		if field.indexed && strings.EqualFold(c.composeJsonFieldIndexName(field), index) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *SqlitePersistence) composeJsonFieldIndexName(field *sqliteJsonField) string {
+	return c.TableName + "_" + field.column
+}
+
+// Adds missing generated columns of JSON fields and their indexes.
+func (c *SqlitePersistence) createJsonFields(correlationId string) error {
+	columns, err := c.Connection.GetColumns(correlationId, c.TableName)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		existing[strings.ToLower(column.Name)] = true
+	}
+
+	table := c.QuoteIdentifier(c.TableName)
+	for _, field := range c.jsonFields {
+		column := c.QuoteIdentifier(field.column)
+		if !existing[strings.ToLower(field.column)] {
+			definition := column
+			if field.sqlType != "" {
+				definition += " " + field.sqlType
+			}
+			if !c.storedJsonFields {
+				definition += " GENERATED ALWAYS AS (JSON_EXTRACT(\"data\", '" + strings.ReplaceAll(field.path, "'", "''") + "')) VIRTUAL"
+			}
+			if _, err = c.Client.Exec("ALTER TABLE " + table + " ADD COLUMN " + definition); err != nil {
+				return err
+			}
+			c.Logger.Debug(correlationId, "Added column %s for JSON field %s to %s", field.column, field.path, c.TableName)
+		}
+
+		if field.indexed {
+			query := "CREATE INDEX IF NOT EXISTS "
+			if field.unique {
+				query = "CREATE UNIQUE INDEX IF NOT EXISTS "
+			}
+			query += c.QuoteIdentifier(c.composeJsonFieldIndexName(field)) + " ON " + table + " (" + column + ")"
+			if _, err = c.Client.Exec(query); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Extracts a value from a document by a JSON path like $.address.city or $.tags[0].
+// Values are converted like JSON_EXTRACT does: booleans become 1 or 0, objects and arrays become JSON text.
+func extractJsonPath(doc interface{}, path string) interface{} {
+	value := doc
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" && value != nil {
+		switch {
+		case strings.HasPrefix(rest, "[") && strings.Contains(rest, "]"):
+			end := strings.Index(rest, "]")
+			index, err := strconv.Atoi(rest[1:end])
+			items, ok := value.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(items) {
+				return nil
+			}
+			value, rest = items[index], rest[end+1:]
+		case strings.HasPrefix(rest, ".\""):
+			end := strings.Index(rest[2:], "\"")
+			if end < 0 {
+				return nil
+			}
+			fields, _ := value.(map[string]interface{})
+			value, rest = fields[rest[2:end+2]], rest[end+3:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			fields, _ := value.(map[string]interface{})
+			value, rest = fields[rest[1:end+1]], rest[end+1:]
+		default:
+			return nil
+		}
+	}
+
+	switch typed := value.(type) {
+	case bool:
+		if typed {
+			return 1
+		}
+		return 0
+	case map[string]interface{}, []interface{}:
+		buf, _ := json.Marshal(typed)
+		return string(buf)
+	}
+	return value
+}
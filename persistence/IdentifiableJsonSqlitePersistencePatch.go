@@ -0,0 +1,328 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Operations of path-level patches of JSON documents.
+const (
+	// Sets a value, creating the path when it doesn't exist.
+	JsonPatchSet = "set"
+	// Replaces a value only when the path exists.
+	JsonPatchReplace = "replace"
+	// Removes a value.
+	JsonPatchRemove = "remove"
+	// Adds a number to a value, missing values are treated as 0.
+	JsonPatchIncrement = "increment"
+	// Appends a value to an array, missing arrays are created.
+	JsonPatchArrayAppend = "array_append"
+	// Moves a value from one path to another.
+	JsonPatchMove = "move"
+	// Copies a value from one path to another.
+	JsonPatchCopy = "copy"
+	// Checks that a value is equal to the given one, otherwise the patch fails.
+	JsonPatchTest = "test"
+)
+
+/*
+Operation of a path-level patch of JSON documents.
+Paths are SQLite JSON paths like $.a.b or $.tags[0].
+*/
+type SqliteJsonPatchOperation struct {
+	// The operation: set, replace, remove, increment, array_append, move, copy or test.
+	Op string `json:"op"`
+	// The path of the changed value.
+	Path string `json:"path"`
+	// The source path of move and copy operations.
+	From string `json:"from,omitempty"`
+	// The value of set, replace, array_append and test operations, or the increment.
+	Value interface{} `json:"value,omitempty"`
+}
+
+var jsonPatchPathPattern = regexp.MustCompile(`^\$((\.[A-Za-z_][A-Za-z0-9_]*|\."([^"\\]|\\.)*"|\[[0-9]+\]|\[#(-[0-9]+)?\])*)$`)
+
+// Converts an RFC 6902 JSON Patch document to path-level patch operations.
+// JSON pointers are converted to JSON paths, numeric tokens are treated as array indexes
+// and "-" appends to arrays. Adding into the middle of an array is not supported.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - patch             an RFC 6902 JSON Patch document.
+// Returns          patch operations or BadRequestError.
+func ParseJsonPatch(correlationId string, patch []byte) ([]*SqliteJsonPatchOperation, error) {
+	var document []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		From  string          `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(patch, &document); err != nil {
+		return nil, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "JSON Patch document is invalid").
+			WithCause(err)
+	}
+
+	operations := make([]*SqliteJsonPatchOperation, 0, len(document))
+	for _, item := range document {
+		path, appended, err := jsonPointerToPath(correlationId, item.Path)
+		if err != nil {
+			return nil, err
+		}
+		operation := &SqliteJsonPatchOperation{Path: path}
+		if item.Value != nil {
+			if err = json.Unmarshal(item.Value, &operation.Value); err != nil {
+				return nil, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Value of "+item.Path+" is invalid").
+					WithCause(err)
+			}
+		}
+		if item.Op == "move" || item.Op == "copy" {
+			fromAppended := false
+			if operation.From, fromAppended, err = jsonPointerToPath(correlationId, item.From); err != nil {
+				return nil, err
+			}
+			if fromAppended {
+				return nil, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Source path "+item.From+" is invalid")
+			}
+		}
+
+		switch item.Op {
+		case "add":
+			operation.Op = JsonPatchSet
+			if appended {
+				operation.Op = JsonPatchArrayAppend
+			} else if strings.HasSuffix(path, "]") {
+				return nil, cerr.NewBadRequestError(correlationId, "UNSUPPORTED_PATCH",
+					"Adding into the middle of array "+item.Path+" is not supported")
+			}
+		case "remove", "replace", "test":
+			operation.Op = item.Op
+		case "move", "copy":
+			operation.Op = item.Op
+		default:
+			return nil, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Patch operation "+item.Op+" is not supported")
+		}
+		if appended && operation.Op != JsonPatchArrayAppend {
+			return nil, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Path "+item.Path+" is invalid for "+item.Op)
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// Converts a JSON pointer to a JSON path. The flag is set when the pointer ends with "-".
+func jsonPointerToPath(correlationId string, pointer string) (path string, appended bool, err error) {
+	if pointer == "" {
+		return "", false, cerr.NewBadRequestError(correlationId, "UNSUPPORTED_PATCH", "Patches of whole documents are not supported")
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return "", false, cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "JSON pointer "+pointer+" is invalid")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	path = "$"
+	for index, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if token == "-" && index == len(tokens)-1 {
+			return path, true, nil
+		}
+		if _, err := strconv.ParseUint(token, 10, 32); err == nil {
+			path += "[" + token + "]"
+		} else {
+			path += ".\"" + strings.ReplaceAll(strings.ReplaceAll(token, "\\", "\\\\"), "\"", "\\\"") + "\""
+		}
+	}
+	return path, false, nil
+}
+
+// Composes an expression that applies an operation to the document d.
+// Returns the expression, a condition for test operations and appends bound values.
+func (c *IdentifiableJsonSqlitePersistence) composePatchOperation(correlationId string, operation *SqliteJsonPatchOperation,
+	values *[]interface{}) (expression string, condition string, err error) {
+	if !jsonPatchPathPattern.MatchString(operation.Path) {
+		return "", "", cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "JSON path "+operation.Path+" is invalid")
+	}
+	if (operation.Op == JsonPatchMove || operation.Op == JsonPatchCopy) && !jsonPatchPathPattern.MatchString(operation.From) {
+		return "", "", cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "JSON path "+operation.From+" is invalid")
+	}
+
+	param := func(value interface{}) string {
+		*values = append(*values, value)
+		return "?" + strconv.Itoa(len(*values))
+	}
+	jsonParam := func(value interface{}) (string, error) {
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return "JSON(" + param(string(buf)) + ")", nil
+	}
+
+	switch operation.Op {
+	case JsonPatchSet, JsonPatchReplace:
+		value, err := jsonParam(operation.Value)
+		if err != nil {
+			return "", "", err
+		}
+		function := "JSON_SET"
+		if operation.Op == JsonPatchReplace {
+			function = "JSON_REPLACE"
+		}
+		return function + "(d, " + param(operation.Path) + ", " + value + ")", "", nil
+	case JsonPatchRemove:
+		return "JSON_REMOVE(d, " + param(operation.Path) + ")", "", nil
+	case JsonPatchIncrement:
+		increment := operation.Value
+		if increment == nil {
+			increment = 1
+		}
+		if _, ok := increment.(string); ok {
+			return "", "", cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Increment of "+operation.Path+" must be a number")
+		}
+		path := param(operation.Path)
+		return "JSON_SET(d, " + path + ", COALESCE(JSON_EXTRACT(d, " + path + "), 0) + " + param(increment) + ")", "", nil
+	case JsonPatchArrayAppend:
+		value, err := jsonParam(operation.Value)
+		if err != nil {
+			return "", "", err
+		}
+		path := param(operation.Path)
+		return "CASE WHEN JSON_TYPE(d, " + path + ") IS NULL THEN JSON_SET(d, " + path + ", JSON_ARRAY(" + value + "))" +
+			" ELSE JSON_INSERT(d, " + param(operation.Path+"[#]") + ", " + value + ") END", "", nil
+	case JsonPatchMove:
+		from := param(operation.From)
+		return "JSON_SET(JSON_REMOVE(d, " + from + "), " + param(operation.Path) + ", JSON_EXTRACT(d, " + from + "))", "", nil
+	case JsonPatchCopy:
+		return "JSON_SET(d, " + param(operation.Path) + ", JSON_EXTRACT(d, " + param(operation.From) + "))", "", nil
+	case JsonPatchTest:
+		value, err := jsonParam(operation.Value)
+		if err != nil {
+			return "", "", err
+		}
+		return "", "JSON_EXTRACT(d, " + param(operation.Path) + ") IS JSON_EXTRACT(" + value + ", '$')", nil
+	}
+	return "", "", cerr.NewBadRequestError(correlationId, "INVALID_PATCH", "Patch operation "+operation.Op+" is not supported")
+}
+
+// Applies path-level patch operations to a JSON document in a single atomic UPDATE statement
+// that returns the changed document. Operations are applied in order, each one sees changes
+// of the previous ones. When a test operation fails the document is not changed.
+// Documents of codecs that don't store JSON text can't be patched.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be patched.
+// - operations        patch operations to be applied.
+// Returns          patched item, nil when the item doesn't exist or error.
+func (c *IdentifiableJsonSqlitePersistence) PatchById(correlationId string, id interface{},
+	operations []*SqliteJsonPatchOperation) (result interface{}, err error) {
+	if id == nil {
+		return nil, nil
+	}
+	if !c.isJsonCodec() {
+		return nil, cerr.NewUnsupportedError(correlationId, "NOT_SUPPORTED",
+			"Path-level patches require JSON documents in "+c.TableName)
+	}
+	// Patches are written in the current shape
+	if err = c.upgradeDocument(correlationId, id); err != nil {
+		return nil, err
+	}
+
+	// Stamps inside documents are patched as well
+	operations = append(make([]*SqliteJsonPatchOperation, 0, len(operations)), operations...)
+	stamps := c.composeStamps(correlationId, false)
+	if c.StampsInData {
+		for field, value := range stamps {
+			operations = append(operations, &SqliteJsonPatchOperation{Op: JsonPatchSet, Path: "$." + field, Value: value})
+		}
+	}
+
+	// Every operation is a layer of nested subqueries over the document d,
+	// so expressions don't repeat the document
+	values := make([]interface{}, 0)
+	document := "SELECT " + c.QuoteIdentifier(c.TableName) + ".\"data\" AS d"
+	conditions := make([]string, 0)
+	for _, operation := range operations {
+		expression, condition, err := c.composePatchOperation(correlationId, operation, &values)
+		if err != nil {
+			return nil, err
+		}
+		if condition != "" {
+			conditions = append(conditions, "(SELECT "+condition+" FROM ("+document+"))")
+			continue
+		}
+		document = "SELECT " + expression + " AS d FROM (" + document + ")"
+	}
+
+	params := "\"data\"=(" + document + ")"
+	if !c.StampsInData {
+		for column, value := range stamps {
+			values = append(values, value)
+			params += "," + c.QuoteIdentifier(column) + "=?" + strconv.Itoa(len(values))
+		}
+	}
+	values = append(values, id)
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + params + c.composeVersionIncrement() +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(values)) + c.composeActiveCondition()
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " RETURNING *"
+
+	result, err = c.patchRow(correlationId, id, query, values, len(conditions) > 0)
+	if err == nil && result != nil {
+		c.Logger.Trace(correlationId, "Patched in %s with id = %s", c.TableName, id)
+	}
+	return result, err
+}
+
+// Executes an update statement that returns the changed data item and records the change.
+// Returns the updated item, nil when the item doesn't exist or error.
+func (c *IdentifiableJsonSqlitePersistence) patchRow(correlationId string, id interface{},
+	query string, values []interface{}, tested bool) (result interface{}, err error) {
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	var before interface{}
+	if c.recordsChanges() {
+		if before, err = c.readOneById(db, id, false); err != nil || before == nil {
+			return nil, err
+		}
+	}
+
+	qResult, qErr := db.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	if qResult.Next() {
+		result = c.Overrides.ConvertToPublic(qResult)
+	}
+	err = qResult.Err()
+	qResult.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		if tested {
+			if current, err := c.readOneById(db, id, false); err != nil || current == nil {
+				return nil, err
+			}
+			return nil, cerr.NewConflictError(correlationId, "PATCH_TEST_FAILED",
+				"Test of patch for item "+fmt.Sprint(id)+" in "+c.TableName+" failed").
+				WithDetails("id", id)
+		}
+		return nil, nil
+	}
+	if err = c.recordChange(db, correlationId, AuditOperationUpdatePartially, id, before, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,248 @@
+package persistence
+
+import (
+	"encoding/json"
+	"strconv"
+
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
+)
+
+// Transforms a JSON document from one version of its shape to the next one.
+// - doc     a document of the version the upcaster is registered for.
+// Returns the document of the next version or error.
+type SqliteUpcaster func(doc map[string]interface{}) (map[string]interface{}, error)
+
+// Registers an upcaster that transforms documents of a given version to the next version.
+// The current version of documents is the one after the last registered step, new and updated
+// documents are stored with it. Outdated documents are upcasted on reading, rewritten before partial
+// updates and by UpgradeDocuments. Documents written before versioning was enabled have version 1.
+// Registering an upcaster enables the doc_version column, existing tables get it with auto_migrate option or a migration.
+// Filters in SQL see stored documents, so they shall expect older shapes until all documents are upgraded.
+// - version     the version of documents transformed by the upcaster, starting from 1.
+// - upcaster    the function that transforms documents to the next version.
+func (c *IdentifiableJsonSqlitePersistence) RegisterUpcaster(version int, upcaster SqliteUpcaster) {
+	if c.upcasters == nil {
+		c.upcasters = make(map[int]SqliteUpcaster)
+	}
+	c.upcasters[version] = upcaster
+	if c.DocVersionColumn == "" {
+		c.DocVersionColumn = "doc_version"
+	}
+}
+
+// Gets the current version of stored JSON documents.
+// Returns the version after the last registered upcaster or 1 when there are no upcasters.
+func (c *IdentifiableSqlitePersistence) DocVersion() int {
+	version := 1
+	for step := range c.upcasters {
+		if step+1 > version {
+			version = step + 1
+		}
+	}
+	return version
+}
+
+// Converts a value of the doc version column. Missing versions are treated as the first version.
+func docVersionOf(value interface{}) int {
+	if value == nil {
+		return 1
+	}
+	return cconv.IntegerConverter.ToInteger(value)
+}
+
+// Transforms a JSON document of a given version to the current version.
+// Steps without upcasters don't change documents.
+func (c *IdentifiableSqlitePersistence) upcastDocument(data string, version int) (string, error) {
+	current := c.DocVersion()
+	if version >= current {
+		return data, nil
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return "", err
+	}
+	for ; version < current; version++ {
+		if upcaster, ok := c.upcasters[version]; ok {
+			next, err := upcaster(doc)
+			if err != nil {
+				return "", err
+			}
+			doc = next
+		}
+	}
+	buf, err := json.Marshal(doc)
+	return string(buf), err
+}
+
+// Rewrites outdated JSON documents in the current version.
+// Documents are read and rewritten in batches, each document is rewritten only when its version
+// didn't change since it was read, so concurrent writes are not overwritten.
+// Versions and stamps of data items are not changed because the content stays the same.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns          a number of rewritten documents or error.
+func (c *IdentifiableSqlitePersistence) UpgradeDocuments(correlationId string) (count int64, err error) {
+	if c.DocVersionColumn == "" || len(c.upcasters) == 0 {
+		return 0, nil
+	}
+	batchSize := c.UpgradeBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	for {
+		upgraded, found, err := c.upgradeRows(correlationId, "", nil, batchSize)
+		count += upgraded
+		if err != nil {
+			return count, err
+		}
+		// Stop when everything is upgraded or documents are changed concurrently
+		if found < batchSize || upgraded == 0 {
+			break
+		}
+	}
+
+	if count > 0 {
+		c.Logger.Debug(correlationId, "Upgraded %d documents in %s to version %d", count, c.TableName, c.DocVersion())
+	}
+	return count, nil
+}
+
+// Rewrites an outdated JSON document with a given id in the current version.
+func (c *IdentifiableSqlitePersistence) upgradeDocument(correlationId string, id interface{}) error {
+	if c.DocVersionColumn == "" || len(c.upcasters) == 0 {
+		return nil
+	}
+	_, _, err := c.upgradeRows(correlationId, c.QuoteIdentifier(c.IdColumn)+"=?2", []interface{}{id}, 1)
+	return err
+}
+
+// Reads a batch of outdated documents that match to a condition and rewrites them in a transaction.
+// The condition can reference values starting from ?2.
+// Returns the number of rewritten documents, the number of found documents or error.
+func (c *IdentifiableSqlitePersistence) upgradeRows(correlationId string, condition string, values []interface{},
+	limit int) (upgraded int64, found int, err error) {
+	table := c.QuoteIdentifier(c.TableName)
+	idColumn := c.QuoteIdentifier(c.IdColumn)
+	versionColumn := c.QuoteIdentifier(c.DocVersionColumn)
+	current := c.DocVersion()
+
+	query := "SELECT " + idColumn + ", \"data\", " + versionColumn + " FROM " + table +
+		" WHERE (" + versionColumn + " IS NULL OR " + versionColumn + "<?1)"
+	if condition != "" {
+		query += " AND " + condition
+	}
+	query += " LIMIT " + strconv.Itoa(limit)
+
+	type outdatedRow struct {
+		id      interface{}
+		data    interface{}
+		version interface{}
+	}
+	rows := make([]*outdatedRow, 0)
+	qResult, err := c.Client.Query(query, append([]interface{}{current}, values...)...)
+	if err != nil {
+		return 0, 0, err
+	}
+	for qResult.Next() {
+		row := &outdatedRow{}
+		if err = qResult.Scan(&row.id, &row.data, &row.version); err != nil {
+			qResult.Close()
+			return 0, 0, err
+		}
+		rows = append(rows, row)
+	}
+	err = qResult.Err()
+	qResult.Close()
+	if err != nil || len(rows) == 0 {
+		return 0, 0, err
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	update := "UPDATE " + table + " SET \"data\"=?1, " + versionColumn + "=?2"
+	if c.storedJsonFields {
+		// Columns of opaque documents are refreshed with their new fields
+		for index, field := range c.jsonFields {
+			update += ", " + c.QuoteIdentifier(field.column) + "=?" + strconv.Itoa(index+5)
+		}
+	}
+	update += " WHERE " + idColumn + "=?3 AND " + versionColumn + " IS ?4"
+	for _, row := range rows {
+		data, fields, err := c.upcastRow(row.data, docVersionOf(row.version))
+		if err != nil {
+			tx.Rollback()
+			return 0, len(rows), cerr.NewInternalError(correlationId, "UPCAST_FAILED",
+				"Failed to upcast document in "+c.TableName).
+				WithDetails("id", row.id).WithDetails("version", docVersionOf(row.version)).
+				WithCause(err)
+		}
+		result, err := tx.Exec(update, append([]interface{}{data, current, row.id, row.version}, fields...)...)
+		if err != nil {
+			tx.Rollback()
+			return 0, len(rows), err
+		}
+		affected, _ := result.RowsAffected()
+		upgraded += affected
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, len(rows), err
+	}
+	return upgraded, len(rows), nil
+}
+
+// Upcasts a value of the data column and encodes it back with the codec of the persistence.
+// Returns the new value and values of stored JSON fields or error.
+func (c *IdentifiableSqlitePersistence) upcastRow(data interface{}, version int) (interface{}, []interface{}, error) {
+	if c.isJsonCodec() {
+		text, err := c.upcastDocument(cconv.StringConverter.ToString(data), version)
+		return text, nil, err
+	}
+	text, err := c.decodeDocument(data, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if text, err = c.upcastDocument(text, version); err != nil {
+		return nil, nil, err
+	}
+
+	fields := make([]interface{}, 0, len(c.jsonFields))
+	if c.storedJsonFields && len(c.jsonFields) > 0 {
+		doc := map[string]interface{}{}
+		if err = json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, nil, err
+		}
+		for _, field := range c.jsonFields {
+			fields = append(fields, extractJsonPath(doc, field.path))
+		}
+	}
+	encoded, err := c.encodeDocument(text)
+	return encoded, fields, err
+}
+
+// Starts the background job that rewrites outdated documents.
+func (c *IdentifiableSqlitePersistence) startUpgrade(correlationId string) {
+	if c.UpgradeInterval <= 0 || c.DocVersionColumn == "" || len(c.upcasters) == 0 || c.upgradeTimer != nil {
+		return
+	}
+	c.upgradeTimer = crun.NewFixedRateTimerFromCallback(func() {
+		if !c.IsOpen() {
+			return
+		}
+		if _, err := c.UpgradeDocuments(correlationId); err != nil {
+			c.Logger.Error(correlationId, err, "Failed to upgrade documents in %s", c.TableName)
+		}
+	}, c.UpgradeInterval, c.UpgradeInterval)
+	c.upgradeTimer.Start()
+}
+
+// Stops the background job that rewrites outdated documents.
+func (c *IdentifiableSqlitePersistence) stopUpgrade() {
+	if c.upgradeTimer != nil {
+		c.upgradeTimer.Stop()
+		c.upgradeTimer = nil
+	}
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Composes the statement that creates the view over JSON documents.
+// Columns of the table except data come first, top-level fields of the prototype
+// that are not kept in columns are extracted from JSON documents and cast to their SQL types.
+func (c *IdentifiableSqlitePersistence) composeJsonView(correlationId string) (string, error) {
+	columns, err := c.Connection.GetColumns(correlationId, c.TableName)
+	if err != nil {
+		return "", err
+	}
+
+	table := c.QuoteIdentifier(c.TableName)
+	names := make(map[string]bool)
+	expressions := make([]string, 0)
+	for _, column := range columns {
+		if column.Hidden && !column.Generated || column.Name == "data" {
+			continue
+		}
+		names[strings.ToLower(column.Name)] = true
+		expressions = append(expressions, table+"."+c.QuoteIdentifier(column.Name))
+	}
+
+	// Opaque documents can't be read by SQL
+	fields := jsonFieldsOf(c.Prototype)
+	if !c.isJsonCodec() {
+		fields = nil
+	}
+	for _, field := range fields {
+		if names[strings.ToLower(field.name)] {
+			continue
+		}
+		names[strings.ToLower(field.name)] = true
+		expression := "JSON_EXTRACT(" + table + ".\"data\", '$.\"" +
+			strings.ReplaceAll(strings.ReplaceAll(field.name, "\"", "\\\""), "'", "''") + "\"')"
+		if field.sqlType != "" {
+			expression = "CAST(" + expression + " AS " + field.sqlType + ")"
+		}
+		expressions = append(expressions, expression+" AS "+c.QuoteIdentifier(field.name))
+	}
+
+	return "CREATE VIEW " + c.QuoteIdentifier(c.JsonViewName) + " AS SELECT " +
+		strings.Join(expressions, ", ") + " FROM " + table, nil
+}
+
+// Creates the view over JSON documents or recreates it when the prototype or the table has changed.
+func (c *IdentifiableSqlitePersistence) createJsonView(correlationId string) error {
+	statement, err := c.composeJsonView(correlationId)
+	if err != nil {
+		return err
+	}
+
+	var existing string
+	err = c.Client.QueryRow("SELECT COALESCE(MAX(sql), '') FROM sqlite_master WHERE type='view' AND name=?1",
+		c.JsonViewName).Scan(&existing)
+	if err != nil || existing == statement {
+		return err
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{"DROP VIEW IF EXISTS " + c.QuoteIdentifier(c.JsonViewName), statement} {
+		if _, err = tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	c.Logger.Debug(correlationId, "Created view %s over %s", c.JsonViewName, c.TableName)
+	return nil
+}
+
+// Field of JSON documents that is flattened into a view column.
+type sqliteJsonViewField struct {
+	name    string
+	sqlType string
+}
+
+// Gets top-level fields of JSON documents serialized from a prototype.
+// Fields of embedded structs are promoted like in encoding/json.
+func jsonFieldsOf(proto reflect.Type) []*sqliteJsonViewField {
+	for proto != nil && proto.Kind() == reflect.Ptr {
+		proto = proto.Elem()
+	}
+	fields := make([]*sqliteJsonViewField, 0)
+	if proto == nil || proto.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < proto.NumField(); i++ {
+		field := proto.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" && !strings.HasPrefix(tag, "-,") {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			fields = append(fields, jsonFieldsOf(field.Type)...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, &sqliteJsonViewField{name: name, sqlType: sqlTypeOf(field.Type)})
+	}
+	return fields
+}
+
+// Gets the SQL type of JSON values serialized from a Go type.
+// Returns empty string for objects and arrays that are kept as JSON text.
+func sqlTypeOf(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "TEXT"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	}
+	return ""
+}
@@ -1,10 +1,18 @@
 package persistence
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
 	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
 	cmpersist "github.com/pip-services3-go/pip-services3-data-go/persistence"
 )
 
@@ -36,12 +44,42 @@ accessing c._collection and c._model properties.
   - connect_timeout:      (optional) number of milliseconds to wait before timing out when connecting a new client (default: 0)
   - idle_timeout:         (optional) number of milliseconds a client must sit idle in the pool and not be checked out (default: 10000)
   - max_pool_size:        (optional) maximum number of clients the pool should contain (default: 10)
+  - id_column:            (optional) name of the id column (default: id)
+  - id_autoincrement:     (optional) lets SQLite assign ids to INTEGER PRIMARY KEY column on create (default: false)
+  - id_generator:         (optional) id generation strategy: object_id, uuid7, ulid, snowflake or sequence (default: object_id)
+  - id_node:              (optional) node id of snowflake generator from 0 to 1023 (default: 0)
+  - id_sequence:          (optional) sequence name of sequence generator (default: table name)
+  - id_prefix:            (optional) prefix of ids generated from sequence (default: "")
+  - id_digits:            (optional) minimum number of digits in ids generated from sequence (default: 6)
+  - max_ids_per_query:    (optional) maximum number of ids bound in a single query, longer lists are split (default: 500)
+  - version_column:       (optional) name of the version column for optimistic locking (default: json name of Version field in the prototype)
+  - timestamps:           (optional) maintains created_at and updated_at columns (default: false)
+  - actor_stamps:         (optional) maintains created_by and updated_by columns (default: false)
+  - created_at_column:    (optional) name of the creation time column
+  - updated_at_column:    (optional) name of the modification time column
+  - created_by_column:    (optional) name of the column with the actor who created the item
+  - updated_by_column:    (optional) name of the column with the actor who modified the item
+  - stamps_in_data:       (optional) keeps stamps inside JSON documents instead of separate columns (default: false)
+  - audit:                (optional) records changes in the audit table named after the table with _audit suffix (default: false)
+  - audit_table:          (optional) name of the audit table, setting it enables the audit
+  - temporal:             (optional) keeps versions of items stamped with the system time in the temporal table named after the table with _history suffix (default: false)
+  - temporal_table:       (optional) name of the temporal table, setting it enables the temporal mode
+  - temporal_retention:   (optional) number of days to keep versions that are no longer valid, older versions are pruned on opening (default: 0 - forever)
+  - doc_version_column:   (optional) name of the column with versions of JSON document shapes (default: doc_version when upcasters are registered)
+  - upgrade_interval:     (optional) interval in milliseconds to rewrite outdated JSON documents in background, 0 disables the job (default: 0)
+  - upgrade_batch_size:   (optional) maximum number of documents rewritten in one statement batch (default: 100)
+  - json_view:            (optional) creates a view with fields of JSON documents named after the table with _v suffix (default: false)
+  - json_view_name:       (optional) name of the view with fields of JSON documents, setting it enables the view
+  - codec:                (optional) codec of JSON documents: json, gzip or gob (default: json)
+- dependencies:
+  - outbox:               (optional) descriptor of SqliteOutbox that receives events about changes
  *
 ### References ###
  *
 - \*:logger:\*:\*:1.0           (optional) [[https://rawgit.com/pip-services-node/pip-services3-components-node/master/doc/api/interfaces/log.ilogger.html ILogger]] components to pass log messages components to pass log messages
 - \*:discovery:\*:\*:1.0        (optional) [[https://rawgit.com/pip-services-node/pip-services3-components-node/master/doc/api/interfaces/connect.idiscovery.html IDiscovery]] services
 - \*:credential-store:\*:\*:1.0 (optional) Credential stores to resolve credentials
+- outbox                      (optional) [[SqliteOutbox]] resolved when dependencies.outbox is configured
  *
 ### Example ###
  *
@@ -94,6 +132,56 @@ accessing c._collection and c._model properties.
 */
 type IdentifiableSqlitePersistence struct {
 	*SqlitePersistence
+
+	//The name of the id column.
+	IdColumn string
+	//The flag to let SQLite assign ids to INTEGER PRIMARY KEY column.
+	AutoIncrementId bool
+	//The generator of ids for new data items. When nil random ids are generated.
+	IdGenerator IdGenerator
+	//The maximum number of ids bound in a single query.
+	MaxIdsPerQuery int
+	//The name of the version column for optimistic locking. Empty string disables locking.
+	VersionColumn string
+	//The names of stamp columns. Empty names disable stamps.
+	CreatedAtColumn string
+	UpdatedAtColumn string
+	CreatedByColumn string
+	UpdatedByColumn string
+	//The flag to keep stamps inside JSON documents (JSON persistence only).
+	StampsInData bool
+	//The provider of actors who change data items. When nil correlation ids are used as actors.
+	ActorProvider ActorProvider
+	//The name of the audit table that records changes. Empty name disables the audit.
+	AuditTableName string
+	//The name of the temporal table that keeps versions of data items. Empty name disables the temporal mode.
+	//Versions are stamped by SQLite with the system time, the Clock doesn't change them.
+	TemporalTableName string
+	//The period to keep versions that are no longer valid. Zero keeps them forever.
+	TemporalRetention time.Duration
+	//The outbox that receives events about changes in the same transactions. Nil disables events.
+	//The outbox table must be in the same database as the persistence table.
+	Outbox *SqliteOutbox
+	//The name of the column that keeps versions of JSON document shapes. Empty name disables versioning (JSON persistence only).
+	DocVersionColumn string
+	//The interval in milliseconds to rewrite outdated JSON documents in background. Zero disables the job.
+	UpgradeInterval int
+	//The maximum number of JSON documents rewritten in one batch.
+	UpgradeBatchSize int
+	//The name of the view that flattens top-level fields of JSON documents into columns. Empty name disables the view (JSON persistence only).
+	JsonViewName string
+	//The codec of documents in the data column. When nil documents are stored as JSON text (JSON persistence only).
+	DocumentCodec SqliteDocumentCodec
+
+	idGeneratorType string
+	idNode          int
+	idSequence      string
+	idPrefix        string
+	idDigits        int
+	temporalColumns string
+	codecType       string
+	upcasters       map[int]SqliteUpcaster
+	upgradeTimer    *crun.FixedRateTimer
 }
 
 //    Creates a new instance of the persistence component.
@@ -104,37 +192,260 @@ func InheritIdentifiableSqlitePersistence(overrides ISqlitePersistenceOverrides,
 		panic("Table name could not be empty")
 	}
 
-	c := &IdentifiableSqlitePersistence{}
+	c := &IdentifiableSqlitePersistence{
+		IdColumn:         "id",
+		MaxIdsPerQuery:   500,
+		VersionColumn:    versionColumnOf(proto),
+		UpgradeBatchSize: 100,
+		idDigits:         6,
+	}
 	c.SqlitePersistence = InheritSqlitePersistence(overrides, proto, tableName)
 	return c
 }
 
+// Configures component by passing configuration parameters.
+// - config    configuration parameters to be set.
+func (c *IdentifiableSqlitePersistence) Configure(config *cconf.ConfigParams) {
+	c.SqlitePersistence.Configure(config)
+
+	c.IdColumn = config.GetAsStringWithDefault("options.id_column", c.IdColumn)
+	c.AutoIncrementId = config.GetAsBooleanWithDefault("options.id_autoincrement", c.AutoIncrementId)
+	c.MaxIdsPerQuery = config.GetAsIntegerWithDefault("options.max_ids_per_query", c.MaxIdsPerQuery)
+	c.VersionColumn = config.GetAsStringWithDefault("options.version_column", c.VersionColumn)
+	if config.GetAsBooleanWithDefault("options.timestamps", false) {
+		c.CreatedAtColumn, c.UpdatedAtColumn = "created_at", "updated_at"
+	}
+	if config.GetAsBooleanWithDefault("options.actor_stamps", false) {
+		c.CreatedByColumn, c.UpdatedByColumn = "created_by", "updated_by"
+	}
+	c.CreatedAtColumn = config.GetAsStringWithDefault("options.created_at_column", c.CreatedAtColumn)
+	c.UpdatedAtColumn = config.GetAsStringWithDefault("options.updated_at_column", c.UpdatedAtColumn)
+	c.CreatedByColumn = config.GetAsStringWithDefault("options.created_by_column", c.CreatedByColumn)
+	c.UpdatedByColumn = config.GetAsStringWithDefault("options.updated_by_column", c.UpdatedByColumn)
+	c.StampsInData = config.GetAsBooleanWithDefault("options.stamps_in_data", c.StampsInData)
+	if config.GetAsBooleanWithDefault("options.audit", false) {
+		c.AuditTableName = c.TableName + "_audit"
+	}
+	c.AuditTableName = config.GetAsStringWithDefault("options.audit_table", c.AuditTableName)
+	if config.GetAsBooleanWithDefault("options.temporal", false) {
+		c.TemporalTableName = c.TableName + "_history"
+	}
+	c.TemporalTableName = config.GetAsStringWithDefault("options.temporal_table", c.TemporalTableName)
+	if days := config.GetAsIntegerWithDefault("options.temporal_retention", 0); days > 0 {
+		c.TemporalRetention = time.Duration(days) * 24 * time.Hour
+	}
+	c.DocVersionColumn = config.GetAsStringWithDefault("options.doc_version_column", c.DocVersionColumn)
+	c.UpgradeInterval = config.GetAsIntegerWithDefault("options.upgrade_interval", c.UpgradeInterval)
+	c.UpgradeBatchSize = config.GetAsIntegerWithDefault("options.upgrade_batch_size", c.UpgradeBatchSize)
+	if config.GetAsBooleanWithDefault("options.json_view", false) {
+		c.JsonViewName = c.TableName + "_v"
+	}
+	c.JsonViewName = config.GetAsStringWithDefault("options.json_view_name", c.JsonViewName)
+	c.codecType = config.GetAsStringWithDefault("options.codec", c.codecType)
+	c.idGeneratorType = config.GetAsStringWithDefault("options.id_generator", c.idGeneratorType)
+	c.idNode = config.GetAsIntegerWithDefault("options.id_node", c.idNode)
+	c.idSequence = config.GetAsStringWithDefault("options.id_sequence", c.idSequence)
+	c.idPrefix = config.GetAsStringWithDefault("options.id_prefix", c.idPrefix)
+	c.idDigits = config.GetAsIntegerWithDefault("options.id_digits", c.idDigits)
+}
+
+// Opens the component, creates the audit, temporal and outbox tables, the view over JSON documents,
+// the id generator set in configuration and starts the upgrade of outdated JSON documents.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns 			error or nil no errors occured.
+func (c *IdentifiableSqlitePersistence) Open(correlationId string) (err error) {
+	if c.IsOpen() {
+		return nil
+	}
+
+	// The schema depends on the codec, so it is created before opening
+	if c.codecType != "" {
+		if c.DocumentCodec, err = createDocumentCodec(correlationId, c.codecType); err != nil {
+			return err
+		}
+	}
+	if !c.isJsonCodec() && c.StampsInData {
+		return cerr.NewConfigError(correlationId, "WRONG_CODEC", "Stamps can be kept in data only by JSON documents")
+	}
+	c.storedJsonFields = !c.isJsonCodec()
+
+	err = c.SqlitePersistence.Open(correlationId)
+	if err != nil {
+		return err
+	}
+
+	if c.isAudited() {
+		if err = c.createAuditTable(); err != nil {
+			c.SqlitePersistence.Close(correlationId)
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create audit table "+c.AuditTableName).
+				WithCause(err)
+		}
+	}
+
+	if c.Outbox == nil && c.references != nil {
+		if dep, ok := c.DependencyResolver.GetOneOptional("outbox").(*SqliteOutbox); ok {
+			c.Outbox = dep
+		}
+	}
+	if c.Outbox != nil {
+		if err = c.Outbox.createTable(c.Client); err != nil {
+			c.SqlitePersistence.Close(correlationId)
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create outbox table "+c.Outbox.TableName).
+				WithCause(err)
+		}
+	}
+
+	if c.JsonViewName != "" {
+		if err = c.createJsonView(correlationId); err != nil {
+			c.SqlitePersistence.Close(correlationId)
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create view "+c.JsonViewName).
+				WithCause(err)
+		}
+	}
+
+	if c.TemporalTableName != "" {
+		if err = c.createTemporalTable(correlationId); err != nil {
+			c.SqlitePersistence.Close(correlationId)
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create temporal table "+c.TemporalTableName).
+				WithCause(err)
+		}
+		if c.Clock != nil {
+			c.Logger.Warn(correlationId, "Versions in %s are stamped with the system time, not the clock of %s",
+				c.TemporalTableName, c.TableName)
+		}
+		if c.TemporalRetention > 0 {
+			// Versions are stamped with the system time
+			if _, err = c.PruneVersions(correlationId, time.Now().Add(-c.TemporalRetention)); err != nil {
+				c.SqlitePersistence.Close(correlationId)
+				return err
+			}
+		}
+	}
+
+	if c.idGeneratorType != "" {
+		c.IdGenerator, err = c.createIdGenerator(correlationId)
+		if err != nil {
+			c.SqlitePersistence.Close(correlationId)
+			return err
+		}
+	}
+
+	c.startUpgrade(correlationId)
+	return nil
+}
+
+// Closes the component, stops the upgrade of JSON documents and frees used resources.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns 			error or nil no errors occured.
+func (c *IdentifiableSqlitePersistence) Close(correlationId string) error {
+	c.stopUpgrade()
+	return c.SqlitePersistence.Close(correlationId)
+}
+
+func (c *IdentifiableSqlitePersistence) createIdGenerator(correlationId string) (IdGenerator, error) {
+	switch strings.ToLower(c.idGeneratorType) {
+	case "object_id", "objectid":
+		return NewObjectIdGenerator(), nil
+	case "uuid7", "uuidv7":
+		return NewUuidV7IdGenerator(), nil
+	case "ulid":
+		return NewUlidIdGenerator(), nil
+	case "snowflake":
+		return NewSnowflakeIdGenerator(c.idNode), nil
+	case "sequence":
+		sequence := c.idSequence
+		if sequence == "" {
+			sequence = c.TableName
+		}
+		return NewSequenceIdGenerator(c.Connection, sequence, c.idPrefix, c.idDigits), nil
+	default:
+		return nil, cerr.NewConfigError(correlationId, "WRONG_ID_GENERATOR",
+			"Id generator "+c.idGeneratorType+" is not supported").
+			WithDetails("id_generator", c.idGeneratorType)
+	}
+}
+
 // Gets a list of data items retrieved by given unique ids.
+// Long lists are split into chunks of MaxIdsPerQuery ids.
 // - correlationId     (optional) transaction id to trace execution through call chain.
 // - ids               ids of data items to be retrieved
 // Returns          a data list or error.
 func (c *IdentifiableSqlitePersistence) GetListByIds(correlationId string, ids []interface{}) (items []interface{}, err error) {
+	return c.getListByIds(correlationId, ids, false)
+}
 
-	params := c.GenerateParameters(ids)
-	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE \"id\" IN(" + params + ")"
+// Gets a list of data items by their ids. Soft-deleted items are read only when includeDeleted is set.
+func (c *IdentifiableSqlitePersistence) getListByIds(correlationId string, ids []interface{}, includeDeleted bool) (items []interface{}, err error) {
+	items = make([]interface{}, 0, len(ids))
 
-	qResult, qErr := c.Client.Query(query, ids...)
-	if qErr != nil {
-		return nil, qErr
+	for _, chunk := range c.splitIds(ids) {
+		params := c.GenerateParameters(chunk)
+		query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) +
+			" WHERE " + c.composeReadFilter(c.QuoteIdentifier(c.IdColumn)+" IN("+params+")", includeDeleted)
+
+		qResult, qErr := c.Client.Query(query, chunk...)
+		if qErr != nil {
+			return nil, qErr
+		}
+		for qResult.Next() {
+			item := c.Overrides.ConvertToPublic(qResult)
+			items = append(items, item)
+		}
+		err = qResult.Err()
+		qResult.Close()
+		if err != nil {
+			return nil, err
+		}
 	}
-	defer qResult.Close()
-	items = make([]interface{}, 0, 0)
-	for qResult.Next() {
 
-		item := c.Overrides.ConvertToPublic(qResult)
-		items = append(items, item)
+	c.Logger.Trace(correlationId, "Retrieved %d from %s", len(items), c.TableName)
+	return items, nil
+}
+
+// Gets a list of data items retrieved by given unique ids
+// in the order of the requested ids.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be retrieved
+// Returns          a data list, a list of ids that were not found or error.
+func (c *IdentifiableSqlitePersistence) GetListByIdsInOrder(correlationId string, ids []interface{}) (items []interface{}, missingIds []interface{}, err error) {
+	return c.getListByIdsInOrder(correlationId, ids, false)
+}
+
+// Gets a list of data items in the order of their ids. Soft-deleted items are read only when includeDeleted is set.
+func (c *IdentifiableSqlitePersistence) getListByIdsInOrder(correlationId string, ids []interface{},
+	includeDeleted bool) (items []interface{}, missingIds []interface{}, err error) {
+	// Duplicated ids are retrieved only once
+	uniqueIds := make([]interface{}, 0, len(ids))
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		key := fmt.Sprint(id)
+		if !requested[key] {
+			requested[key] = true
+			uniqueIds = append(uniqueIds, id)
+		}
+	}
+
+	found, err := c.getListByIds(correlationId, uniqueIds, includeDeleted)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if items != nil {
-		c.Logger.Trace(correlationId, "Retrieved %d from %s", len(items), c.TableName)
+	// Ids read from the database may have different types than the requested ones
+	itemsById := make(map[string]interface{}, len(found))
+	for _, item := range found {
+		itemsById[fmt.Sprint(c.getObjectId(item))] = item
 	}
 
-	return items, qResult.Err()
+	items = make([]interface{}, 0, len(ids))
+	missingIds = make([]interface{}, 0)
+	for _, id := range ids {
+		if item, ok := itemsById[fmt.Sprint(id)]; ok {
+			items = append(items, item)
+		} else {
+			missingIds = append(missingIds, id)
+		}
+	}
+	return items, missingIds, nil
 }
 
 // Gets a data item by its unique id.
@@ -142,8 +453,13 @@ func (c *IdentifiableSqlitePersistence) GetListByIds(correlationId string, ids [
 // - id                an id of data item to be retrieved.
 // Returns           data item or error.
 func (c *IdentifiableSqlitePersistence) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	return c.getOneById(correlationId, id, false)
+}
 
-	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE \"id\"=$1"
+// Gets a data item by its id. Soft-deleted items are read only when includeDeleted is set.
+func (c *IdentifiableSqlitePersistence) getOneById(correlationId string, id interface{}, includeDeleted bool) (item interface{}, err error) {
+
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + c.composeReadFilter(c.QuoteIdentifier(c.IdColumn)+"=?1", includeDeleted)
 
 	qResult, qErr := c.Client.Query(query, id)
 	if qErr != nil {
@@ -172,14 +488,137 @@ func (c *IdentifiableSqlitePersistence) Create(correlationId string, item interf
 	if item == nil {
 		return nil, nil
 	}
+	if c.AutoIncrementId {
+		return c.createWithAutoIncrement(correlationId, item)
+	}
+
 	// Assign unique id
 	var newItem interface{}
 	newItem = cmpersist.CloneObject(item, c.Prototype)
-	cmpersist.GenerateObjectId(&newItem)
+	if err = c.generateObjectId(correlationId, &newItem); err != nil {
+		return nil, err
+	}
+	if c.VersionColumn != "" || c.hasStamps() || c.recordsChanges() {
+		return c.createRow(correlationId, newItem)
+	}
 
 	return c.SqlitePersistence.Create(correlationId, newItem)
 }
 
+// Creates a data item with the initial version and stamps.
+func (c *IdentifiableSqlitePersistence) createRow(correlationId string, item interface{}) (result interface{}, err error) {
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(item))
+	if c.VersionColumn != "" {
+		row[c.VersionColumn] = 1
+	}
+	c.applyStamps(row, c.composeStamps(correlationId, true))
+	columns := c.GenerateColumns(row)
+	params := c.GenerateParameters(row)
+	values := c.GenerateValues(columns, row)
+	query := "INSERT INTO " + c.QuoteIdentifier(c.TableName) + " (" + columns + ") VALUES (" + params + ")"
+
+	_, qErr := db.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	id := c.getObjectId(item)
+	result, err = c.readOneById(db, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.recordChange(db, correlationId, AuditOperationCreate, id, nil, result); err != nil {
+		return nil, err
+	}
+	c.Logger.Trace(correlationId, "Created in %s with id = %s", c.TableName, id)
+	return result, nil
+}
+
+// Creates a data item and lets SQLite assign its id.
+func (c *IdentifiableSqlitePersistence) createWithAutoIncrement(correlationId string, item interface{}) (result interface{}, err error) {
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	var newItem interface{}
+	newItem = cmpersist.CloneObject(item, c.Prototype)
+
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(newItem))
+	if id, ok := row[c.IdColumn]; ok && isZeroId(id) {
+		delete(row, c.IdColumn)
+	}
+	if c.VersionColumn != "" {
+		row[c.VersionColumn] = 1
+	}
+	stamps := c.composeStamps(correlationId, true)
+	c.applyStamps(row, stamps)
+	columns := c.GenerateColumns(row)
+	params := c.GenerateParameters(row)
+	values := c.GenerateValues(columns, row)
+	query := "INSERT INTO " + c.QuoteIdentifier(c.TableName) + " (" + columns + ") VALUES (" + params + ")"
+
+	qResult, qErr := db.Exec(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	id, err := qResult.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	c.setObjectId(&newItem, id)
+
+	// Documents that keep the id inside are rewritten with the assigned id
+	newRow := c.convertToMap(c.Overrides.ConvertFromPublic(newItem))
+	delete(newRow, c.IdColumn)
+	if c.VersionColumn != "" {
+		newRow[c.VersionColumn] = row[c.VersionColumn]
+	}
+	c.applyStamps(newRow, stamps)
+	delete(row, c.IdColumn)
+	oldJson, _ := json.Marshal(row)
+	newJson, _ := json.Marshal(newRow)
+	if string(oldJson) != string(newJson) {
+		setParams, setColumns := c.GenerateSetParameters(newRow)
+		setValues := c.GenerateValues(setColumns, newRow)
+		setValues = append(setValues, id)
+		query = "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + setParams +
+			" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(setValues))
+		_, qErr = db.Exec(query, setValues...)
+		if qErr != nil {
+			return nil, qErr
+		}
+	}
+
+	result = cmpersist.CloneObjectForResult(newItem, c.Prototype)
+	if c.VersionColumn != "" || c.hasStamps() || c.recordsChanges() {
+		result, err = c.readOneById(db, id, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = c.recordChange(db, correlationId, AuditOperationCreate, id, nil, result); err != nil {
+		return nil, err
+	}
+	c.Logger.Trace(correlationId, "Created in %s with id = %d", c.TableName, id)
+	return result, nil
+}
+
 // Sets a data item. If the data item exists it updates it,
 // otherwise it create a new data item.
 // - correlation_id    (optional) transaction id to trace execution through call chain.
@@ -191,41 +630,79 @@ func (c *IdentifiableSqlitePersistence) Set(correlationId string, item interface
 		return nil, nil
 	}
 
+	// Items without ids are created with ids assigned by SQLite
+	if c.AutoIncrementId && isZeroId(c.getObjectId(item)) {
+		return c.createWithAutoIncrement(correlationId, item)
+	}
+
 	// Assign unique id
 	var newItem interface{}
 	newItem = cmpersist.CloneObject(item, c.Prototype)
-	cmpersist.GenerateObjectId(&newItem)
-	row := c.Overrides.ConvertFromPublic(item)
+	if err = c.generateObjectId(correlationId, &newItem); err != nil {
+		return nil, err
+	}
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(newItem))
+	version, checkVersion := row[c.VersionColumn]
+	if c.VersionColumn != "" {
+		// New items start from the first version
+		row[c.VersionColumn] = 1
+	}
+	c.applyStamps(row, c.composeStamps(correlationId, true))
 	params := c.GenerateParameters(row)
 	setParams, columns := c.GenerateSetParameters(row)
 	values := c.GenerateValues(columns, row)
-	id := cmpersist.GetObjectId(newItem)
+	id := c.getObjectId(newItem)
 
 	query := "INSERT INTO " + c.QuoteIdentifier(c.TableName) + " (" + columns + ")" +
 		" VALUES (" + params + ")" +
-		" ON CONFLICT (\"id\") DO UPDATE SET " + setParams
+		" ON CONFLICT (" + c.QuoteIdentifier(c.IdColumn) + ") DO UPDATE SET " + setParams
+	if c.SoftDelete {
+		// Setting a soft-deleted item restores it
+		query += "," + c.QuoteIdentifier(c.DeletedColumn) + "=NULL"
+	}
+	query += c.composeStampsPreservation(row, &values)
+	if c.VersionColumn != "" {
+		query += c.composeVersionIncrement()
+		if checkVersion {
+			values = append(values, version)
+			query += " WHERE " + c.QuoteIdentifier(c.VersionColumn) + "=?" + strconv.Itoa(len(values))
+		}
+	}
+
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	var before interface{}
+	if c.recordsChanges() {
+		if before, err = c.readOneById(db, id, true); err != nil {
+			return nil, err
+		}
+	}
 
-	qResult, qErr := c.Client.Query(query, values...)
+	qResult, qErr := db.Exec(query, values...)
 	if qErr != nil {
 		return nil, qErr
 	}
-	defer qResult.Close()
-	qResult.Next()
-	if qResult.Err() != nil {
-		return nil, qResult.Err()
+	if c.VersionColumn != "" {
+		if count, err := qResult.RowsAffected(); err != nil || count == 0 {
+			return nil, c.checkVersionConflict(db, correlationId, id, version, err)
+		}
 	}
 
-	query = "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE id=$1"
-	qResult2, qErr2 := c.Client.Query(query, id)
-	if qErr2 != nil {
-		return nil, qErr2
+	result, err = c.readOneById(db, id, false)
+	if err != nil || result == nil {
+		return nil, err
 	}
-	defer qResult2.Close()
-	if !qResult2.Next() {
-		return nil, qResult2.Err()
+	if err = c.recordChange(db, correlationId, AuditOperationSet, id, before, result); err != nil {
+		return nil, err
 	}
-
-	result = c.Overrides.ConvertToPublic(qResult2)
 	c.Logger.Trace(correlationId, "Set in %s with id = %s", c.TableName, id)
 	return result, nil
 }
@@ -241,39 +718,29 @@ func (c *IdentifiableSqlitePersistence) Update(correlationId string, item interf
 	}
 	var newItem interface{}
 	newItem = cmpersist.CloneObject(item, c.Prototype)
-	id := cmpersist.GetObjectId(newItem)
+	id := c.getObjectId(newItem)
 
-	row := c.Overrides.ConvertFromPublic(newItem)
+	row := c.convertToMap(c.Overrides.ConvertFromPublic(newItem))
+	c.applyStamps(row, c.composeStamps(correlationId, false))
 	params, col := c.GenerateSetParameters(row)
 	values := c.GenerateValues(col, row)
+	params += c.composeStampsPreservation(row, &values)
 	values = append(values, id)
 
 	query := "UPDATE " + c.QuoteIdentifier(c.TableName) +
-		" SET " + params + " WHERE \"id\"=$" + strconv.FormatInt((int64)(len(values)), 16)
-
-	qResult, qErr := c.Client.Query(query, values...)
-	if qErr != nil {
-		return nil, qErr
-	}
-	defer qResult.Close()
-	qResult.Next()
-	if qResult.Err() != nil {
-		return nil, qResult.Err()
-	}
-	query = "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE id=$1"
-	qResult2, qErr2 := c.Client.Query(query, id)
-	if qErr2 != nil {
-		return nil, qErr2
-	}
-	defer qResult2.Close()
-	if !qResult2.Next() {
-		return nil, qResult2.Err()
+		" SET " + params + c.composeVersionIncrement() +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(values)) + c.composeActiveCondition()
+	version, checkVersion := row[c.VersionColumn]
+	if checkVersion {
+		values = append(values, version)
+		query += " AND " + c.QuoteIdentifier(c.VersionColumn) + "=?" + strconv.Itoa(len(values))
 	}
 
-	result = c.Overrides.ConvertToPublic(qResult2)
-	c.Logger.Trace(correlationId, "Updated in %s with id = %s", c.TableName, id)
-	return result, nil
-
+	result, err = c.updateRow(correlationId, AuditOperationUpdate, id, query, values, version, checkVersion)
+	if err == nil && result != nil {
+		c.Logger.Trace(correlationId, "Updated in %s with id = %s", c.TableName, id)
+	}
+	return result, err
 }
 
 // Updates only few selected fields in a data item.
@@ -287,38 +754,67 @@ func (c *IdentifiableSqlitePersistence) UpdatePartially(correlationId string, id
 		return nil, nil
 	}
 
-	row := c.Overrides.ConvertFromPublicPartial(data.Value())
+	row := c.convertToMap(c.Overrides.ConvertFromPublicPartial(data.Value()))
+	c.applyStamps(row, c.composeStamps(correlationId, false))
 	params, col := c.GenerateSetParameters(row)
 	values := c.GenerateValues(col, row)
 	values = append(values, id)
 
 	query := "UPDATE " + c.QuoteIdentifier(c.TableName) +
-		" SET " + params + " WHERE \"id\"=$" + strconv.FormatInt((int64)(len(values)), 16)
+		" SET " + params + c.composeVersionIncrement() +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?" + strconv.Itoa(len(values)) + c.composeActiveCondition()
+	version, checkVersion := row[c.VersionColumn]
+	if checkVersion {
+		values = append(values, version)
+		query += " AND " + c.QuoteIdentifier(c.VersionColumn) + "=?" + strconv.Itoa(len(values))
+	}
 
-	qResult, qErr := c.Client.Query(query, values...)
+	result, err = c.updateRow(correlationId, AuditOperationUpdatePartially, id, query, values, version, checkVersion)
+	if err == nil && result != nil {
+		c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.TableName, id)
+	}
+	return result, err
+}
+
+// Executes an update statement of a data item, checks its version and records the change in the audit table.
+// Returns the updated item, nil when the item doesn't exist or error.
+func (c *IdentifiableSqlitePersistence) updateRow(correlationId string, operation string, id interface{},
+	query string, values []interface{}, version interface{}, checkVersion bool) (result interface{}, err error) {
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	var before interface{}
+	if c.recordsChanges() {
+		if before, err = c.readOneById(db, id, false); err != nil || before == nil {
+			return nil, err
+		}
+	}
 
+	qResult, qErr := db.Exec(query, values...)
 	if qErr != nil {
 		return nil, qErr
 	}
-	defer qResult.Close()
-	qResult.Next()
-	if qResult.Err() != nil {
-		return nil, qResult.Err()
+	if checkVersion {
+		if count, err := qResult.RowsAffected(); err != nil || count == 0 {
+			return nil, c.checkVersionConflict(db, correlationId, id, version, err)
+		}
 	}
-	query = "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE id=$1"
-	qResult2, qErr2 := c.Client.Query(query, id)
-	if qErr2 != nil {
-		return nil, qErr2
+
+	result, err = c.readOneById(db, id, false)
+	if err != nil || result == nil {
+		return nil, err
 	}
-	defer qResult2.Close()
-	if !qResult2.Next() {
-		return nil, qResult2.Err()
+	if err = c.recordChange(db, correlationId, operation, id, before, result); err != nil {
+		return nil, err
 	}
-
-	result = c.Overrides.ConvertToPublic(qResult2)
-	c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.TableName, id)
 	return result, nil
-
 }
 
 // Deleted a data item by it's unique id.
@@ -326,49 +822,268 @@ func (c *IdentifiableSqlitePersistence) UpdatePartially(correlationId string, id
 // - id                an id of the item to be deleted
 // Returns          (optional)  deleted item or error.
 func (c *IdentifiableSqlitePersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
-
-	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE id=$1"
-	qResult, qErr := c.Client.Query(query, id)
-	if qErr != nil {
-		return nil, qErr
+	db, tx, err := c.beginWrite()
+	if err != nil {
+		return nil, err
 	}
-	if !qResult.Next() {
-		defer qResult.Close()
-		return nil, qResult.Err()
+	defer func() {
+		if err = endWrite(tx, err); err != nil {
+			result = nil
+		}
+	}()
+
+	result, err = c.readOneById(db, id, false)
+	if err != nil || result == nil {
+		return nil, err
 	}
-	result = c.Overrides.ConvertToPublic(qResult)
-	qResult.Close()
 
-	query = "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE \"id\"=$1"
-	_, qErr2 := c.Client.Exec(query, id)
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?1"
+	values := []interface{}{id}
+	if c.SoftDelete {
+		query = c.composeSoftDelete(c.QuoteIdentifier(c.IdColumn)+"=?1", 2)
+		values = append(values, FormatSqliteTimestamp(c.now()))
+	}
+	_, qErr2 := db.Exec(query, values...)
 	if qErr2 != nil {
 		return nil, qErr2
 	}
+	if err = c.recordChange(db, correlationId, AuditOperationDelete, id, result, nil); err != nil {
+		return nil, err
+	}
 	c.Logger.Trace(correlationId, "Deleted from %s with id = %s", c.TableName, id)
 	return result, nil
 }
 
 // Deletes multiple data items by their unique ids.
+// Long lists are split into chunks of MaxIdsPerQuery ids deleted in one transaction.
 // - correlationId     (optional) transaction id to trace execution through call chain.
 // - ids               ids of data items to be deleted.
-// Returns          (optional)  error or null for success.
-func (c *IdentifiableSqlitePersistence) DeleteByIds(correlationId string, ids []interface{}) error {
+// Returns          a number of deleted items or error.
+func (c *IdentifiableSqlitePersistence) DeleteByIds(correlationId string, ids []interface{}) (count int64, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
 
-	params := c.GenerateParameters(ids)
-	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE \"id\" IN(" + params + ")"
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return 0, err
+	}
+	deletedAt := FormatSqliteTimestamp(c.now())
 
-	qResult, qErr := c.Client.Exec(query, ids...)
-	if qErr != nil {
-		return qErr
+	for _, chunk := range c.splitIds(ids) {
+		params := c.GenerateParameters(chunk)
+		condition := c.QuoteIdentifier(c.IdColumn) + " IN(" + params + ")"
+		query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + condition
+		values := chunk
+		if c.SoftDelete {
+			query = c.composeSoftDelete(condition, len(chunk)+1)
+			values = append(append(make([]interface{}, 0, len(chunk)+1), chunk...), deletedAt)
+		}
+
+		deleted, err := c.deleteRows(tx, correlationId, AuditOperationDelete, condition+c.composeActiveCondition(), chunk, query, values)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		count += deleted
 	}
 
-	if qErr != nil {
-		return qErr
+	if err = tx.Commit(); err != nil {
+		return 0, err
 	}
 
-	count, err := qResult.RowsAffected()
 	if count != 0 {
 		c.Logger.Trace(correlationId, "Deleted %d items from %s", count, c.TableName)
 	}
-	return err
+	return count, nil
+}
+
+// Deletes data items that match to a given filter.
+// This method shall be called by a func (c * IdentifiableSqlitePersistence) deleteByFilter method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object.
+// - Returns           error or nil for success.
+func (c *IdentifiableSqlitePersistence) DeleteByFilter(correlationId string, filter string) (err error) {
+	if !c.recordsChanges() {
+		return c.SqlitePersistence.DeleteByFilter(correlationId, filter)
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+
+	query, values := c.composeDeleteByFilter(filter)
+	count, err := c.deleteRows(tx, correlationId, AuditOperationDelete, c.composeActiveFilter(filter), nil, query, values)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	c.Logger.Trace(correlationId, "Deleted %d items from %s", count, c.TableName)
+	return nil
+}
+
+// Executes a delete statement and records deleted items matching a condition in the audit table and the outbox.
+// Returns a number of deleted items or error.
+func (c *IdentifiableSqlitePersistence) deleteRows(db sqlExecutor, correlationId string, operation string, condition string,
+	conditionValues []interface{}, query string, values []interface{}) (count int64, err error) {
+	deleted, err := c.readSnapshots(db, condition, conditionValues)
+	if err != nil {
+		return 0, err
+	}
+
+	qResult, qErr := db.Exec(query, values...)
+	if qErr != nil {
+		return 0, qErr
+	}
+	count, err = qResult.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range deleted {
+		if err = c.recordChange(db, correlationId, operation, c.getObjectId(item), item, nil); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// Splits ids into chunks that fit into a single query.
+func (c *IdentifiableSqlitePersistence) splitIds(ids []interface{}) [][]interface{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	size := c.MaxIdsPerQuery
+	if size <= 0 {
+		size = len(ids)
+	}
+
+	chunks := make([][]interface{}, 0, len(ids)/size+1)
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
+
+// Composes an assignment that increments the version: ,"version"="version"+1
+// When the version column is also assigned by the other parameters the rightmost assignment wins.
+func (c *IdentifiableSqlitePersistence) composeVersionIncrement() string {
+	if c.VersionColumn == "" {
+		return ""
+	}
+	column := c.QuoteIdentifier(c.VersionColumn)
+	return "," + column + "=" + column + "+1"
+}
+
+// Checks why a versioned write didn't change any rows.
+// Returns nil when the item doesn't exist or ConflictError with the current version.
+func (c *IdentifiableSqlitePersistence) checkVersionConflict(db sqlExecutor, correlationId string, id interface{}, expected interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+
+	query := "SELECT " + c.QuoteIdentifier(c.VersionColumn) + " FROM " + c.QuoteIdentifier(c.TableName) +
+		" WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?1" + c.composeActiveCondition()
+	var current sql.NullInt64
+	err = db.QueryRow(query, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return cerr.NewConflictError(correlationId, "VERSION_CONFLICT",
+		"Item "+fmt.Sprint(id)+" in "+c.TableName+" was changed by another writer").
+		WithDetails("id", id).
+		WithDetails("version", current.Int64).
+		WithDetails("expected_version", expected)
+}
+
+// Gets the name of the version column from Version field of the prototype.
+func versionColumnOf(proto reflect.Type) string {
+	if proto.Kind() == reflect.Ptr {
+		proto = proto.Elem()
+	}
+	if proto.Kind() != reflect.Struct {
+		return ""
+	}
+	field, ok := proto.FieldByName("Version")
+	if !ok {
+		return ""
+	}
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// Gets the id of a data item. Maps keep ids under the id column name.
+func (c *IdentifiableSqlitePersistence) getObjectId(item interface{}) interface{} {
+	if item != nil && reflect.ValueOf(item).Kind() == reflect.Map {
+		return cmpersist.GetProperty(item, c.IdColumn)
+	}
+	return cmpersist.GetObjectId(item)
+}
+
+// Generates a new id for a data item when it's empty.
+func (c *IdentifiableSqlitePersistence) generateObjectId(correlationId string, item *interface{}) error {
+	if c.IdGenerator != nil {
+		if !isZeroId(c.getObjectId(*item)) {
+			return nil
+		}
+		id, err := c.IdGenerator.NextId(correlationId)
+		if err != nil {
+			return err
+		}
+		c.setObjectId(item, id)
+		return nil
+	}
+
+	if reflect.ValueOf(*item).Kind() == reflect.Map && !strings.EqualFold(c.IdColumn, "id") {
+		if isZeroId(cmpersist.GetProperty(*item, c.IdColumn)) {
+			cmpersist.SetProperty(*item, c.IdColumn, cdata.IdGenerator.NextLong())
+		}
+		return nil
+	}
+	cmpersist.GenerateObjectId(item)
+	return nil
+}
+
+// Sets the id of a data item converting it to the type of the id field.
+func (c *IdentifiableSqlitePersistence) setObjectId(item *interface{}, id interface{}) {
+	if reflect.ValueOf(*item).Kind() == reflect.Map {
+		cmpersist.SetProperty(*item, c.IdColumn, id)
+		return
+	}
+
+	value := id
+	if current := cmpersist.GetObjectId(*item); current != nil {
+		currentType := reflect.TypeOf(current)
+		idType := reflect.TypeOf(id)
+		if currentType.Kind() == reflect.String && idType.Kind() != reflect.String {
+			value = fmt.Sprint(id)
+		} else if idType.ConvertibleTo(currentType) && idType.Kind() != reflect.String {
+			value = reflect.ValueOf(id).Convert(currentType).Interface()
+		} else if idType.Kind() == reflect.String && currentType.Kind() != reflect.String {
+			if number, err := strconv.ParseInt(id.(string), 10, 64); err == nil {
+				value = reflect.ValueOf(number).Convert(currentType).Interface()
+			}
+		}
+	}
+	cmpersist.SetObjectId(item, value)
+}
+
+func isZeroId(id interface{}) bool {
+	return id == nil || reflect.ValueOf(id).IsZero()
 }
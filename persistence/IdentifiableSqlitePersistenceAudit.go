@@ -0,0 +1,224 @@
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Operations recorded in the audit table.
+const (
+	AuditOperationCreate          = "create"
+	AuditOperationSet             = "set"
+	AuditOperationUpdate          = "update"
+	AuditOperationUpdatePartially = "update_partially"
+	AuditOperationDelete          = "delete"
+	AuditOperationRestore         = "restore"
+	AuditOperationPurge           = "purge"
+)
+
+/*
+Entry of the audit table that records a change of a data item.
+
+Entries are written in the same transaction as the change,
+so the audit table never misses committed changes.
+Snapshots keep data items in public format as JSON.
+*/
+type SqliteAuditEntry struct {
+	// The sequential number of the entry.
+	Id int64 `json:"id"`
+	// The id of the changed data item.
+	EntityId string `json:"entity_id"`
+	// The operation that changed the data item.
+	Operation string `json:"operation"`
+	// The correlation id of the call that changed the data item.
+	CorrelationId string `json:"correlation_id"`
+	// The time of the change.
+	Time time.Time `json:"time"`
+	// The data item before the change, nil for created items.
+	Before json.RawMessage `json:"before"`
+	// The data item after the change, nil for deleted items.
+	After json.RawMessage `json:"after"`
+}
+
+// The database handle used by writes: the client or a transaction of a recorded write.
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Checks if writes are recorded in the audit table.
+func (c *IdentifiableSqlitePersistence) isAudited() bool {
+	return c.AuditTableName != ""
+}
+
+// Creates the audit table and its index when they don't exist.
+func (c *IdentifiableSqlitePersistence) createAuditTable() error {
+	table := c.QuoteIdentifier(c.AuditTableName)
+	_, err := c.Client.Exec("CREATE TABLE IF NOT EXISTS " + table +
+		" (\"id\" INTEGER PRIMARY KEY AUTOINCREMENT, \"entity_id\" TEXT NOT NULL, \"operation\" TEXT NOT NULL," +
+		" \"correlation_id\" TEXT, \"time\" TEXT NOT NULL, \"before\" TEXT, \"after\" TEXT)")
+	if err != nil {
+		return err
+	}
+	_, err = c.Client.Exec("CREATE INDEX IF NOT EXISTS " + c.QuoteIdentifier(c.AuditTableName+"_entity_id") +
+		" ON " + table + " (\"entity_id\", \"id\")")
+	return err
+}
+
+// Checks if writes are recorded in the audit table or the outbox.
+func (c *IdentifiableSqlitePersistence) recordsChanges() bool {
+	return c.isAudited() || c.Outbox != nil
+}
+
+// Begins a write. Recorded writes run in a transaction, other writes use the client.
+func (c *IdentifiableSqlitePersistence) beginWrite() (db sqlExecutor, tx *sql.Tx, err error) {
+	if !c.recordsChanges() {
+		return c.Client, nil, nil
+	}
+	tx, err = c.Client.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, tx, nil
+}
+
+// Ends a write by committing its transaction or rolling it back on errors.
+func endWrite(tx *sql.Tx, err error) error {
+	if tx == nil {
+		return err
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reads a data item by its id inside a write.
+// Soft-deleted items are read only when includeDeleted is set.
+func (c *IdentifiableSqlitePersistence) readOneById(db sqlExecutor, id interface{}, includeDeleted bool) (item interface{}, err error) {
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + c.QuoteIdentifier(c.IdColumn) + "=?1"
+	if !includeDeleted {
+		query += c.composeActiveCondition()
+	}
+	qResult, qErr := db.Query(query, id)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+	if !qResult.Next() {
+		return nil, qResult.Err()
+	}
+	return c.Overrides.ConvertToPublic(qResult), nil
+}
+
+// Reads snapshots of data items matching a condition before they are changed by a recorded write.
+func (c *IdentifiableSqlitePersistence) readSnapshots(db sqlExecutor, condition string, values []interface{}) (items []interface{}, err error) {
+	if !c.recordsChanges() {
+		return nil, nil
+	}
+
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	qResult, qErr := db.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	items = make([]interface{}, 0)
+	for qResult.Next() {
+		items = append(items, c.Overrides.ConvertToPublic(qResult))
+	}
+	return items, qResult.Err()
+}
+
+// Records a change in the audit table and the outbox.
+func (c *IdentifiableSqlitePersistence) recordChange(db sqlExecutor, correlationId string, operation string,
+	id interface{}, before interface{}, after interface{}) error {
+	if err := c.writeAudit(db, correlationId, operation, id, before, after); err != nil {
+		return err
+	}
+	return c.writeOutbox(db, correlationId, operation, id, before, after)
+}
+
+// Writes an entry to the audit table. Nil snapshots are stored as NULL.
+func (c *IdentifiableSqlitePersistence) writeAudit(db sqlExecutor, correlationId string, operation string,
+	id interface{}, before interface{}, after interface{}) error {
+	if !c.isAudited() {
+		return nil
+	}
+
+	snapshots := make([]interface{}, 2)
+	for index, item := range []interface{}{before, after} {
+		if item == nil {
+			continue
+		}
+		buf, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		snapshots[index] = string(buf)
+	}
+	var corrId interface{}
+	if correlationId != "" {
+		corrId = correlationId
+	}
+
+	query := "INSERT INTO " + c.QuoteIdentifier(c.AuditTableName) +
+		" (\"entity_id\", \"operation\", \"correlation_id\", \"time\", \"before\", \"after\") VALUES (?1, ?2, ?3, ?4, ?5, ?6)"
+	_, err := db.Exec(query, fmt.Sprint(id), operation, corrId, FormatSqliteTimestamp(c.now()), snapshots[0], snapshots[1])
+	return err
+}
+
+// Gets the history of changes of a data item from the audit table.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - id                an id of the data item.
+// Returns          audit entries from the oldest to the newest or error.
+func (c *IdentifiableSqlitePersistence) GetHistoryById(correlationId string, id interface{}) (entries []*SqliteAuditEntry, err error) {
+	entries = make([]*SqliteAuditEntry, 0)
+	if !c.isAudited() {
+		return entries, nil
+	}
+
+	query := "SELECT \"id\", \"entity_id\", \"operation\", \"correlation_id\", \"time\", \"before\", \"after\" FROM " +
+		c.QuoteIdentifier(c.AuditTableName) + " WHERE \"entity_id\"=?1 ORDER BY \"id\""
+	qResult, qErr := c.Client.Query(query, fmt.Sprint(id))
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	for qResult.Next() {
+		entry := &SqliteAuditEntry{}
+		var corrId, before, after sql.NullString
+		var timestamp string
+		err = qResult.Scan(&entry.Id, &entry.EntityId, &entry.Operation, &corrId, &timestamp, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		entry.CorrelationId = corrId.String
+		entry.Time, err = time.Parse(SqliteTimestampFormat, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, entry)
+	}
+	if err = qResult.Err(); err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d audit entries from %s with id = %s", len(entries), c.AuditTableName, id)
+	return entries, nil
+}
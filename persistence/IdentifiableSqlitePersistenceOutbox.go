@@ -0,0 +1,41 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Composes a message about a change of a data item.
+// The message type is the table name and the operation separated by a dot,
+// the payload is the item after the change or before it for deleted items.
+func (c *IdentifiableSqlitePersistence) composeOutboxMessage(correlationId string, operation string,
+	id interface{}, before interface{}, after interface{}) (*SqliteOutboxMessage, error) {
+	item := after
+	if item == nil {
+		item = before
+	}
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	return &SqliteOutboxMessage{
+		Type:          c.TableName + "." + operation,
+		Key:           fmt.Sprint(id),
+		Payload:       payload,
+		CorrelationId: correlationId,
+	}, nil
+}
+
+// Appends a message about a change of a data item to the outbox.
+func (c *IdentifiableSqlitePersistence) writeOutbox(db sqlExecutor, correlationId string, operation string,
+	id interface{}, before interface{}, after interface{}) error {
+	if c.Outbox == nil {
+		return nil
+	}
+
+	message, err := c.composeOutboxMessage(correlationId, operation, id, before, after)
+	if err != nil {
+		return err
+	}
+	return c.Outbox.append(db, correlationId, message)
+}
@@ -0,0 +1,140 @@
+package persistence
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Checks if any stamp columns are configured.
+func (c *IdentifiableSqlitePersistence) hasStamps() bool {
+	return c.CreatedAtColumn != "" || c.UpdatedAtColumn != "" ||
+		c.CreatedByColumn != "" || c.UpdatedByColumn != ""
+}
+
+// Composes stamps for a created or updated data item.
+func (c *IdentifiableSqlitePersistence) composeStamps(correlationId string, create bool) map[string]interface{} {
+	stamps := map[string]interface{}{}
+	if !c.hasStamps() {
+		return stamps
+	}
+
+	now := FormatSqliteTimestamp(c.now())
+
+	var actor interface{}
+	if c.CreatedByColumn != "" || c.UpdatedByColumn != "" {
+		provider := c.ActorProvider
+		if provider == nil {
+			provider = NewCorrelationActorProvider("")
+		}
+		if value := provider.GetActor(correlationId); value != "" {
+			actor = value
+		}
+	}
+
+	if create && c.CreatedAtColumn != "" {
+		stamps[c.CreatedAtColumn] = now
+	}
+	if create && c.CreatedByColumn != "" {
+		stamps[c.CreatedByColumn] = actor
+	}
+	if c.UpdatedAtColumn != "" {
+		stamps[c.UpdatedAtColumn] = now
+	}
+	if c.UpdatedByColumn != "" {
+		stamps[c.UpdatedByColumn] = actor
+	}
+	return stamps
+}
+
+// Applies stamps to a row as columns or as fields inside the JSON data column.
+func (c *IdentifiableSqlitePersistence) applyStamps(row map[string]interface{}, stamps map[string]interface{}) {
+	if len(stamps) == 0 {
+		return
+	}
+
+	if !c.StampsInData {
+		for column, value := range stamps {
+			row[column] = value
+		}
+		return
+	}
+
+	data, ok := row["data"].(string)
+	if !ok {
+		return
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return
+	}
+	for field, value := range stamps {
+		doc[field] = value
+	}
+	buf, _ := json.Marshal(doc)
+	row["data"] = string(buf)
+}
+
+// Composes assignments that keep creation stamps when existing rows are overwritten.
+// The assignments are added after other assignments since the rightmost assignment of a column wins.
+// Data values of JSON documents are appended to the values.
+func (c *IdentifiableSqlitePersistence) composeStampsPreservation(row map[string]interface{}, values *[]interface{}) string {
+	columns := make([]string, 0, 2)
+	if c.CreatedAtColumn != "" {
+		columns = append(columns, c.CreatedAtColumn)
+	}
+	if c.CreatedByColumn != "" {
+		columns = append(columns, c.CreatedByColumn)
+	}
+	if len(columns) == 0 {
+		return ""
+	}
+
+	if !c.StampsInData {
+		assignments := make([]string, len(columns))
+		for index, column := range columns {
+			column = c.QuoteIdentifier(column)
+			assignments[index] = column + "=" + column
+		}
+		return "," + strings.Join(assignments, ",")
+	}
+
+	data, ok := row["data"]
+	if !ok {
+		return ""
+	}
+	*values = append(*values, data)
+	param := "?" + strconv.Itoa(len(*values))
+
+	// Existing stamps are copied into the new document when they are present
+	result := param
+	for _, field := range columns {
+		path := "'$." + field + "'"
+		result = "JSON_SET(" + result + "," + path +
+			",COALESCE(JSON_EXTRACT(data," + path + "),JSON_EXTRACT(" + param + "," + path + ")))"
+	}
+	return ",data=" + result
+}
+
+// Composes assignments of stamps to existing rows: ,"updated_at"=?3
+// Stamps kept in data are set inside the JSON data column. Stamp values are appended to the values.
+func (c *IdentifiableSqlitePersistence) composeStampsAssignment(stamps map[string]interface{}, values *[]interface{}) string {
+	if len(stamps) == 0 {
+		return ""
+	}
+
+	assignments := make([]string, 0, len(stamps))
+	for column, value := range stamps {
+		*values = append(*values, value)
+		param := "?" + strconv.Itoa(len(*values))
+		if c.StampsInData {
+			assignments = append(assignments, "'$."+column+"',"+param)
+		} else {
+			assignments = append(assignments, c.QuoteIdentifier(column)+"="+param)
+		}
+	}
+	if c.StampsInData {
+		return ",data=JSON_SET(data," + strings.Join(assignments, ",") + ")"
+	}
+	return "," + strings.Join(assignments, ",")
+}
@@ -0,0 +1,221 @@
+package persistence
+
+import (
+	"strings"
+	"time"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// The SQLite expression that gets the current time in SqliteTimestampFormat.
+// Triggers stamp versions with the system time, so the Clock of the persistence doesn't affect them.
+const sqliteNowExpression = "strftime('%Y-%m-%dT%H:%M:%fZ','now')"
+
+// Creates the temporal table that keeps versions of data items
+// and triggers that maintain it on every change of the persistence table.
+// Versions valid now have NULL in valid_to column. Versions are stamped with the system time,
+// so AS OF queries work only with wall-clock time even when a Clock is set to stamp data items.
+func (c *IdentifiableSqlitePersistence) createTemporalTable(correlationId string) error {
+	columns, err := c.Connection.GetColumns(correlationId, c.TableName)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return cerr.NewInvalidStateError(correlationId, "NO_TABLE", "Table "+c.TableName+" does not exist")
+	}
+
+	history := c.QuoteIdentifier(c.TemporalTableName)
+	names := make([]string, len(columns))
+	definitions := make([]string, len(columns))
+	for index, column := range columns {
+		names[index] = c.QuoteIdentifier(column.Name)
+		definitions[index] = strings.TrimSpace(c.QuoteIdentifier(column.Name) + " " + column.Type)
+	}
+	_, err = c.Client.Exec("CREATE TABLE IF NOT EXISTS " + history + " (" + strings.Join(definitions, ", ") +
+		", \"valid_from\" TEXT NOT NULL, \"valid_to\" TEXT)")
+	if err != nil {
+		return err
+	}
+
+	// Columns added to the persistence table later are added to the temporal table
+	existing, err := c.Connection.GetColumns(correlationId, c.TemporalTableName)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, column := range existing {
+		known[strings.ToLower(column.Name)] = true
+	}
+	for index, column := range columns {
+		if !known[strings.ToLower(column.Name)] {
+			_, err = c.Client.Exec("ALTER TABLE " + history + " ADD COLUMN " + definitions[index])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err = c.Client.Exec("CREATE INDEX IF NOT EXISTS " + c.QuoteIdentifier(c.TemporalTableName+"_valid") +
+		" ON " + history + " (" + c.QuoteIdentifier(c.IdColumn) + ", \"valid_from\")")
+	if err != nil {
+		return err
+	}
+
+	// Triggers are recreated to copy the current set of columns
+	table := c.QuoteIdentifier(c.TableName)
+	id := c.QuoteIdentifier(c.IdColumn)
+	insertColumns := strings.Join(names, ", ") + ", \"valid_from\""
+	newValues := "NEW." + strings.Join(names, ", NEW.") + ", " + sqliteNowExpression
+	closeVersion := "UPDATE " + history + " SET \"valid_to\"=" + sqliteNowExpression +
+		" WHERE " + id + "=OLD." + id + " AND \"valid_to\" IS NULL;"
+	openVersion := "INSERT INTO " + history + " (" + insertColumns + ") VALUES (" + newValues + ");"
+	statements := []string{
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(c.TableName+"_temporal_insert"),
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(c.TableName+"_temporal_update"),
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(c.TableName+"_temporal_delete"),
+		"CREATE TRIGGER " + c.QuoteIdentifier(c.TableName+"_temporal_insert") + " AFTER INSERT ON " + table +
+			" BEGIN " + openVersion + " END",
+		"CREATE TRIGGER " + c.QuoteIdentifier(c.TableName+"_temporal_update") + " AFTER UPDATE ON " + table +
+			" BEGIN " + closeVersion + " " + openVersion + " END",
+		"CREATE TRIGGER " + c.QuoteIdentifier(c.TableName+"_temporal_delete") + " AFTER DELETE ON " + table +
+			" BEGIN " + closeVersion + " END",
+		// Items stored before the temporal mode was enabled get their first version
+		"INSERT INTO " + history + " (" + insertColumns + ")" +
+			" SELECT " + strings.Join(names, ", ") + ", " + sqliteNowExpression + " FROM " + table + " AS t" +
+			" WHERE NOT EXISTS (SELECT 1 FROM " + history + " AS h WHERE h." + id + "=t." + id + " AND h.\"valid_to\" IS NULL)",
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	c.temporalColumns = strings.Join(names, ", ")
+	return nil
+}
+
+// Checks that the temporal mode is enabled and opened.
+func (c *IdentifiableSqlitePersistence) checkTemporal(correlationId string) error {
+	if c.TemporalTableName == "" || c.temporalColumns == "" {
+		return cerr.NewInvalidStateError(correlationId, "NOT_TEMPORAL",
+			"Temporal mode is not enabled for "+c.TableName)
+	}
+	return nil
+}
+
+// Composes a condition that selects versions valid at the time bound to a given parameter.
+func composeValidAt(param string) string {
+	return "\"valid_from\"<=" + param + " AND (\"valid_to\" IS NULL OR \"valid_to\">" + param + ")"
+}
+
+// Gets a data item by its unique id as it was at a given time.
+// The time is compared with the system time when versions were written, not with the Clock of the persistence.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be retrieved.
+// - asOf              the time of the state to be retrieved.
+// Returns           data item, nil if the item didn't exist at that time, or error.
+func (c *IdentifiableSqlitePersistence) GetOneByIdAsOf(correlationId string, id interface{}, asOf time.Time) (item interface{}, err error) {
+	return c.getOneByIdAsOf(correlationId, id, asOf, false)
+}
+
+// Gets a data item by its id as it was at a given time. Soft-deleted items are read only when includeDeleted is set.
+func (c *IdentifiableSqlitePersistence) getOneByIdAsOf(correlationId string, id interface{}, asOf time.Time,
+	includeDeleted bool) (item interface{}, err error) {
+	if err = c.checkTemporal(correlationId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + c.temporalColumns + " FROM " + c.QuoteIdentifier(c.TemporalTableName) +
+		" WHERE " + c.composeReadFilter(c.QuoteIdentifier(c.IdColumn)+"=?1 AND "+composeValidAt("?2"), includeDeleted) +
+		" ORDER BY \"valid_from\" DESC LIMIT 1"
+	qResult, qErr := c.Client.Query(query, id, FormatSqliteTimestamp(asOf))
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+	if !qResult.Next() {
+		c.Logger.Trace(correlationId, "Nothing found from %s with id = %s as of %s", c.TemporalTableName, id, asOf)
+		return nil, qResult.Err()
+	}
+
+	item = c.Overrides.ConvertToPublic(qResult)
+	c.Logger.Trace(correlationId, "Retrieved from %s with id = %s as of %s", c.TemporalTableName, id, asOf)
+	return item, nil
+}
+
+// Gets a list of data items as they were at a given time, retrieved by a given filter
+// and sorted according to sort parameters.
+// The time is compared with the system time when versions were written, not with the Clock of the persistence.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - filter           (optional) a filter JSON object
+// - sort             (optional) sorting JSON object
+// - select           (optional) projection JSON object
+// - asOf             the time of the state to be retrieved.
+// Returns          data list or error.
+func (c *IdentifiableSqlitePersistence) GetListByFilterAsOf(correlationId string, filter interface{}, sort interface{}, sel interface{},
+	asOf time.Time) (items []interface{}, err error) {
+	if err = c.checkTemporal(correlationId); err != nil {
+		return nil, err
+	}
+
+	columns := c.temporalColumns
+	if slct, ok := sel.(string); ok && slct != "" {
+		columns = slct
+	}
+	query := "SELECT " + columns + " FROM " + c.QuoteIdentifier(c.TemporalTableName) + " WHERE " + composeValidAt("?1")
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " AND (" + flt + ")"
+	}
+	if srt, ok := sort.(string); ok && srt != "" {
+		query += " ORDER BY " + srt
+	}
+
+	qResult, qErr := c.Client.Query(query, FormatSqliteTimestamp(asOf))
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	items = make([]interface{}, 0)
+	for qResult.Next() {
+		items = append(items, c.Overrides.ConvertToPublic(qResult))
+	}
+	if err = qResult.Err(); err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d from %s as of %s", len(items), c.TemporalTableName, asOf)
+	return items, nil
+}
+
+// Permanently removes versions of data items that stopped being valid before a given time.
+// Versions valid now are never removed.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - olderThan         the time before which versions stopped being valid.
+// Returns          a number of removed versions or error.
+func (c *IdentifiableSqlitePersistence) PruneVersions(correlationId string, olderThan time.Time) (count int64, err error) {
+	if err = c.checkTemporal(correlationId); err != nil {
+		return 0, err
+	}
+
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TemporalTableName) +
+		" WHERE \"valid_to\" IS NOT NULL AND \"valid_to\"<?1"
+	qResult, qErr := c.Client.Exec(query, FormatSqliteTimestamp(olderThan))
+	if qErr != nil {
+		return 0, qErr
+	}
+
+	count, err = qResult.RowsAffected()
+	if count != 0 {
+		c.Logger.Trace(correlationId, "Pruned %d versions from %s", count, c.TemporalTableName)
+	}
+	return count, err
+}
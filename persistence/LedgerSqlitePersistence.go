@@ -0,0 +1,324 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// The hash that precedes the first entry of a ledger.
+const LedgerGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+/*
+Abstract persistence component that stores data items in SQLite
+as an append-only ledger protected by a hash chain.
+
+Every entry keeps its data item as canonical JSON (keys sorted, no extra spaces)
+together with the hash of the previous entry and its own SHA-256 hash
+calculated over the previous hash and the canonical JSON.
+Triggers reject updates and deletes of entries, and Verify operation
+walks the chain to detect entries that were changed after the fact.
+
+Removal of the last entries cannot be detected from the chain alone,
+so auditors shall keep the last hash returned by Verify to compare it later.
+
+### Configuration parameters ###
+
+- collection:                  (optional) SQLite collection name
+- connection(s):
+  - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+  - database:                  database file name
+
+- options:
+  - max_page_size:        (optional) maximum page size (default: 100)
+
+### Example ###
+
+	type MyLedgerPersistence struct {
+		persist.LedgerSqlitePersistence
+	}
+
+	func NewMyLedgerPersistence() *MyLedgerPersistence {
+		c := &MyLedgerPersistence{}
+		c.LedgerSqlitePersistence = *persist.InheritLedgerSqlitePersistence(c, reflect.TypeOf(MyEvent{}), "myevents")
+		return c
+	}
+
+	func (c *MyLedgerPersistence) DefineSchema() {
+		c.ClearSchema()
+		c.EnsureTable()
+	}
+
+	event, err := persistence.Append("123", MyEvent{Account: "1", Amount: 100})
+	result, err := persistence.Verify("123")
+	fmt.Println(result.Valid) // Result: true
+*/
+type LedgerSqlitePersistence struct {
+	*SqlitePersistence
+
+	appendLock sync.Mutex
+}
+
+/*
+Result of the ledger verification.
+*/
+type SqliteLedgerVerification struct {
+	// True if the whole chain is intact.
+	Valid bool `json:"valid"`
+	// The number of verified entries up to the first broken one.
+	Entries int64 `json:"entries"`
+	// The hash of the last verified entry.
+	LastHash string `json:"last_hash"`
+	// The sequence number of the first broken entry or 0 when the chain is intact.
+	BrokenSeq int64 `json:"broken_seq"`
+	// The reason why the entry is broken.
+	Reason string `json:"reason"`
+}
+
+// Creates a new instance of the persistence component.
+// - overrides a references to child class that overrides virtual methods
+// - proto     a prototype of data items.
+// - tableName a table name.
+func InheritLedgerSqlitePersistence(overrides ISqlitePersistenceOverrides, proto reflect.Type, tableName string) *LedgerSqlitePersistence {
+	if tableName == "" {
+		panic("Table name could not be empty")
+	}
+
+	c := &LedgerSqlitePersistence{}
+	c.SqlitePersistence = InheritSqlitePersistence(overrides, proto, tableName)
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// Ledger entries are never deleted, so soft deletes are always disabled.
+// - config    configuration parameters to be set.
+func (c *LedgerSqlitePersistence) Configure(config *cconf.ConfigParams) {
+	c.SqlitePersistence.Configure(config)
+	c.SoftDelete = false
+}
+
+// Adds DML statements to automatically create the ledger table
+// and triggers that reject updates and deletes of entries.
+func (c *LedgerSqlitePersistence) EnsureTable() {
+	table := c.QuoteIdentifier(c.TableName)
+	c.EnsureSchema("CREATE TABLE IF NOT EXISTS " + table +
+		" (\"seq\" INTEGER PRIMARY KEY AUTOINCREMENT, \"data\" JSON NOT NULL," +
+		" \"prev_hash\" TEXT NOT NULL UNIQUE, \"hash\" TEXT NOT NULL UNIQUE)")
+	c.EnsureSchema("CREATE TRIGGER IF NOT EXISTS " + c.QuoteIdentifier(c.TableName+"_reject_update") +
+		" BEFORE UPDATE ON " + table + " BEGIN SELECT RAISE(ABORT, 'Ledger entries cannot be changed'); END")
+	c.EnsureSchema("CREATE TRIGGER IF NOT EXISTS " + c.QuoteIdentifier(c.TableName+"_reject_delete") +
+		" BEFORE DELETE ON " + table + " BEGIN SELECT RAISE(ABORT, 'Ledger entries cannot be deleted'); END")
+}
+
+// Converts object value from internal to public format.
+// - rows     a row in internal format to convert.
+// Returns converted object in public format.
+func (c *LedgerSqlitePersistence) ConvertToPublic(rows *sql.Rows) interface{} {
+	columns, err := rows.Columns()
+	if err != nil || len(columns) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err = rows.Scan(pointers...); err != nil {
+		return nil
+	}
+
+	for index, column := range columns {
+		if column != "data" {
+			continue
+		}
+		var data []byte
+		switch value := values[index].(type) {
+		case string:
+			data = []byte(value)
+		case []byte:
+			data = value
+		default:
+			return nil
+		}
+		docPointer := c.NewObjectByPrototype()
+		json.Unmarshal(data, docPointer.Interface())
+		return c.DereferenceObject(docPointer)
+	}
+	return nil
+}
+
+// Convert object value from public to internal format.
+// - value     an object in public format to convert.
+// Returns converted object in internal format.
+func (c *LedgerSqlitePersistence) ConvertFromPublic(value interface{}) interface{} {
+	data, err := canonicalJson(value)
+	if err != nil {
+		return nil
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// Appends a data item to the end of the ledger.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item             an item to be appended.
+// Returns          appended item or error.
+func (c *LedgerSqlitePersistence) Append(correlationId string, item interface{}) (result interface{}, err error) {
+	if item == nil {
+		return nil, nil
+	}
+	data, err := canonicalJson(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// Appends from this process are serialized, unique prev_hash protects
+	// the chain from forks caused by other processes
+	c.appendLock.Lock()
+	defer c.appendLock.Unlock()
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	prevHash := LedgerGenesisHash
+	query := "SELECT \"hash\" FROM " + c.QuoteIdentifier(c.TableName) + " ORDER BY \"seq\" DESC LIMIT 1"
+	err = tx.QueryRow(query).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return nil, err
+	}
+
+	hash := ledgerHash(prevHash, data)
+	query = "INSERT INTO " + c.QuoteIdentifier(c.TableName) + " (\"data\", \"prev_hash\", \"hash\") VALUES (?1, ?2, ?3)"
+	qResult, err := tx.Exec(query, data, prevHash, hash)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	seq, err := qResult.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	docPointer := c.NewObjectByPrototype()
+	json.Unmarshal([]byte(data), docPointer.Interface())
+	c.Logger.Trace(correlationId, "Appended to %s with seq = %d", c.TableName, seq)
+	return c.DereferenceObject(docPointer), nil
+}
+
+// Creates a data item by appending it to the end of the ledger.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item             an item to be created.
+// Returns          created item or error.
+func (c *LedgerSqlitePersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	return c.Append(correlationId, item)
+}
+
+// Rejects deletes of ledger entries.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object.
+// Returns           BadRequestError.
+func (c *LedgerSqlitePersistence) DeleteByFilter(correlationId string, filter string) error {
+	return c.rejectChange(correlationId)
+}
+
+// Rejects clearing of the ledger.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns           BadRequestError.
+func (c *LedgerSqlitePersistence) Clear(correlationId string) error {
+	return c.rejectChange(correlationId)
+}
+
+func (c *LedgerSqlitePersistence) rejectChange(correlationId string) error {
+	return cerr.NewBadRequestError(correlationId, "LEDGER_APPEND_ONLY",
+		"Entries of ledger "+c.TableName+" cannot be changed or deleted")
+}
+
+// Walks the hash chain from the first entry and checks every link.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns          the verification result with the first broken entry or error.
+func (c *LedgerSqlitePersistence) Verify(correlationId string) (result *SqliteLedgerVerification, err error) {
+	query := "SELECT \"seq\", \"data\", \"prev_hash\", \"hash\" FROM " + c.QuoteIdentifier(c.TableName) + " ORDER BY \"seq\""
+	qResult, qErr := c.Client.Query(query)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	result = &SqliteLedgerVerification{Valid: true, LastHash: LedgerGenesisHash}
+	for qResult.Next() {
+		var seq int64
+		var data, prevHash, hash string
+		if err = qResult.Scan(&seq, &data, &prevHash, &hash); err != nil {
+			return nil, err
+		}
+
+		reason := ""
+		if prevHash != result.LastHash {
+			reason = "previous hash does not match the hash of the previous entry"
+		} else if canonical, err := canonicalJson(json.RawMessage(data)); err != nil || canonical != data {
+			reason = "data is not in canonical form"
+		} else if hash != ledgerHash(prevHash, data) {
+			reason = "hash does not match the entry data"
+		}
+		if reason != "" {
+			result.Valid = false
+			result.BrokenSeq = seq
+			result.Reason = reason
+			c.Logger.Warn(correlationId, "Ledger %s is broken at seq = %d: %s", c.TableName, seq, reason)
+			return result, nil
+		}
+
+		result.Entries++
+		result.LastHash = hash
+	}
+	if err = qResult.Err(); err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Verified %d entries of ledger %s", result.Entries, c.TableName)
+	return result, nil
+}
+
+// Calculates the hash of a ledger entry: SHA-256 over the previous hash and the canonical JSON.
+func ledgerHash(prevHash string, data string) string {
+	hash := sha256.Sum256([]byte(prevHash + "\n" + data))
+	return hex.EncodeToString(hash[:])
+}
+
+// Converts a value to canonical JSON with sorted keys and numbers kept as they are.
+func canonicalJson(value interface{}) (string, error) {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	var doc interface{}
+	if err = decoder.Decode(&doc); err != nil {
+		return "", err
+	}
+
+	// Maps are encoded with sorted keys
+	var result strings.Builder
+	encoder := json.NewEncoder(&result)
+	encoder.SetEscapeHTML(false)
+	if err = encoder.Encode(doc); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(result.String(), "\n"), nil
+}
@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+)
+
+/*
+Generates human-readable ids like ORD-000123 from named sequences
+stored in a SQLite table.
+
+Sequence values are incremented atomically, so several persistences
+and processes can share the same sequence.
+*/
+type SequenceIdGenerator struct {
+	connection *conn.SqliteConnection
+	lock       sync.Mutex
+	// The connection pool where the sequence table was created.
+	tableClient *sql.DB
+	// The name of the table that keeps sequences.
+	TableName string
+	// The sequence name.
+	Sequence string
+	// The prefix added to sequence values.
+	Prefix string
+	// The minimum number of digits, values are padded with zeros.
+	Digits int
+}
+
+// Creates a new instance of the generator.
+// - connection     a SQLite connection to store sequences.
+// - sequence       a sequence name.
+// - prefix         (optional) a prefix added to sequence values.
+// - digits         (optional) a minimum number of digits.
+func NewSequenceIdGenerator(connection *conn.SqliteConnection, sequence string, prefix string, digits int) *SequenceIdGenerator {
+	return &SequenceIdGenerator{
+		connection: connection,
+		TableName:  "id_sequences",
+		Sequence:   sequence,
+		Prefix:     prefix,
+		Digits:     digits,
+	}
+}
+
+// Gets the next value of the sequence and formats it as an id.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a generated id or error.
+func (c *SequenceIdGenerator) NextId(correlationId string) (interface{}, error) {
+	if c.connection == nil || c.connection.GetConnection() == nil {
+		return nil, cerr.NewInvalidStateError(correlationId, "NO_CONNECTION", "SQLite connection is not opened")
+	}
+	client := c.connection.GetConnection()
+	if err := c.ensureTable(client); err != nil {
+		return nil, err
+	}
+
+	var value int64
+	query := "INSERT INTO \"" + c.TableName + "\" (\"name\", \"value\") VALUES (?1, 1)" +
+		" ON CONFLICT (\"name\") DO UPDATE SET \"value\"=\"value\"+1 RETURNING \"value\""
+	err := client.QueryRow(query, c.Sequence).Scan(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return fmt.Sprintf("%s%0*d", c.Prefix, c.Digits, value), nil
+}
+
+// Creates the sequence table on the first use of a connection.
+// Failed attempts are repeated on the next id.
+func (c *SequenceIdGenerator) ensureTable(client *sql.DB) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tableClient == client {
+		return nil
+	}
+
+	_, err := client.Exec("CREATE TABLE IF NOT EXISTS \"" + c.TableName + "\"" +
+		" (\"name\" TEXT PRIMARY KEY, \"value\" INTEGER NOT NULL)")
+	if err != nil {
+		return err
+	}
+	c.tableClient = client
+	return nil
+}
@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+)
+
+// Custom epoch of snowflake ids: 2020-01-01T00:00:00Z in milliseconds
+const snowflakeEpoch int64 = 1577836800000
+
+/*
+Generates time-ordered 64-bit integer ids in snowflake layout:
+41 bits of milliseconds since 2020-01-01, 10 bits of node id and 12 bits of sequence.
+
+Each process that writes to the same table shall use a unique node id.
+*/
+type SnowflakeIdGenerator struct {
+	lock     sync.Mutex
+	node     int64
+	lastTime int64
+	sequence int64
+}
+
+// Creates a new instance of the generator.
+// - node     a unique node id from 0 to 1023.
+func NewSnowflakeIdGenerator(node int) *SnowflakeIdGenerator {
+	return &SnowflakeIdGenerator{
+		node: int64(node) & 0x3ff,
+	}
+}
+
+// Generates a new snowflake id.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a generated int64 id or error.
+func (c *SnowflakeIdGenerator) NextId(correlationId string) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now().UnixMilli() - snowflakeEpoch
+	if now > c.lastTime {
+		c.lastTime = now
+		c.sequence = 0
+	} else {
+		c.sequence = (c.sequence + 1) & 0xfff
+		// Sequence overflow borrows the next millisecond
+		if c.sequence == 0 {
+			c.lastTime++
+		}
+	}
+
+	return c.lastTime<<22 | c.node<<12 | c.sequence, nil
+}
@@ -0,0 +1,186 @@
+package persistence
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Codec that converts documents of JSON persistence to values of the data column and back.
+
+Codecs other than JSON store opaque values that SQL JSON functions can't read,
+so fields used in filters shall be declared with EnsureJsonField and partial updates
+are applied by reading and rewriting documents. Other formats like zstd
+can be added by implementing this interface.
+*/
+type SqliteDocumentCodec interface {
+	// Encodes a document into the value of the data column.
+	// - doc     a document in public format.
+	// Returns a string with JSON text, a []byte for BLOB storage or error.
+	Encode(doc interface{}) (interface{}, error)
+
+	// Decodes the value of the data column into a document.
+	// - data    the value of the data column, a string or []byte.
+	// - target  a pointer to the document to decode into.
+	// Returns error or nil when the document was decoded.
+	Decode(data interface{}, target interface{}) error
+
+	// Checks if documents are stored as JSON text that SQL JSON functions can query.
+	IsJson() bool
+}
+
+// Codec that stores documents as JSON text. It is used by default.
+type JsonDocumentCodec struct{}
+
+// Creates a new instance of the codec.
+func NewJsonDocumentCodec() *JsonDocumentCodec {
+	return &JsonDocumentCodec{}
+}
+
+// Encodes a document into JSON text.
+func (c *JsonDocumentCodec) Encode(doc interface{}) (interface{}, error) {
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+// Decodes JSON text into a document.
+func (c *JsonDocumentCodec) Decode(data interface{}, target interface{}) error {
+	return json.Unmarshal(documentBytes(data), target)
+}
+
+// Returns true because documents are JSON text.
+func (c *JsonDocumentCodec) IsJson() bool {
+	return true
+}
+
+// Codec that stores documents as gzip-compressed JSON in BLOBs.
+// Documents stored as uncompressed JSON text are still decoded,
+// so existing tables can switch to the codec without rewriting documents.
+type GzipDocumentCodec struct {
+	// The compression level from gzip.BestSpeed to gzip.BestCompression.
+	Level int
+}
+
+// Creates a new instance of the codec.
+// - level     the compression level, gzip.DefaultCompression for the default level.
+func NewGzipDocumentCodec(level int) *GzipDocumentCodec {
+	return &GzipDocumentCodec{Level: level}
+}
+
+// Encodes a document into a compressed BLOB.
+func (c *GzipDocumentCodec) Encode(doc interface{}) (interface{}, error) {
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var result bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&result, c.Level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(buf); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// Decodes a compressed BLOB or JSON text into a document.
+func (c *GzipDocumentCodec) Decode(data interface{}, target interface{}) error {
+	buf := documentBytes(data)
+	if len(buf) < 2 || buf[0] != 0x1f || buf[1] != 0x8b {
+		return json.Unmarshal(buf, target)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	buf, err = io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, target)
+}
+
+// Returns false because documents are compressed.
+func (c *GzipDocumentCodec) IsJson() bool {
+	return false
+}
+
+// Codec that stores documents in BLOBs encoded by encoding/gob.
+// Gob decodes documents only into the types they were encoded from,
+// so it doesn't support upcasting of documents and prototypes shall be structs or pointers to structs.
+type GobDocumentCodec struct{}
+
+// Creates a new instance of the codec.
+func NewGobDocumentCodec() *GobDocumentCodec {
+	return &GobDocumentCodec{}
+}
+
+func init() {
+	// Nested values of decoded JSON documents
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Encodes a document into a gob BLOB.
+func (c *GobDocumentCodec) Encode(doc interface{}) (interface{}, error) {
+	var result bytes.Buffer
+	if err := gob.NewEncoder(&result).Encode(doc); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// Decodes a gob BLOB into a document.
+func (c *GobDocumentCodec) Decode(data interface{}, target interface{}) error {
+	buf := documentBytes(data)
+	if len(buf) == 0 {
+		return errors.New("document is empty")
+	}
+	return gob.NewDecoder(bytes.NewReader(buf)).Decode(target)
+}
+
+// Returns false because documents are binary.
+func (c *GobDocumentCodec) IsJson() bool {
+	return false
+}
+
+// Converts a value of the data column into bytes.
+func documentBytes(data interface{}) []byte {
+	switch value := data.(type) {
+	case []byte:
+		return value
+	case string:
+		return []byte(value)
+	}
+	return nil
+}
+
+// Creates a codec by its name in configuration.
+func createDocumentCodec(correlationId string, name string) (SqliteDocumentCodec, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return NewJsonDocumentCodec(), nil
+	case "gzip":
+		return NewGzipDocumentCodec(gzip.DefaultCompression), nil
+	case "gob":
+		return NewGobDocumentCodec(), nil
+	default:
+		return nil, cerr.NewConfigError(correlationId, "WRONG_CODEC", "Document codec "+name+" is not supported").
+			WithDetails("codec", name)
+	}
+}
@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Versioned schema migration step for SqlitePersistence.
+
+Migrations are applied in ascending version order on top of the baseline
+schema created from DefineSchema. Once applied, a migration is recorded
+in the schema_migrations table together with a checksum of its up script,
+so modified migrations are detected on the next opening.
+
+### Example ###
+
+	func (c *MySqlitePersistence) DefineSchema() {
+		c.ClearSchema()
+		c.EnsureSchema("CREATE TABLE \"mydata\" (\"id\" TEXT PRIMARY KEY, \"name\" TEXT)")
+		c.EnsureMigration(1, "add_description",
+			"ALTER TABLE \"mydata\" ADD COLUMN \"description\" TEXT",
+			"ALTER TABLE \"mydata\" DROP COLUMN \"description\"")
+	}
+*/
+type SqliteMigration struct {
+	// The migration version (must be positive and unique per persistence).
+	Version int
+	// The human-readable migration description.
+	Description string
+	// SQL statements that upgrade the schema.
+	Up string
+	// SQL statements that revert the upgrade (optional).
+	Down string
+}
+
+// Creates a new instance of the migration step.
+// - version     a migration version.
+// - description a migration description.
+// - up          SQL statements to upgrade the schema.
+// - down        (optional) SQL statements to revert the upgrade.
+func NewSqliteMigration(version int, description string, up string, down string) *SqliteMigration {
+	return &SqliteMigration{
+		Version:     version,
+		Description: description,
+		Up:          up,
+		Down:        down,
+	}
+}
+
+// Calculates a checksum of the upgrade statements.
+// Returns SHA-256 hash of the up script in hex format.
+func (c *SqliteMigration) Checksum() string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(c.Up)))
+	return hex.EncodeToString(hash[:])
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.*)\.(up|down)\.sql$`)
+
+// Reads migration steps from a file system like embed.FS.
+// Files must be named as <version>_<description>.up.sql and <version>_<description>.down.sql.
+// - fsys     a file system to read migrations from.
+// - dir      a directory with migration files ("." for the root).
+// Returns migration steps sorted by version or error.
+func ReadSqliteMigrations(fsys fs.FS, dir string) ([]*SqliteMigration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[int]*SqliteMigration, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = NewSqliteMigration(version, strings.ReplaceAll(match[2], "_", " "), "", "")
+			migrations[version] = migration
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]*SqliteMigration, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
@@ -0,0 +1,489 @@
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+)
+
+/*
+Event record kept in the outbox table until it is delivered.
+*/
+type SqliteOutboxMessage struct {
+	// The sequential number of the message that defines the delivery order.
+	Id int64 `json:"id"`
+	// The event type, for instance "orders.create".
+	Type string `json:"type"`
+	// The key of the event, usually the id of the changed data item.
+	Key string `json:"key"`
+	// The event payload as JSON.
+	Payload json.RawMessage `json:"payload"`
+	// The correlation id of the call that produced the event.
+	CorrelationId string `json:"correlation_id"`
+	// The time when the message was appended.
+	CreatedAt time.Time `json:"created_at"`
+	// The number of failed delivery attempts.
+	Attempts int `json:"attempts"`
+	// The error of the last failed delivery attempt.
+	LastError string `json:"last_error"`
+}
+
+// Interface for components that deliver outbox messages to a message broker or other consumers.
+type SqliteOutboxPublisher interface {
+	// Publishes a message. Returned errors make the relay retry the message later.
+	// - correlationId     (optional) transaction id to trace execution through call chain.
+	// - message           a message to be published.
+	// Returns          error or nil when the message was delivered.
+	Publish(correlationId string, message *SqliteOutboxMessage) error
+}
+
+/*
+Transactional outbox that publishes events exactly when the data changes that produced them are committed.
+
+Events are appended to the outbox table in the same transaction as the data changes,
+either by persistence components that reference the outbox or by Append inside custom transactions.
+A relay started on opening reads pending messages in order of their ids,
+hands them to the publisher and marks them as delivered.
+
+Delivery is at-least-once: a message is published again when marking it as delivered fails
+or when several relays share the same table, so consumers shall be idempotent.
+A failed message is retried with exponential backoff and blocks all following messages to keep their order.
+Delivered messages are removed after the retention period.
+
+### Configuration parameters ###
+
+- table:                       (optional) name of the outbox table (default: outbox)
+- connection(s):
+  - discovery_key:             (optional) a key to retrieve the connection from [[IDiscovery]]
+  - database:                  path to database file
+
+- options:
+  - interval:                  (optional) relay interval in milliseconds, 0 disables the relay (default: 1000)
+  - batch_size:                (optional) maximum number of messages delivered in one run (default: 100)
+  - retry_timeout:             (optional) delay before the first retry of a failed message in milliseconds (default: 1000)
+  - max_retry_timeout:         (optional) maximum delay between retries in milliseconds (default: 600000)
+  - retention:                 (optional) time to keep delivered messages in milliseconds, 0 keeps them forever (default: 86400000)
+
+### References ###
+
+- \*:logger:\*:\*:1.0           (optional) [[ILogger]] components to pass log messages
+- \*:connection:sqlite:\*:1.0   (optional) shared SqliteConnection, a local one is created when missing
+- publisher                   (optional) [[SqliteOutboxPublisher]] resolved when dependencies.publisher is configured
+
+### Example ###
+
+	outbox := persist.NewSqliteOutbox()
+	outbox.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", "./data/app.db",
+	))
+	outbox.Publisher = myBrokerPublisher
+	outbox.Open("123")
+
+	persistence.Outbox = outbox
+	persistence.Open("123")
+	persistence.Create("123", MyData{Id: "1", Name: "ABC"}) // Publishes "mydata.create" event
+*/
+type SqliteOutbox struct {
+	defaultConfig *cconf.ConfigParams
+	config        *cconf.ConfigParams
+	references    cref.IReferences
+	// The dependency resolver.
+	DependencyResolver *cref.DependencyResolver
+	// The logger.
+	Logger *clog.CompositeLogger
+	// The SQLite connection used by the relay.
+	Connection *conn.SqliteConnection
+	// The SQLite client used by the relay.
+	Client *sql.DB
+	// The name of the outbox table.
+	TableName string
+	// The publisher that delivers messages.
+	Publisher SqliteOutboxPublisher
+	// The clock used to schedule deliveries. When nil the system time is used.
+	Clock Clock
+	// The relay interval in milliseconds. Zero disables the relay.
+	Interval int
+	// The maximum number of messages delivered in one run.
+	BatchSize int
+	// The delay before the first retry of a failed message.
+	RetryTimeout time.Duration
+	// The maximum delay between retries.
+	MaxRetryTimeout time.Duration
+	// The time to keep delivered messages. Zero keeps them forever.
+	Retention time.Duration
+
+	localConnection bool
+	lock            sync.Mutex
+	relayLock       sync.Mutex
+	timer           *crun.FixedRateTimer
+}
+
+// Creates a new instance of the outbox.
+func NewSqliteOutbox() *SqliteOutbox {
+	c := &SqliteOutbox{
+		defaultConfig: cconf.NewConfigParamsFromTuples(
+			"dependencies.connection", "*:connection:sqlite:*:1.0",
+		),
+		Logger:          clog.NewCompositeLogger(),
+		TableName:       "outbox",
+		Interval:        1000,
+		BatchSize:       100,
+		RetryTimeout:    time.Second,
+		MaxRetryTimeout: 10 * time.Minute,
+		Retention:       24 * time.Hour,
+	}
+	c.DependencyResolver = cref.NewDependencyResolver()
+	c.DependencyResolver.Configure(c.defaultConfig)
+	return c
+}
+
+// Configures component by passing configuration parameters.
+// - config    configuration parameters to be set.
+func (c *SqliteOutbox) Configure(config *cconf.ConfigParams) {
+	config = config.SetDefaults(c.defaultConfig)
+	c.config = config
+	c.DependencyResolver.Configure(config)
+
+	c.TableName = config.GetAsStringWithDefault("table", c.TableName)
+	c.Interval = config.GetAsIntegerWithDefault("options.interval", c.Interval)
+	c.BatchSize = config.GetAsIntegerWithDefault("options.batch_size", c.BatchSize)
+	c.RetryTimeout = time.Duration(config.GetAsLongWithDefault("options.retry_timeout",
+		int64(c.RetryTimeout/time.Millisecond))) * time.Millisecond
+	c.MaxRetryTimeout = time.Duration(config.GetAsLongWithDefault("options.max_retry_timeout",
+		int64(c.MaxRetryTimeout/time.Millisecond))) * time.Millisecond
+	c.Retention = time.Duration(config.GetAsLongWithDefault("options.retention",
+		int64(c.Retention/time.Millisecond))) * time.Millisecond
+}
+
+// Sets references to dependent components.
+// - references 	references to locate the component dependencies.
+func (c *SqliteOutbox) SetReferences(references cref.IReferences) {
+	c.references = references
+	c.Logger.SetReferences(references)
+
+	c.DependencyResolver.SetReferences(references)
+	if dep, ok := c.DependencyResolver.GetOneOptional("connection").(*conn.SqliteConnection); ok {
+		c.Connection = dep
+		c.localConnection = false
+	}
+	if dep, ok := c.DependencyResolver.GetOneOptional("publisher").(SqliteOutboxPublisher); ok {
+		c.Publisher = dep
+	}
+}
+
+// Checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *SqliteOutbox) IsOpen() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Client != nil
+}
+
+// Opens the component: creates the outbox table and starts the relay.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns 			error or nil no errors occured.
+func (c *SqliteOutbox) Open(correlationId string) (err error) {
+	if c.IsOpen() {
+		return nil
+	}
+
+	if c.Connection == nil {
+		c.Connection = conn.NewSqliteConnection()
+		if c.config != nil {
+			c.Connection.Configure(c.config)
+		}
+		if c.references != nil {
+			c.Connection.SetReferences(c.references)
+		}
+		c.localConnection = true
+	}
+	if c.localConnection {
+		if err = c.Connection.Open(correlationId); err != nil {
+			return err
+		}
+	}
+	if !c.Connection.IsOpen() {
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "SQLite connection is not opened")
+	}
+
+	client := c.Connection.GetConnection()
+	if err = c.createTable(client); err != nil {
+		c.closeConnection(correlationId)
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create outbox table "+c.TableName).
+			WithCause(err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Client = client
+	if c.Interval > 0 {
+		c.timer = crun.NewFixedRateTimerFromCallback(func() { c.relay(correlationId) }, c.Interval, c.Interval)
+		c.timer.Start()
+	}
+	c.Logger.Debug(correlationId, "Opened outbox %s", c.TableName)
+	return nil
+}
+
+// Closes component and stops the relay.
+// - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns 			error or nil no errors occured.
+func (c *SqliteOutbox) Close(correlationId string) error {
+	c.lock.Lock()
+	if c.Client == nil {
+		c.lock.Unlock()
+		return nil
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.Client = nil
+	c.lock.Unlock()
+
+	// Waits for the current run of the relay
+	c.relayLock.Lock()
+	c.relayLock.Unlock()
+
+	err := c.closeConnection(correlationId)
+	c.Logger.Debug(correlationId, "Closed outbox %s", c.TableName)
+	return err
+}
+
+func (c *SqliteOutbox) getClient() *sql.DB {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Client
+}
+
+func (c *SqliteOutbox) closeConnection(correlationId string) error {
+	if c.localConnection && c.Connection != nil {
+		return c.Connection.Close(correlationId)
+	}
+	return nil
+}
+
+func (c *SqliteOutbox) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Creates the outbox table and its index when they don't exist.
+func (c *SqliteOutbox) createTable(db sqlExecutor) error {
+	table := conn.QuoteIdentifier(c.TableName)
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS " + table +
+		" (\"id\" INTEGER PRIMARY KEY AUTOINCREMENT, \"type\" TEXT NOT NULL, \"key\" TEXT, \"payload\" TEXT," +
+		" \"correlation_id\" TEXT, \"created_at\" TEXT NOT NULL, \"attempts\" INTEGER NOT NULL DEFAULT 0," +
+		" \"next_attempt_at\" TEXT, \"last_error\" TEXT, \"delivered_at\" TEXT)")
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS " + conn.QuoteIdentifier(c.TableName+"_delivered_at") +
+		" ON " + table + " (\"delivered_at\", \"id\")")
+	return err
+}
+
+// Appends messages to the outbox inside a transaction.
+// They become visible to the relay only when the transaction is committed.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - tx                a transaction that changes data.
+// - messages          messages to be appended. Ids, attempts and creation times are ignored.
+// Returns          error or nil for success.
+func (c *SqliteOutbox) Append(correlationId string, tx *sql.Tx, messages ...*SqliteOutboxMessage) error {
+	if tx == nil {
+		return cerr.NewInvalidStateError(correlationId, "NO_TRANSACTION", "Outbox messages must be appended in a transaction")
+	}
+	return c.append(tx, correlationId, messages...)
+}
+
+func (c *SqliteOutbox) append(db sqlExecutor, correlationId string, messages ...*SqliteOutboxMessage) error {
+	query := "INSERT INTO " + conn.QuoteIdentifier(c.TableName) +
+		" (\"type\", \"key\", \"payload\", \"correlation_id\", \"created_at\") VALUES (?1, ?2, ?3, ?4, ?5)"
+	createdAt := FormatSqliteTimestamp(c.now())
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		var payload, corrId interface{}
+		if message.Payload != nil {
+			payload = string(message.Payload)
+		}
+		if message.CorrelationId != "" {
+			corrId = message.CorrelationId
+		} else if correlationId != "" {
+			corrId = correlationId
+		}
+		if _, err := db.Exec(query, message.Type, message.Key, payload, corrId, createdAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gets messages that are not delivered yet in the delivery order.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns          pending messages or error.
+func (c *SqliteOutbox) GetPendingMessages(correlationId string) (messages []*SqliteOutboxMessage, err error) {
+	client := c.getClient()
+	if client == nil {
+		return nil, cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Outbox "+c.TableName+" is not opened")
+	}
+	messages, _, err = c.readPending(client, 0)
+	return messages, err
+}
+
+// Reads pending messages with times of their next delivery attempts. Zero limit reads all messages.
+func (c *SqliteOutbox) readPending(client *sql.DB, limit int) (messages []*SqliteOutboxMessage, nextAttempts []string, err error) {
+	query := "SELECT \"id\", \"type\", \"key\", \"payload\", \"correlation_id\", \"created_at\", \"attempts\"," +
+		" \"next_attempt_at\", \"last_error\" FROM " + conn.QuoteIdentifier(c.TableName) +
+		" WHERE \"delivered_at\" IS NULL ORDER BY \"id\""
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	qResult, qErr := client.Query(query)
+	if qErr != nil {
+		return nil, nil, qErr
+	}
+	defer qResult.Close()
+
+	messages = make([]*SqliteOutboxMessage, 0)
+	nextAttempts = make([]string, 0)
+	for qResult.Next() {
+		message := &SqliteOutboxMessage{}
+		var key, payload, corrId, nextAttempt, lastError sql.NullString
+		var createdAt string
+		err = qResult.Scan(&message.Id, &message.Type, &key, &payload, &corrId, &createdAt,
+			&message.Attempts, &nextAttempt, &lastError)
+		if err != nil {
+			return nil, nil, err
+		}
+		message.Key = key.String
+		if payload.Valid {
+			message.Payload = json.RawMessage(payload.String)
+		}
+		message.CorrelationId = corrId.String
+		message.LastError = lastError.String
+		if message.CreatedAt, err = time.Parse(SqliteTimestampFormat, createdAt); err != nil {
+			return nil, nil, err
+		}
+		messages = append(messages, message)
+		nextAttempts = append(nextAttempts, nextAttempt.String)
+	}
+	return messages, nextAttempts, qResult.Err()
+}
+
+// Delivers pending messages in order until a message fails or waits for a retry.
+// The relay calls it on every tick, it can also be called to deliver messages right away.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns          a number of delivered messages or error.
+func (c *SqliteOutbox) Relay(correlationId string) (count int, err error) {
+	c.relayLock.Lock()
+	defer c.relayLock.Unlock()
+
+	client := c.getClient()
+	if client == nil {
+		return 0, cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Outbox "+c.TableName+" is not opened")
+	}
+	if c.Publisher == nil {
+		return 0, cerr.NewInvalidStateError(correlationId, "NO_PUBLISHER", "Publisher of outbox "+c.TableName+" is missing")
+	}
+
+	messages, nextAttempts, err := c.readPending(client, c.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	table := conn.QuoteIdentifier(c.TableName)
+	for index, message := range messages {
+		now := c.now()
+		if nextAttempts[index] != "" && nextAttempts[index] > FormatSqliteTimestamp(now) {
+			break
+		}
+
+		if pubErr := c.Publisher.Publish(message.CorrelationId, message); pubErr != nil {
+			message.Attempts++
+			nextAttempt := now.Add(c.retryTimeout(message.Attempts))
+			_, err = client.Exec("UPDATE "+table+" SET \"attempts\"=?1, \"next_attempt_at\"=?2, \"last_error\"=?3 WHERE \"id\"=?4",
+				message.Attempts, FormatSqliteTimestamp(nextAttempt), pubErr.Error(), message.Id)
+			c.Logger.Warn(message.CorrelationId, "Failed to publish message %d from outbox %s, attempt %d: %s",
+				message.Id, c.TableName, message.Attempts, pubErr.Error())
+			break
+		}
+
+		_, err = client.Exec("UPDATE "+table+" SET \"delivered_at\"=?1 WHERE \"id\"=?2", FormatSqliteTimestamp(now), message.Id)
+		if err != nil {
+			break
+		}
+		count++
+	}
+
+	if count > 0 {
+		c.Logger.Trace(correlationId, "Delivered %d messages from outbox %s", count, c.TableName)
+	}
+	return count, err
+}
+
+// Calculates the delay before the next attempt to deliver a message that failed a number of times.
+func (c *SqliteOutbox) retryTimeout(attempts int) time.Duration {
+	timeout := c.RetryTimeout
+	for i := 1; i < attempts && timeout < c.MaxRetryTimeout; i++ {
+		timeout *= 2
+	}
+	if c.MaxRetryTimeout > 0 && timeout > c.MaxRetryTimeout {
+		timeout = c.MaxRetryTimeout
+	}
+	return timeout
+}
+
+// Removes messages that were delivered before the retention period.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns          a number of removed messages or error.
+func (c *SqliteOutbox) Cleanup(correlationId string) (count int64, err error) {
+	client := c.getClient()
+	if client == nil {
+		return 0, cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Outbox "+c.TableName+" is not opened")
+	}
+	if c.Retention <= 0 {
+		return 0, nil
+	}
+
+	deliveredBefore := FormatSqliteTimestamp(c.now().Add(-c.Retention))
+	qResult, err := client.Exec("DELETE FROM "+conn.QuoteIdentifier(c.TableName)+
+		" WHERE \"delivered_at\" IS NOT NULL AND \"delivered_at\"<?1", deliveredBefore)
+	if err != nil {
+		return 0, err
+	}
+	if count, err = qResult.RowsAffected(); err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		c.Logger.Trace(correlationId, "Removed %d delivered messages from outbox %s", count, c.TableName)
+	}
+	return count, nil
+}
+
+// Runs the relay and the cleanup on a timer tick.
+func (c *SqliteOutbox) relay(correlationId string) {
+	if !c.IsOpen() {
+		return
+	}
+	if c.Publisher != nil {
+		if _, err := c.Relay(correlationId); err != nil {
+			c.Logger.Error(correlationId, err, "Failed to relay messages from outbox %s", c.TableName)
+		}
+	}
+	if _, err := c.Cleanup(correlationId); err != nil {
+		c.Logger.Error(correlationId, err, "Failed to clean up outbox %s", c.TableName)
+	}
+}
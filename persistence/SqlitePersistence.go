@@ -4,8 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"math/rand"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +35,10 @@ able to store data items of any type. Specific CRUD operations
 over the data items must be implemented in child classes by
 accessing c._db or c._collection properties.
 
+Full-text indexes defined by EnsureFullTextIndex need SQLite with FTS5 support.
+Build with the sqlite_fts5 tag, for instance go test -tags "sqlite_json sqlite_fts5",
+otherwise opening fails with "no such module: fts5".
+
 ### Configuration parameters ###
 
 - collection:                  (optional) SQLite collection name
@@ -47,6 +51,14 @@ accessing c._db or c._collection properties.
   - store_key:                 (optional) a key to retrieve the credentials from [[https://rawgit.com/pip-services-node/pip-services3-components-node/master/doc/api/interfaces/auth.icredentialstore.html ICredentialStore]]
   - username:                  (optional) user name
   - password:                  (optional) user password
+- options:
+  - max_page_size:             (optional) maximum number of items returned in a single page (default: 100)
+  - migrations_table:          (optional) name of the table that keeps applied migrations (default: schema_migrations)
+  - auto_migrate:              (optional) compares the live table with the declared schema on opening and adds missing columns and indexes (default: false)
+  - strict_schema:             (optional) fails opening when auto_migrate finds differences it cannot fix (default: false)
+  - soft_delete:               (optional) marks deleted items with a timestamp instead of removing them (default: false)
+  - deleted_column:            (optional) name of the column that keeps the time of soft deletion (default: deleted_at)
+  - random_seed:               (optional) seed of random picks by GetOneRandom and GetRandomSample to make them reproducible
 
 ### References ###
 
@@ -102,6 +114,7 @@ type SqlitePersistence struct {
 	opened           bool
 	localConnection  bool
 	schemaStatements []string
+	migrations       []*SqliteMigration
 
 	//The dependency resolver.
 	DependencyResolver *cref.DependencyResolver
@@ -116,6 +129,24 @@ type SqlitePersistence struct {
 	//The SQLite table object.
 	TableName   string
 	MaxPageSize int
+	//The name of the table that keeps applied migrations.
+	MigrationsTableName string
+	//The flag to fix additive schema differences on opening.
+	AutoMigrate bool
+	//The flag to fail opening on schema differences that cannot be fixed.
+	StrictSchema bool
+	//The flag to mark deleted items with a timestamp instead of removing them.
+	SoftDelete bool
+	//The name of the column that keeps the time of soft deletion.
+	DeletedColumn string
+	//The clock used to stamp data items and their soft deletions. When nil the system time is used.
+	Clock Clock
+
+	random        *sqliteRandom
+	fullTextIndex *sqliteFullTextIndex
+	jsonFields    []*sqliteJsonField
+	// JSON fields are kept in columns filled on writes instead of generated columns
+	storedJsonFields bool
 }
 
 // Creates a new instance of the persistence component.
@@ -129,10 +160,14 @@ func InheritSqlitePersistence(overrides ISqlitePersistenceOverrides, proto refle
 			"collection", nil,
 			"dependencies.connection", "*:connection:sqlite:*:1.0",
 		),
-		schemaStatements: make([]string, 0),
-		Logger:           clog.NewCompositeLogger(),
-		MaxPageSize:      100,
-		TableName:        tableName,
+		schemaStatements:    make([]string, 0),
+		migrations:          make([]*SqliteMigration, 0),
+		Logger:              clog.NewCompositeLogger(),
+		MaxPageSize:         100,
+		TableName:           tableName,
+		MigrationsTableName: "schema_migrations",
+		DeletedColumn:       "deleted_at",
+		random:              newSqliteRandom(time.Now().UnixNano()),
 	}
 
 	c.DependencyResolver = cref.NewDependencyResolver()
@@ -152,6 +187,14 @@ func (c *SqlitePersistence) Configure(config *cconf.ConfigParams) {
 	c.TableName = config.GetAsStringWithDefault("collection", c.TableName)
 	c.TableName = config.GetAsStringWithDefault("table", c.TableName)
 	c.MaxPageSize = config.GetAsIntegerWithDefault("options.max_page_size", c.MaxPageSize)
+	c.MigrationsTableName = config.GetAsStringWithDefault("options.migrations_table", c.MigrationsTableName)
+	c.AutoMigrate = config.GetAsBooleanWithDefault("options.auto_migrate", c.AutoMigrate)
+	c.StrictSchema = config.GetAsBooleanWithDefault("options.strict_schema", c.StrictSchema)
+	c.SoftDelete = config.GetAsBooleanWithDefault("options.soft_delete", c.SoftDelete)
+	c.DeletedColumn = config.GetAsStringWithDefault("options.deleted_column", c.DeletedColumn)
+	if seed := config.GetAsNullableLong("options.random_seed"); seed != nil {
+		c.SetRandomSeed(*seed)
+	}
 }
 
 // Sets references to dependent components.
@@ -192,6 +235,8 @@ func (c *SqlitePersistence) createConnection() *conn.SqliteConnection {
 }
 
 // Adds index definition to create it on opening
+// Maps don't keep the order of keys, so keys are indexed in alphabetical order.
+// Indexes with other orders of keys shall be defined by EnsureSchema.
 // - keys index keys (fields)
 // - options index options
 func (c *SqlitePersistence) EnsureIndex(name string, keys map[string]string, options map[string]string) {
@@ -210,8 +255,14 @@ func (c *SqlitePersistence) EnsureIndex(name string, keys map[string]string, opt
 		builder += " " + options["type"]
 	}
 
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
 	fields := ""
-	for key, _ := range keys {
+	for _, key := range names {
 		if fields != "" {
 			fields += ", "
 		}
@@ -242,6 +293,8 @@ func (c *SqlitePersistence) EnsureSchema(schemaStatement string) {
 // Clears all auto-created objects
 func (c *SqlitePersistence) ClearSchema() {
 	c.schemaStatements = []string{}
+	c.fullTextIndex = nil
+	c.jsonFields = nil
 }
 
 // Converts object value from internal to func (c * SqlitePersistence) format.
@@ -344,17 +397,65 @@ func (c *SqlitePersistence) Open(correlationId string) (err error) {
 	// Recreate objects
 	err = c.CreateSchema(correlationId)
 	if err != nil {
-		c.Client = nil
-		err = cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Connection to sqlite failed").WithCause(err)
-	} else {
-		c.opened = true
-		c.Logger.Debug(correlationId, "Connected to sqlite database %s, collection %s", c.DatabaseName, c.QuoteIdentifier(c.TableName))
+		c.abortOpen(correlationId)
+		return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Connection to sqlite failed").WithCause(err)
 	}
 
-	return err
+	// Apply versioned migrations
+	err = c.Migrate(correlationId)
+	if err != nil {
+		c.abortOpen(correlationId)
+		return err
+	}
+
+	// Bring the live table in line with the declared schema
+	if c.AutoMigrate {
+		_, err = c.FixSchemaDrift(correlationId)
+		if err != nil {
+			c.abortOpen(correlationId)
+			return err
+		}
+	}
+
+	// Add generated columns of JSON fields to existing tables
+	if len(c.jsonFields) > 0 {
+		err = c.createJsonFields(correlationId)
+		if err != nil {
+			c.abortOpen(correlationId)
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create JSON fields of "+c.TableName).
+				WithCause(err)
+		}
+	}
+
+	// Create the full-text index over existing tables
+	if c.fullTextIndex != nil {
+		err = c.createFullTextIndex(correlationId)
+		if err != nil {
+			c.abortOpen(correlationId)
+			if appErr, ok := err.(*cerr.ApplicationError); ok {
+				return appErr
+			}
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to create full-text index "+c.FullTextTableName()).
+				WithCause(err)
+		}
+	}
+
+	c.opened = true
+	c.Logger.Debug(correlationId, "Connected to sqlite database %s, collection %s", c.DatabaseName, c.QuoteIdentifier(c.TableName))
+	return nil
 
 }
 
+// Releases resources of a failed opening. Local connections are closed,
+// so the next opening doesn't create another connection pool over them.
+func (c *SqlitePersistence) abortOpen(correlationId string) {
+	c.Client = nil
+	if c.localConnection && c.Connection != nil {
+		// Errors are logged by the connection
+		c.Connection.Close(correlationId)
+	}
+}
+
 // Closes component and frees used resources.
 // - correlationId 	(optional) transaction id to trace execution through call chain.
 // - Returns 			error or nil no errors occured.
@@ -443,7 +544,7 @@ func (c *SqlitePersistence) GenerateColumns(values interface{}) string {
 
 }
 
-// Generates a list of value parameters to use in SQL statements like: "$1,$2,$3"
+// Generates a list of value parameters to use in SQL statements like: "?1,?2,?3"
 // - values an array with values or a key-value map
 // Returns a generated list of value parameters
 func (c *SqlitePersistence) GenerateParameters(values interface{}) string {
@@ -455,8 +556,8 @@ func (c *SqlitePersistence) GenerateParameters(values interface{}) string {
 			if result.String() != "" {
 				result.WriteString(",")
 			}
-			result.WriteString("$")
-			result.WriteString(strconv.FormatInt((int64)(index), 16))
+			result.WriteString("?")
+			result.WriteString(strconv.Itoa(index))
 		}
 
 		return result.String()
@@ -471,14 +572,14 @@ func (c *SqlitePersistence) GenerateParameters(values interface{}) string {
 		if result.String() != "" {
 			result.WriteString(",")
 		}
-		result.WriteString("$")
-		result.WriteString(strconv.FormatInt((int64)(index), 16))
+		result.WriteString("?")
+		result.WriteString(strconv.Itoa(index))
 	}
 
 	return result.String()
 }
 
-// Generates a list of column sets to use in UPDATE statements like: column1=$1,column2=$2
+// Generates a list of column sets to use in UPDATE statements like: column1=?1,column2=?2
 // - values a key-value map with columns and values
 // Returns a generated list of column sets
 func (c *SqlitePersistence) GenerateSetParameters(values interface{}) (params string, columns string) {
@@ -495,7 +596,7 @@ func (c *SqlitePersistence) GenerateSetParameters(values interface{}) (params st
 			setParamsBuf.WriteString(",")
 			columnBuf.WriteString(",")
 		}
-		setParamsBuf.WriteString(c.QuoteIdentifier(column) + "=$" + strconv.FormatInt((int64)(index), 16))
+		setParamsBuf.WriteString(c.QuoteIdentifier(column) + "=?" + strconv.Itoa(index))
 		columnBuf.WriteString(c.QuoteIdentifier(column))
 		index++
 	}
@@ -536,6 +637,14 @@ func (c *SqlitePersistence) convertToMap(values interface{}) map[string]interfac
 		c.Logger.Error("SqlitePersistence", mErr, "Error data convertion")
 		return nil
 	}
+	// BLOBs are kept as they are instead of base64 strings
+	if source, ok := values.(map[string]interface{}); ok {
+		for key, value := range source {
+			if value, ok := value.([]byte); ok {
+				items[key] = value
+			}
+		}
+	}
 	return items
 }
 
@@ -566,10 +675,8 @@ func (c *SqlitePersistence) GetPageByFilter(correlationId string, filter interfa
 	take := paging.GetTake((int64)(c.MaxPageSize))
 	pagingEnabled := paging.Total
 
-	if filter != nil {
-		if flt, ok := filter.(string); ok && flt != "" {
-			query += " WHERE " + flt
-		}
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " WHERE " + flt
 	}
 
 	if sort != nil {
@@ -604,10 +711,8 @@ func (c *SqlitePersistence) GetPageByFilter(correlationId string, filter interfa
 
 	if pagingEnabled {
 		query := "SELECT COUNT(*) AS count FROM " + c.QuoteIdentifier(c.TableName)
-		if filter != nil {
-			if flt, ok := sel.(string); ok && flt != "" {
-				query += " WHERE " + flt
-			}
+		if flt := c.composeActiveFilter(filter); flt != "" {
+			query += " WHERE " + flt
 		}
 
 		qResult2, qErr2 := c.Client.Query(query)
@@ -642,10 +747,8 @@ func (c *SqlitePersistence) GetCountByFilter(correlationId string, filter interf
 
 	query := "SELECT COUNT(*) AS count FROM " + c.QuoteIdentifier(c.TableName)
 
-	if filter != nil {
-		if flt, ok := filter.(string); ok && flt != "" {
-			query += " WHERE " + flt
-		}
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " WHERE " + flt
 	}
 
 	qResult, qErr := c.Client.Query(query)
@@ -687,10 +790,8 @@ func (c *SqlitePersistence) GetListByFilter(correlationId string, filter interfa
 		}
 	}
 
-	if filter != nil {
-		if flt, ok := filter.(string); ok && flt != "" {
-			query += " WHERE " + flt
-		}
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " WHERE " + flt
 	}
 
 	if sort != nil {
@@ -718,63 +819,25 @@ func (c *SqlitePersistence) GetListByFilter(correlationId string, filter interfa
 }
 
 // Gets a random item from items that match to a given filter.
+// The item is picked by a random rowid, see GetRandomSample.
 // This method shall be called by a func (c * SqlitePersistence) getOneRandom method from child class that
 // receives FilterParams and converts them into a filter function.
 // - correlationId     (optional) transaction id to trace execution through call chain.
 // - filter            (optional) a filter JSON object
-// - Returns            random item or error.
+// - Returns            random item or error. Nil is returned when nothing is found.
 func (c *SqlitePersistence) GetOneRandom(correlationId string, filter interface{}) (item interface{}, err error) {
-
-	query := "SELECT COUNT(*) AS count FROM " + c.QuoteIdentifier(c.TableName)
-
-	if filter != nil {
-		if flt, ok := filter.(string); ok && flt != "" {
-			query += " WHERE " + flt
-		}
-	}
-
-	qResult, qErr := c.Client.Query(query)
-	if qErr != nil {
-		return nil, qErr
-	}
-	defer qResult.Close()
-
-	query = "SELECT * FROM " + c.QuoteIdentifier(c.TableName)
-	if filter != nil {
-		if flt, ok := filter.(string); ok && flt != "" {
-			query += " WHERE " + flt
-		}
-	}
-
-	var count int64 = 0
-	if !qResult.Next() {
-		return nil, qResult.Err()
-	}
-
-	var cnt interface{}
-	err = qResult.Scan(&cnt)
-	if err != nil {
-		cnt = 0
-	}
-	count = cconv.LongConverter.ToLong(cnt)
-
-	rand.Seed(time.Now().UnixNano())
-	pos := rand.Int63n(int64(count))
-	query += " OFFSET " + strconv.FormatInt(pos, 10) + " LIMIT 1"
-	qResult2, qErr2 := c.Client.Query(query)
-	if qErr2 != nil {
-		return nil, qErr
-	}
-	defer qResult2.Close()
-	if !qResult2.Next() {
+	rowId, found, err := c.pickRandomRowId(filter)
+	if err != nil || !found {
 		c.Logger.Trace(correlationId, "Random item wasn't found from %s", c.TableName)
-		return nil, qResult2.Err()
+		return nil, err
 	}
 
-	item = c.Overrides.ConvertToPublic(qResult2)
+	item, err = c.queryOneByRowId(c.Client, rowId)
+	if err != nil || item == nil {
+		return nil, err
+	}
 	c.Logger.Trace(correlationId, "Retrieved random item from %s", c.TableName)
 	return item, nil
-
 }
 
 // Creates a data item.
@@ -815,12 +878,8 @@ func (c *SqlitePersistence) Create(correlationId string, item interface{}) (resu
 // - filter            (optional) a filter JSON object.
 // - Returns           error or nil for success.
 func (c *SqlitePersistence) DeleteByFilter(correlationId string, filter string) (err error) {
-	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName)
-	if filter != "" {
-		query += " WHERE " + filter
-	}
-
-	qResult, qErr := c.Client.Exec(query)
+	query, values := c.composeDeleteByFilter(filter)
+	qResult, qErr := c.Client.Exec(query, values...)
 
 	if qErr != nil {
 		return qErr
@@ -835,6 +894,26 @@ func (c *SqlitePersistence) DeleteByFilter(correlationId string, filter string)
 	return nil
 }
 
+// Composes a statement that deletes data items matching a filter.
+// Soft-deleted items are marked with the time of deletion instead.
+func (c *SqlitePersistence) composeDeleteByFilter(filter string) (query string, values []interface{}) {
+	query = "DELETE FROM " + c.QuoteIdentifier(c.TableName)
+	if filter != "" {
+		query += " WHERE " + filter
+	}
+	values = []interface{}{}
+
+	if c.SoftDelete {
+		query = "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + c.QuoteIdentifier(c.DeletedColumn) + "=?1" +
+			" WHERE " + c.composeNotDeleted()
+		if filter != "" {
+			query += " AND (" + filter + ")"
+		}
+		values = append(values, FormatSqliteTimestamp(c.now()))
+	}
+	return query, values
+}
+
 // service function for return pointer on new prototype object for unmarshaling
 func (c *SqlitePersistence) NewObjectByPrototype() reflect.Value {
 	proto := c.Prototype
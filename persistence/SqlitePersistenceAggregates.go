@@ -0,0 +1,228 @@
+package persistence
+
+import (
+	"strconv"
+	"strings"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Aggregate functions.
+const (
+	AggregateCount         = "count"
+	AggregateCountDistinct = "count_distinct"
+	AggregateSum           = "sum"
+	AggregateAvg           = "avg"
+	AggregateMin           = "min"
+	AggregateMax           = "max"
+)
+
+/*
+Aggregate computed over groups of data items.
+*/
+type SqliteAggregate struct {
+	// The aggregate function: count, count_distinct, sum, avg, min or max.
+	Function string
+	// The aggregated field: a column name or a JSON path like $.price. Empty field counts all items.
+	// Paths in documents of codecs other than JSON shall be declared by EnsureJsonField.
+	Field string
+	// (optional) The name of the result. By default it is the function and the field like sum_price.
+	Alias string
+}
+
+/*
+Condition on aggregates of groups, the HAVING clause of aggregation queries.
+*/
+type SqliteAggregateCondition struct {
+	// The alias of the aggregate.
+	Alias string
+	// The comparison operator: =, !=, <, <=, > or >=.
+	Operator string
+	// The value to compare with.
+	Value interface{}
+}
+
+/*
+Group of data items with computed aggregates.
+*/
+type SqliteAggregateRow struct {
+	// Values of grouped fields by the fields as they were requested.
+	Groups *cdata.AnyValueMap
+	// Values of aggregates by their aliases. Counts are integers and averages are floats.
+	Values *cdata.AnyValueMap
+}
+
+var aggregateOperators = map[string]bool{"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// Gets the name of an aggregate result.
+func (a *SqliteAggregate) name() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	if a.Field == "" {
+		return strings.ToLower(a.Function)
+	}
+	return strings.ToLower(a.Function) + "_" + fullTextColumnName(a.Field)
+}
+
+// Composes an SQL expression that reads a field: a column or a JSON path in the data column.
+// JSON paths are bound as parameters. Documents of codecs that don't store JSON text can't be read by SQL,
+// so their paths are read from columns declared by EnsureJsonField.
+func (c *SqlitePersistence) composeFieldExpression(correlationId string, field string,
+	values *[]interface{}) (string, error) {
+	if !strings.HasPrefix(field, "$") {
+		return c.QuoteIdentifier(field), nil
+	}
+	if c.storedJsonFields {
+		for _, jsonField := range c.jsonFields {
+			if jsonField.path == field {
+				return c.QuoteIdentifier(jsonField.column), nil
+			}
+		}
+		return "", cerr.NewBadRequestError(correlationId, "INVALID_FIELD",
+			"JSON path "+field+" is not declared by EnsureJsonField and can't be read from encoded documents").
+			WithDetails("field", field)
+	}
+	*values = append(*values, field)
+	return "JSON_EXTRACT(\"data\", ?" + strconv.Itoa(len(*values)) + ")", nil
+}
+
+// Composes an SQL expression of an aggregate.
+func (c *SqlitePersistence) composeAggregateExpression(correlationId string, aggregate *SqliteAggregate,
+	values *[]interface{}) (string, error) {
+	function := strings.ToLower(aggregate.Function)
+	if aggregate.Field == "" {
+		if function != AggregateCount {
+			return "", cerr.NewBadRequestError(correlationId, "INVALID_AGGREGATE",
+				"Aggregate "+aggregate.Function+" requires a field")
+		}
+		return "COUNT(*)", nil
+	}
+
+	field, err := c.composeFieldExpression(correlationId, aggregate.Field, values)
+	if err != nil {
+		return "", err
+	}
+	switch function {
+	case AggregateCount:
+		return "COUNT(" + field + ")", nil
+	case AggregateCountDistinct:
+		return "COUNT(DISTINCT " + field + ")", nil
+	case AggregateSum, AggregateMin, AggregateMax:
+		return strings.ToUpper(function) + "(" + field + ")", nil
+	case AggregateAvg:
+		return "AVG(" + field + ")", nil
+	}
+	return "", cerr.NewBadRequestError(correlationId, "INVALID_AGGREGATE",
+		"Aggregate function "+aggregate.Function+" is not supported")
+}
+
+// Computes aggregates over groups of data items retrieved by a given filter.
+// Groups are sorted by the grouped fields. Fields are column names or JSON paths like $.category
+// inside the data column of JSON persistences. Paths and values of conditions are bound as parameters.
+// This method shall be called by a func (c * SqlitePersistence) getAggregatesByFilter method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object.
+// - groupBy           (optional) grouped fields. Without them aggregates are computed over all items.
+// - aggregates        aggregates to compute.
+// - having            (optional) conditions on aggregates that groups shall satisfy.
+// Returns          groups with aggregates or error.
+func (c *SqlitePersistence) GetAggregatesByFilter(correlationId string, filter interface{}, groupBy []string,
+	aggregates []*SqliteAggregate, having []*SqliteAggregateCondition) (rows []*SqliteAggregateRow, err error) {
+	if len(aggregates) == 0 {
+		return nil, cerr.NewBadRequestError(correlationId, "INVALID_AGGREGATE", "Aggregates are not defined")
+	}
+
+	values := make([]interface{}, 0)
+	columns := make([]string, 0, len(groupBy)+len(aggregates))
+	for _, field := range groupBy {
+		column, err := c.composeFieldExpression(correlationId, field, &values)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	expressions := make(map[string]string, len(aggregates))
+	for _, aggregate := range aggregates {
+		expression, err := c.composeAggregateExpression(correlationId, aggregate, &values)
+		if err != nil {
+			return nil, err
+		}
+		expressions[aggregate.name()] = expression
+		columns = append(columns, expression)
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + c.QuoteIdentifier(c.TableName)
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " WHERE " + flt
+	}
+	// Groups are referenced by positions, so JSON paths are not repeated
+	positions := make([]string, len(groupBy))
+	for index := range groupBy {
+		positions[index] = strconv.Itoa(index + 1)
+	}
+	if len(groupBy) > 0 {
+		query += " GROUP BY " + strings.Join(positions, ", ")
+	}
+
+	conditions := make([]string, 0, len(having))
+	for _, condition := range having {
+		expression, ok := expressions[condition.Alias]
+		if !ok {
+			return nil, cerr.NewBadRequestError(correlationId, "INVALID_AGGREGATE",
+				"Aggregate "+condition.Alias+" in condition is not defined")
+		}
+		if !aggregateOperators[condition.Operator] {
+			return nil, cerr.NewBadRequestError(correlationId, "INVALID_AGGREGATE",
+				"Operator "+condition.Operator+" is not supported")
+		}
+		values = append(values, condition.Value)
+		conditions = append(conditions, expression+" "+condition.Operator+" ?"+strconv.Itoa(len(values)))
+	}
+	if len(conditions) > 0 {
+		query += " HAVING " + strings.Join(conditions, " AND ")
+	}
+	if len(groupBy) > 0 {
+		query += " ORDER BY " + strings.Join(positions, ", ")
+	}
+
+	qResult, qErr := c.Client.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	rows = make([]*SqliteAggregateRow, 0)
+	for qResult.Next() {
+		buf := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for index := range buf {
+			pointers[index] = &buf[index]
+		}
+		if err = qResult.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for index, value := range buf {
+			if value, ok := value.([]byte); ok {
+				buf[index] = string(value)
+			}
+		}
+
+		row := &SqliteAggregateRow{
+			Groups: cdata.NewEmptyAnyValueMap(),
+			Values: cdata.NewEmptyAnyValueMap(),
+		}
+		for index, field := range groupBy {
+			row.Groups.Put(field, buf[index])
+		}
+		for index, aggregate := range aggregates {
+			row.Values.Put(aggregate.name(), buf[len(groupBy)+index])
+		}
+		rows = append(rows, row)
+	}
+
+	c.Logger.Trace(correlationId, "Computed %d groups of aggregates in %s", len(rows), c.TableName)
+	return rows, qResult.Err()
+}
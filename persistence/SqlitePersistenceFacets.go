@@ -0,0 +1,142 @@
+package persistence
+
+import (
+	"strconv"
+	"strings"
+
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+/*
+Value of a field with the number of data items that have it.
+*/
+type SqliteFacetValue struct {
+	// The value of the field. JSON objects and arrays are returned as JSON text.
+	Value interface{}
+	// The number of data items with the value.
+	Count int64
+}
+
+// Gets distinct values of a field in data items retrieved by a given filter with numbers of items for each value.
+// The field is a column name or a JSON path like $.status inside the data column of JSON persistences.
+// Paths in documents of codecs other than JSON shall be declared by EnsureJsonField.
+// Items without the field are not counted. Values are sorted in ascending order.
+// This method shall be called by a func (c * SqlitePersistence) getDistinctValues method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - field             a column name or a JSON path.
+// - filter            (optional) a filter JSON object.
+// Returns          distinct values with counts or error.
+func (c *SqlitePersistence) GetDistinctValues(correlationId string, field string,
+	filter interface{}) (items []*SqliteFacetValue, err error) {
+	if field == "" {
+		return nil, cerr.NewBadRequestError(correlationId, "INVALID_FACET", "Field is not defined")
+	}
+
+	values := make([]interface{}, 0)
+	expression, err := c.composeFieldExpression(correlationId, field, &values)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT " + expression + ", COUNT(*) FROM " + c.QuoteIdentifier(c.TableName) +
+		" WHERE " + expression + " IS NOT NULL"
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " AND (" + flt + ")"
+	}
+	query += " GROUP BY 1 ORDER BY 1"
+
+	qResult, qErr := c.Client.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	items = make([]*SqliteFacetValue, 0)
+	for qResult.Next() {
+		item := &SqliteFacetValue{}
+		if err = qResult.Scan(&item.Value, &item.Count); err != nil {
+			return nil, err
+		}
+		if value, ok := item.Value.([]byte); ok {
+			item.Value = string(value)
+		}
+		items = append(items, item)
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d distinct values of %s from %s", len(items), field, c.TableName)
+	return items, qResult.Err()
+}
+
+// Gets facets of data items retrieved by a given filter: the most frequent values of each field with numbers of items.
+// All facets are computed by a single query. Fields are column names or JSON paths like $.key,
+// paths in documents of codecs other than JSON shall be declared by EnsureJsonField.
+// Items without a field are not counted in its facet. Values are sorted by counts in descending order.
+// This method shall be called by a func (c * SqlitePersistence) getFacets method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object.
+// - fields            fields to compute facets for.
+// - limit             (optional) a maximum number of values in each facet, 0 for all values.
+// Returns          facet values by the fields as they were requested or error.
+func (c *SqlitePersistence) GetFacets(correlationId string, filter interface{}, fields []string,
+	limit int) (facets map[string][]*SqliteFacetValue, err error) {
+	if len(fields) == 0 {
+		return nil, cerr.NewBadRequestError(correlationId, "INVALID_FACET", "Fields are not defined")
+	}
+
+	values := make([]interface{}, 0)
+	query := "WITH \"items\" AS (SELECT * FROM " + c.QuoteIdentifier(c.TableName)
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		query += " WHERE " + flt
+	}
+	query += ")"
+
+	counts := make([]string, len(fields))
+	for index, field := range fields {
+		if field == "" {
+			return nil, cerr.NewBadRequestError(correlationId, "INVALID_FACET", "Field is not defined")
+		}
+		expression, err := c.composeFieldExpression(correlationId, field, &values)
+		if err != nil {
+			return nil, err
+		}
+		counts[index] = "SELECT " + strconv.Itoa(index) + " AS \"facet\", " + expression + " AS \"value\"," +
+			" COUNT(*) AS \"count\" FROM \"items\" WHERE " + expression + " IS NOT NULL GROUP BY 2"
+	}
+	// Values are ranked inside each facet, so the limit applies to every field separately
+	query += " SELECT \"facet\", \"value\", \"count\" FROM (SELECT \"facet\", \"value\", \"count\"," +
+		" ROW_NUMBER() OVER (PARTITION BY \"facet\" ORDER BY \"count\" DESC, \"value\") AS \"rank\"" +
+		" FROM (" + strings.Join(counts, " UNION ALL ") + "))"
+	if limit > 0 {
+		values = append(values, limit)
+		query += " WHERE \"rank\"<=?" + strconv.Itoa(len(values))
+	}
+	query += " ORDER BY \"facet\", \"rank\""
+
+	qResult, qErr := c.Client.Query(query, values...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	facets = make(map[string][]*SqliteFacetValue, len(fields))
+	for _, field := range fields {
+		facets[field] = make([]*SqliteFacetValue, 0)
+	}
+	for qResult.Next() {
+		var facet interface{}
+		item := &SqliteFacetValue{}
+		if err = qResult.Scan(&facet, &item.Value, &item.Count); err != nil {
+			return nil, err
+		}
+		if value, ok := item.Value.([]byte); ok {
+			item.Value = string(value)
+		}
+		field := fields[cconv.IntegerConverter.ToInteger(facet)]
+		facets[field] = append(facets[field], item)
+	}
+
+	c.Logger.Trace(correlationId, "Computed %d facets of %s", len(fields), c.TableName)
+	return facets, qResult.Err()
+}
@@ -0,0 +1,322 @@
+package persistence
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Markers of matched terms in snippets and highlights.
+const (
+	FullTextHighlightStart = "<b>"
+	FullTextHighlightEnd   = "</b>"
+	FullTextEllipsis       = "..."
+	// The maximum number of tokens in snippets.
+	FullTextSnippetTokens = 16
+)
+
+/*
+Data item found by a full-text search.
+*/
+type SqliteTextSearchResult struct {
+	// The found data item.
+	Item interface{} `json:"item"`
+	// The bm25 score of the item. Lower scores are better matches.
+	Score float64 `json:"score"`
+	// The fragment of the best matching field with matched terms highlighted.
+	Snippet string `json:"snippet"`
+	// The indexed fields with matched terms highlighted.
+	Highlights map[string]string `json:"highlights"`
+}
+
+/*
+Page of data items found by a full-text search in the order of their ranks.
+*/
+type SqliteTextSearchPage struct {
+	// The total number of found items when it was requested by paging parameters.
+	Total *int64 `json:"total"`
+	// The found items.
+	Data []*SqliteTextSearchResult `json:"data"`
+}
+
+// Names of columns with ranks, snippets and highlights in results of full-text searches.
+const (
+	fullTextScoreColumn   = "fts_score"
+	fullTextSnippetColumn = "fts_snippet"
+)
+
+func fullTextHighlightColumn(index int) string {
+	return "fts_highlight_" + strconv.Itoa(index)
+}
+
+// Definition of the full-text index of a table.
+type sqliteFullTextIndex struct {
+	fields    []string
+	columns   []string
+	tokenizer string
+}
+
+// Adds a full-text index over the given fields to create it on opening.
+// The index is an FTS5 table that takes its content from the persistence table
+// and is kept in sync by triggers. Existing rows are indexed when the index is created.
+// Fields are column names or JSON paths like $.content inside the data column of JSON persistences.
+// Paths in documents of codecs other than JSON shall be declared by EnsureJsonField.
+// SQLite shall be built with FTS5 support, for the mattn driver use sqlite_fts5 build tag,
+// otherwise opening fails with "no such module: fts5".
+// - fields      indexed fields.
+// - tokenizer   (optional) FTS5 tokenizer, for instance "porter unicode61" or "trigram" for substring search (default: unicode61).
+func (c *SqlitePersistence) EnsureFullTextIndex(fields []string, tokenizer string) {
+	index := &sqliteFullTextIndex{
+		fields:    fields,
+		columns:   make([]string, len(fields)),
+		tokenizer: tokenizer,
+	}
+	for i, field := range fields {
+		index.columns[i] = fullTextColumnName(field)
+	}
+	c.fullTextIndex = index
+}
+
+// Gets the name of the FTS5 table that indexes the persistence table.
+func (c *SqlitePersistence) FullTextTableName() string {
+	return c.TableName + "_fts"
+}
+
+// Converts a field to the name of its column in the FTS5 table.
+func fullTextColumnName(field string) string {
+	if !strings.HasPrefix(field, "$") {
+		return field
+	}
+	return jsonPathColumnName(field)
+}
+
+// Composes an SQL expression that reads a field of a row. The row is a table name or new/old in triggers.
+// Documents of codecs that don't store JSON text can't be read by SQL,
+// so their paths are read from columns declared by EnsureJsonField.
+func (c *SqlitePersistence) composeFullTextField(correlationId string, row string, field string) (string, error) {
+	if !strings.HasPrefix(field, "$") {
+		return row + "." + c.QuoteIdentifier(field), nil
+	}
+	if c.storedJsonFields {
+		for _, jsonField := range c.jsonFields {
+			if jsonField.path == field {
+				return row + "." + c.QuoteIdentifier(jsonField.column), nil
+			}
+		}
+		return "", cerr.NewConfigError(correlationId, "INVALID_FIELD",
+			"JSON path "+field+" in full-text index is not declared by EnsureJsonField and can't be read from encoded documents").
+			WithDetails("field", field)
+	}
+	return "JSON_EXTRACT(" + row + ".\"data\", '" + strings.ReplaceAll(field, "'", "''") + "')", nil
+}
+
+// Creates the FTS5 table, its content view and synchronization triggers
+// or recreates them when indexed fields or the tokenizer have changed.
+func (c *SqlitePersistence) createFullTextIndex(correlationId string) error {
+	index := c.fullTextIndex
+	ftsName := c.FullTextTableName()
+	table := c.QuoteIdentifier(c.TableName)
+	fts := c.QuoteIdentifier(ftsName)
+	columns := make([]string, len(index.columns))
+	viewColumns := make([]string, len(index.columns))
+	newValues := make([]string, len(index.columns))
+	oldValues := make([]string, len(index.columns))
+	var err error
+	for i, column := range index.columns {
+		columns[i] = c.QuoteIdentifier(column)
+		if viewColumns[i], err = c.composeFullTextField(correlationId, table, index.fields[i]); err != nil {
+			return err
+		}
+		viewColumns[i] += " AS " + columns[i]
+		newValues[i], _ = c.composeFullTextField(correlationId, "new", index.fields[i])
+		oldValues[i], _ = c.composeFullTextField(correlationId, "old", index.fields[i])
+	}
+	columnList := strings.Join(columns, ", ")
+
+	// External content is read through a view, so JSON fields can be indexed as well
+	options := ", content='" + ftsName + "_content', content_rowid='doc_rowid'"
+	if index.tokenizer != "" {
+		options += ", tokenize='" + strings.ReplaceAll(index.tokenizer, "'", "''") + "'"
+	}
+	insert := "INSERT INTO " + fts + " (rowid, " + columnList + ") VALUES (new.rowid, " + strings.Join(newValues, ", ") + ");"
+	delete := "INSERT INTO " + fts + " (" + fts + ", rowid, " + columnList + ") VALUES ('delete', old.rowid, " + strings.Join(oldValues, ", ") + ");"
+	names := []string{ftsName + "_content", ftsName, ftsName + "_insert", ftsName + "_delete", ftsName + "_update"}
+	statements := []string{
+		"CREATE VIEW " + c.QuoteIdentifier(names[0]) + " AS SELECT " + table + ".rowid AS \"doc_rowid\", " +
+			strings.Join(viewColumns, ", ") + " FROM " + table,
+		"CREATE VIRTUAL TABLE " + fts + " USING fts5(" + columnList + options + ")",
+		"CREATE TRIGGER " + c.QuoteIdentifier(names[2]) + " AFTER INSERT ON " + table +
+			" BEGIN " + insert + " END",
+		"CREATE TRIGGER " + c.QuoteIdentifier(names[3]) + " AFTER DELETE ON " + table +
+			" BEGIN " + delete + " END",
+		"CREATE TRIGGER " + c.QuoteIdentifier(names[4]) + " AFTER UPDATE ON " + table +
+			" BEGIN " + delete + " " + insert + " END",
+	}
+
+	// The index is kept when all its objects are defined by the same statements
+	existing := make(map[string]string)
+	qResult, err := c.Client.Query("SELECT name, sql FROM sqlite_master WHERE name IN (?1, ?2, ?3, ?4, ?5)",
+		names[0], names[1], names[2], names[3], names[4])
+	if err != nil {
+		return err
+	}
+	for qResult.Next() {
+		var name, sql string
+		if err = qResult.Scan(&name, &sql); err != nil {
+			qResult.Close()
+			return err
+		}
+		existing[name] = sql
+	}
+	qResult.Close()
+	if err = qResult.Err(); err != nil {
+		return err
+	}
+	changed := false
+	for i, name := range names {
+		changed = changed || existing[name] != statements[i]
+	}
+	if !changed {
+		return nil
+	}
+
+	queries := []string{
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(names[2]),
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(names[3]),
+		"DROP TRIGGER IF EXISTS " + c.QuoteIdentifier(names[4]),
+		"DROP TABLE IF EXISTS " + fts,
+		"DROP VIEW IF EXISTS " + c.QuoteIdentifier(names[0]),
+	}
+	queries = append(queries, statements...)
+	queries = append(queries, "INSERT INTO "+fts+" ("+fts+") VALUES ('rebuild')")
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	c.Logger.Debug(correlationId, "Created full-text index %s", ftsName)
+	return nil
+}
+
+// Searches data items by text in the full-text index and ranks them with bm25.
+// This method shall be called by a func (c * SqlitePersistence) searchByText method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - query             an FTS5 query, for instance "sqlite AND (search OR index)".
+// - filter            (optional) a filter JSON object.
+// - paging            (optional) paging parameters.
+// Returns          a page of found items with scores, snippets and highlights or error.
+func (c *SqlitePersistence) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *SqliteTextSearchPage, err error) {
+	index := c.fullTextIndex
+	if index == nil {
+		return nil, cerr.NewInvalidStateError(correlationId, "NO_FULL_TEXT_INDEX",
+			"Full-text index is not defined for "+c.TableName)
+	}
+
+	if paging == nil {
+		paging = cdata.NewEmptyPagingParams()
+	}
+	skip := paging.GetSkip(-1)
+	take := paging.GetTake((int64)(c.MaxPageSize))
+
+	fts := c.QuoteIdentifier(c.FullTextTableName())
+	table := c.QuoteIdentifier(c.TableName)
+	condition := fts + " MATCH ?1"
+	if flt := c.composeActiveFilter(filter); flt != "" {
+		condition += " AND " + fts + ".rowid IN (SELECT rowid FROM " + table + " WHERE " + flt + ")"
+	}
+
+	// Items are read by the ranking statement, so found items can't disappear before they are read
+	columns := "bm25(" + fts + ") AS \"" + fullTextScoreColumn + "\", snippet(" + fts + ", -1, ?2, ?3, ?4, " +
+		strconv.Itoa(FullTextSnippetTokens) + ") AS \"" + fullTextSnippetColumn + "\""
+	for i := range index.columns {
+		columns += ", highlight(" + fts + ", " + strconv.Itoa(i) + ", ?2, ?3) AS \"" + fullTextHighlightColumn(i) + "\""
+	}
+	statement := "SELECT " + columns + ", " + table + ".* FROM " + fts +
+		" JOIN " + table + " ON " + table + ".rowid=" + fts + ".rowid WHERE " + condition + " ORDER BY " + fts + ".rank LIMIT " + strconv.FormatInt(take, 10)
+	if skip >= 0 {
+		statement += " OFFSET " + strconv.FormatInt(skip, 10)
+	}
+
+	qResult, qErr := c.Client.Query(statement, query, FullTextHighlightStart, FullTextHighlightEnd, FullTextEllipsis)
+	if qErr != nil {
+		return nil, c.convertFullTextError(correlationId, qErr)
+	}
+	defer qResult.Close()
+	names, err := qResult.Columns()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*SqliteTextSearchResult, 0)
+	for qResult.Next() {
+		result := &SqliteTextSearchResult{Highlights: make(map[string]string, len(index.fields))}
+		highlights := make([]interface{}, len(index.fields))
+		values := []interface{}{&result.Score, &result.Snippet}
+		for i := range highlights {
+			values = append(values, &highlights[i])
+		}
+		for len(values) < len(names) {
+			values = append(values, new(interface{}))
+		}
+		if err = qResult.Scan(values...); err != nil {
+			return nil, err
+		}
+		for i, field := range index.fields {
+			result.Highlights[field] = cconv.StringConverter.ToString(highlights[i])
+		}
+
+		// The row is scanned again to convert columns of the table
+		result.Item = c.Overrides.ConvertToPublic(qResult)
+		if item, ok := result.Item.(map[string]interface{}); ok {
+			delete(item, fullTextScoreColumn)
+			delete(item, fullTextSnippetColumn)
+			for i := range index.columns {
+				delete(item, fullTextHighlightColumn(i))
+			}
+		}
+		results = append(results, result)
+	}
+	if err = qResult.Err(); err != nil {
+		return nil, c.convertFullTextError(correlationId, err)
+	}
+
+	c.Logger.Trace(correlationId, "Found %d items in %s", len(results), c.TableName)
+
+	page = &SqliteTextSearchPage{Data: results}
+	if paging.Total {
+		var total int64
+		err = c.Client.QueryRow("SELECT COUNT(*) FROM "+fts+" WHERE "+condition, query).Scan(&total)
+		if err != nil {
+			return nil, c.convertFullTextError(correlationId, err)
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// Reports syntax errors of full-text queries as bad requests.
+// Statements are generated, so generic SQL errors of the search come from the query.
+func (c *SqlitePersistence) convertFullTextError(correlationId string, err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrError {
+		return cerr.NewBadRequestError(correlationId, "INVALID_TEXT_QUERY", "Full-text query is invalid").
+			WithCause(err)
+	}
+	return err
+}
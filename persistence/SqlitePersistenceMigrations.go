@@ -0,0 +1,295 @@
+package persistence
+
+import (
+	"database/sql"
+	"io/fs"
+	"sort"
+	"strconv"
+	"time"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+)
+
+// Adds a versioned migration step to apply it on opening.
+// A step with the same version replaces the previously added one.
+// - version     a migration version (must be positive).
+// - description a migration description.
+// - up          SQL statements to upgrade the schema.
+// - down        (optional) SQL statements to revert the upgrade.
+func (c *SqlitePersistence) EnsureMigration(version int, description string, up string, down string) {
+	c.AddMigrations(NewSqliteMigration(version, description, up, down))
+}
+
+// Adds versioned migration steps to apply them on opening.
+// Steps with the same version replace previously added ones.
+// - migrations  migration steps to be added.
+func (c *SqlitePersistence) AddMigrations(migrations ...*SqliteMigration) {
+	for _, migration := range migrations {
+		if migration == nil {
+			continue
+		}
+		if migration.Version <= 0 {
+			panic("Migration version must be positive")
+		}
+
+		replaced := false
+		for index, existing := range c.migrations {
+			if existing.Version == migration.Version {
+				c.migrations[index] = migration
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.migrations = append(c.migrations, migration)
+		}
+	}
+	sort.Slice(c.migrations, func(i, j int) bool { return c.migrations[i].Version < c.migrations[j].Version })
+}
+
+// Loads versioned migration steps from a file system like embed.FS.
+// See ReadSqliteMigrations for file naming rules.
+// - fsys     a file system to read migrations from.
+// - dir      a directory with migration files ("." for the root).
+// Returns error or nil for success.
+func (c *SqlitePersistence) LoadMigrations(fsys fs.FS, dir string) error {
+	migrations, err := ReadSqliteMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+	c.AddMigrations(migrations...)
+	return nil
+}
+
+// Clears all added migration steps
+func (c *SqlitePersistence) ClearMigrations() {
+	c.migrations = []*SqliteMigration{}
+}
+
+// Gets the latest migration version known to the persistence.
+// Returns the latest version or 0 if no migrations were added.
+func (c *SqlitePersistence) GetLatestMigrationVersion() int {
+	if len(c.migrations) == 0 {
+		return 0
+	}
+	return c.migrations[len(c.migrations)-1].Version
+}
+
+// Gets the current schema version of the persistence table stored in the database.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns the latest applied migration version or error.
+func (c *SqlitePersistence) GetSchemaVersion(correlationId string) (version int, err error) {
+	exists, err := c.migrationsTableExists()
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	query := "SELECT COALESCE(MAX(\"version\"), 0) FROM " + c.QuoteIdentifier(c.MigrationsTableName) +
+		" WHERE \"table_name\"=?"
+	err = c.Client.QueryRow(query, c.TableName).Scan(&version)
+	return version, err
+}
+
+// Applies pending migration steps inside a transaction.
+// It is called automatically on opening. The method refuses to apply migrations
+// if the database schema is newer than the code knows about
+// or if already applied migrations were modified.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns error or nil for success.
+func (c *SqlitePersistence) Migrate(correlationId string) error {
+	exists, err := c.migrationsTableExists()
+	if err != nil {
+		return err
+	}
+	// Nothing to check and nothing to apply
+	if !exists && len(c.migrations) == 0 {
+		return nil
+	}
+
+	if !exists {
+		err = c.createMigrationsTable()
+		if err != nil {
+			return err
+		}
+	}
+
+	applied, err := c.readAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateAppliedMigrations(correlationId, applied)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]*SqliteMigration, 0)
+	for _, migration := range c.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range pending {
+		c.Logger.Debug(correlationId, "Applying migration %d (%s) to %s", migration.Version, migration.Description, c.TableName)
+
+		_, err = tx.Exec(migration.Up)
+		if err == nil {
+			query := "INSERT INTO " + c.QuoteIdentifier(c.MigrationsTableName) +
+				" (\"table_name\",\"version\",\"description\",\"checksum\",\"applied_at\") VALUES (?1,?2,?3,?4,?5)"
+			_, err = tx.Exec(query, c.TableName, migration.Version, migration.Description,
+				migration.Checksum(), time.Now().UTC().Format(time.RFC3339Nano))
+		}
+		if err != nil {
+			tx.Rollback()
+			return cerr.NewInternalError(correlationId, "MIGRATION_FAILED",
+				"Failed to apply migration "+strconv.Itoa(migration.Version)+" to "+c.TableName).
+				WithDetails("version", migration.Version).
+				WithCause(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err == nil {
+		c.Logger.Info(correlationId, "Migrated %s to version %d", c.TableName, pending[len(pending)-1].Version)
+	}
+	return err
+}
+
+// Reverts applied migration steps down to the given version inside a transaction.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - version           a version to revert to (0 reverts all migrations).
+// Returns error or nil for success.
+func (c *SqlitePersistence) MigrateDown(correlationId string, version int) error {
+	exists, err := c.migrationsTableExists()
+	if err != nil || !exists {
+		return err
+	}
+
+	applied, err := c.readAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateAppliedMigrations(correlationId, applied)
+	if err != nil {
+		return err
+	}
+
+	reverted := make([]*SqliteMigration, 0)
+	for index := len(c.migrations) - 1; index >= 0; index-- {
+		migration := c.migrations[index]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			if migration.Down == "" {
+				return cerr.NewInvalidStateError(correlationId, "MIGRATION_IRREVERSIBLE",
+					"Migration "+strconv.Itoa(migration.Version)+" of "+c.TableName+" cannot be reverted").
+					WithDetails("version", migration.Version)
+			}
+			reverted = append(reverted, migration)
+		}
+	}
+	if len(reverted) == 0 {
+		return nil
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range reverted {
+		c.Logger.Debug(correlationId, "Reverting migration %d (%s) from %s", migration.Version, migration.Description, c.TableName)
+
+		_, err = tx.Exec(migration.Down)
+		if err == nil {
+			query := "DELETE FROM " + c.QuoteIdentifier(c.MigrationsTableName) +
+				" WHERE \"table_name\"=?1 AND \"version\"=?2"
+			_, err = tx.Exec(query, c.TableName, migration.Version)
+		}
+		if err != nil {
+			tx.Rollback()
+			return cerr.NewInternalError(correlationId, "MIGRATION_FAILED",
+				"Failed to revert migration "+strconv.Itoa(migration.Version)+" from "+c.TableName).
+				WithDetails("version", migration.Version).
+				WithCause(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *SqlitePersistence) migrationsTableExists() (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM sqlite_schema WHERE type='table' AND name=?"
+	err := c.Client.QueryRow(query, c.MigrationsTableName).Scan(&count)
+	return count > 0, err
+}
+
+func (c *SqlitePersistence) createMigrationsTable() error {
+	query := "CREATE TABLE IF NOT EXISTS " + c.QuoteIdentifier(c.MigrationsTableName) +
+		" (\"table_name\" TEXT NOT NULL, \"version\" INTEGER NOT NULL, \"description\" TEXT," +
+		" \"checksum\" TEXT NOT NULL, \"applied_at\" TEXT NOT NULL, PRIMARY KEY (\"table_name\", \"version\"))"
+	_, err := c.Client.Exec(query)
+	return err
+}
+
+func (c *SqlitePersistence) readAppliedMigrations() (map[int]string, error) {
+	query := "SELECT \"version\", \"checksum\" FROM " + c.QuoteIdentifier(c.MigrationsTableName) +
+		" WHERE \"table_name\"=?"
+	rows, err := c.Client.Query(query, c.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string, 0)
+	for rows.Next() {
+		var version int
+		var checksum sql.NullString
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum.String
+	}
+	return applied, rows.Err()
+}
+
+func (c *SqlitePersistence) validateAppliedMigrations(correlationId string, applied map[int]string) error {
+	latest := c.GetLatestMigrationVersion()
+	for version, checksum := range applied {
+		if version > latest {
+			return cerr.NewInvalidStateError(correlationId, "SCHEMA_TOO_NEW",
+				"Schema of "+c.TableName+" has version "+strconv.Itoa(version)+
+					" that is newer than the latest known version "+strconv.Itoa(latest)).
+				WithDetails("version", version).
+				WithDetails("latest_version", latest)
+		}
+
+		var migration *SqliteMigration
+		for _, m := range c.migrations {
+			if m.Version == version {
+				migration = m
+				break
+			}
+		}
+		if migration == nil {
+			return cerr.NewInvalidStateError(correlationId, "MIGRATION_MISSING",
+				"Applied migration "+strconv.Itoa(version)+" of "+c.TableName+" is not known").
+				WithDetails("version", version)
+		}
+		if migration.Checksum() != checksum {
+			return cerr.NewInvalidStateError(correlationId, "MIGRATION_CHECKSUM_MISMATCH",
+				"Applied migration "+strconv.Itoa(version)+" of "+c.TableName+" was modified").
+				WithDetails("version", version)
+		}
+	}
+	return nil
+}
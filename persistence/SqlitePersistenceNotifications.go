@@ -0,0 +1,65 @@
+package persistence
+
+import (
+	"database/sql"
+
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+)
+
+// Callback function that receives committed changes of data items.
+// The item is nil for deleted items.
+type SqliteItemChangeListener func(event *conn.SqliteChangeEvent, item interface{})
+
+// Subscribes a listener to committed changes of the persistence table.
+// Changed items are read by their rowids after the commit, so they can be
+// newer than the change when the item was changed again. Tables created
+// WITHOUT ROWID do not report changes. The connection must be configured
+// with options.notifications set to true.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - operation         (optional) insert, update or delete, "" subscribes to all operations.
+// - listener          a function that receives changes and changed items.
+// Returns          a subscription or error.
+func (c *SqlitePersistence) Subscribe(correlationId string, operation string,
+	listener SqliteItemChangeListener) (*conn.SqliteChangeSubscription, error) {
+	if c.Connection == nil {
+		return nil, cerr.NewInvalidStateError(correlationId, "NO_CONNECTION", "SQLite connection is missing")
+	}
+
+	return c.Connection.Subscribe(correlationId, c.TableName, operation, func(event *conn.SqliteChangeEvent) {
+		var item interface{}
+		if event.Operation != conn.SqliteChangeDelete {
+			item = c.readOneByRowId(correlationId, event.RowId)
+		}
+		listener(event, item)
+	})
+}
+
+// Reads a data item by its rowid. Returns nil when the item doesn't exist or can't be read.
+func (c *SqlitePersistence) readOneByRowId(correlationId string, rowId int64) interface{} {
+	client := c.Client
+	if client == nil {
+		return nil
+	}
+
+	item, err := c.queryOneByRowId(client, rowId)
+	if err != nil {
+		c.Logger.Error(correlationId, err, "Failed to read changed item from %s", c.TableName)
+		return nil
+	}
+	return item
+}
+
+// Reads a data item by its rowid. Returns nil when the item doesn't exist.
+func (c *SqlitePersistence) queryOneByRowId(client *sql.DB, rowId int64) (item interface{}, err error) {
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE rowid=?1"
+	qResult, qErr := client.Query(query, rowId)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+	if !qResult.Next() {
+		return nil, qResult.Err()
+	}
+	return c.Overrides.ConvertToPublic(qResult), nil
+}
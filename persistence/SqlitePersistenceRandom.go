@@ -0,0 +1,187 @@
+package persistence
+
+import (
+	"database/sql"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Source of random picks shared by copies of the persistence.
+type sqliteRandom struct {
+	lock   sync.Mutex
+	source *rand.Rand
+}
+
+func newSqliteRandom(seed int64) *sqliteRandom {
+	return &sqliteRandom{source: rand.New(rand.NewSource(seed))}
+}
+
+// Gets a random number in [0, n).
+func (c *sqliteRandom) int63n(n int64) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.source.Int63n(n)
+}
+
+// Sets the seed of random picks by GetOneRandom and GetRandomSample.
+// The same seed over the same data gives the same picks, so tests can be reproducible.
+// - seed     the seed of random numbers.
+func (c *SqlitePersistence) SetRandomSeed(seed int64) {
+	c.random.lock.Lock()
+	defer c.random.lock.Unlock()
+	c.random.source.Seed(seed)
+}
+
+// Gets a random sample of distinct data items that match to a given filter.
+// Items are picked by random rowids between the smallest and the largest rowid of the table,
+// each pick takes the first matching item from the rowid, so the table is neither sorted
+// nor skipped through with OFFSET. Items after gaps in rowids are picked more often.
+// When picks repeat too often, the rest of the sample is drawn from rowids of all matching items.
+// This method shall be called by a func (c * SqlitePersistence) getRandomSample method from child class that
+// receives FilterParams and converts them into a filter function.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// - size              a maximum number of items in the sample.
+// Returns          sampled items in the order of their rowids or error.
+// All matching items are returned when there are no more of them than the size.
+func (c *SqlitePersistence) GetRandomSample(correlationId string, filter interface{}, size int) (items []interface{}, err error) {
+	if size <= 0 {
+		return make([]interface{}, 0), nil
+	}
+	count, err := c.GetCountByFilter(correlationId, filter)
+	if err != nil {
+		return nil, err
+	}
+	if count <= int64(size) {
+		return c.GetListByFilter(correlationId, filter, "rowid", nil)
+	}
+
+	minId, maxId, err := c.rowIdRange()
+	if err != nil {
+		return nil, err
+	}
+	flt := c.composeActiveFilter(filter)
+	picked := make(map[int64]bool, size)
+	rowIds := make([]interface{}, 0, size)
+	for attempt := 0; len(rowIds) < size && attempt < size*4; attempt++ {
+		rowId, found, err := c.probeRowId(flt, minId, maxId)
+		if err != nil {
+			return nil, err
+		}
+		if found && !picked[rowId] {
+			picked[rowId] = true
+			rowIds = append(rowIds, rowId)
+		}
+	}
+	if len(rowIds) < size {
+		rest, err := c.drawRowIds(flt, picked, size-len(rowIds))
+		if err != nil {
+			return nil, err
+		}
+		rowIds = append(rowIds, rest...)
+	}
+
+	params := make([]string, len(rowIds))
+	for index := range rowIds {
+		params[index] = "?" + strconv.Itoa(index+1)
+	}
+	query := "SELECT * FROM " + c.QuoteIdentifier(c.TableName) + " WHERE rowid IN (" +
+		strings.Join(params, ",") + ") ORDER BY rowid"
+	qResult, qErr := c.Client.Query(query, rowIds...)
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer qResult.Close()
+
+	items = make([]interface{}, 0, len(rowIds))
+	for qResult.Next() {
+		items = append(items, c.Overrides.ConvertToPublic(qResult))
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved random sample of %d items from %s", len(items), c.TableName)
+	return items, qResult.Err()
+}
+
+// Picks a rowid of a random item that matches to a given filter.
+func (c *SqlitePersistence) pickRandomRowId(filter interface{}) (rowId int64, found bool, err error) {
+	minId, maxId, err := c.rowIdRange()
+	if err != nil {
+		return 0, false, err
+	}
+	return c.probeRowId(c.composeActiveFilter(filter), minId, maxId)
+}
+
+// Gets the smallest and the largest rowid of the table. The smallest is greater than the largest for empty tables.
+func (c *SqlitePersistence) rowIdRange() (minId int64, maxId int64, err error) {
+	var minValue, maxValue sql.NullInt64
+	query := "SELECT MIN(rowid), MAX(rowid) FROM " + c.QuoteIdentifier(c.TableName)
+	if err = c.Client.QueryRow(query).Scan(&minValue, &maxValue); err != nil {
+		return 0, 0, err
+	}
+	if !minValue.Valid || !maxValue.Valid {
+		return 1, 0, nil
+	}
+	return minValue.Int64, maxValue.Int64, nil
+}
+
+// Picks a random rowid in a range and finds the first item from it that matches to a filter.
+// The search wraps around to the beginning of the table.
+func (c *SqlitePersistence) probeRowId(flt string, minId int64, maxId int64) (rowId int64, found bool, err error) {
+	if minId > maxId {
+		return 0, false, nil
+	}
+	start := minId + c.random.int63n(maxId-minId+1)
+
+	table := c.QuoteIdentifier(c.TableName)
+	condition := ""
+	if flt != "" {
+		condition = " AND (" + flt + ")"
+	}
+	query := "SELECT * FROM (SELECT rowid FROM " + table + " WHERE rowid>=?1" + condition + " ORDER BY rowid LIMIT 1)" +
+		" UNION ALL SELECT * FROM (SELECT rowid FROM " + table + " WHERE rowid<?1" + condition + " ORDER BY rowid LIMIT 1)" +
+		" LIMIT 1"
+	err = c.Client.QueryRow(query, start).Scan(&rowId)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return rowId, err == nil, err
+}
+
+// Draws random rowids of matching items that were not picked yet.
+func (c *SqlitePersistence) drawRowIds(flt string, picked map[int64]bool, size int) ([]interface{}, error) {
+	query := "SELECT rowid FROM " + c.QuoteIdentifier(c.TableName)
+	if flt != "" {
+		query += " WHERE " + flt
+	}
+	query += " ORDER BY rowid"
+	qResult, err := c.Client.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer qResult.Close()
+
+	candidates := make([]int64, 0)
+	for qResult.Next() {
+		var rowId int64
+		if err = qResult.Scan(&rowId); err != nil {
+			return nil, err
+		}
+		if !picked[rowId] {
+			candidates = append(candidates, rowId)
+		}
+	}
+	if err = qResult.Err(); err != nil {
+		return nil, err
+	}
+
+	// Partial Fisher-Yates shuffle keeps draws reproducible with a seed
+	rowIds := make([]interface{}, 0, size)
+	for index := 0; index < size && index < len(candidates); index++ {
+		swap := index + int(c.random.int63n(int64(len(candidates)-index)))
+		candidates[index], candidates[swap] = candidates[swap], candidates[index]
+		rowIds = append(rowIds, candidates[index])
+	}
+	return rowIds, nil
+}
@@ -0,0 +1,316 @@
+package persistence
+
+import (
+	"strconv"
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// The format of timestamps stored in SQLite.
+// It has a fixed width, so stored timestamps can be compared as strings.
+const SqliteTimestampFormat = "2006-01-02T15:04:05.000Z"
+
+// Formats a time as a UTC timestamp in SqliteTimestampFormat.
+func FormatSqliteTimestamp(value time.Time) string {
+	return value.UTC().Format(SqliteTimestampFormat)
+}
+
+// Gets the current time from the clock or the system time when the clock is not set.
+func (c *SqlitePersistence) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// Composes a condition that excludes soft-deleted rows: "deleted_at" IS NULL
+func (c *SqlitePersistence) composeNotDeleted() string {
+	return c.QuoteIdentifier(c.DeletedColumn) + " IS NULL"
+}
+
+// Filter passed by SqliteDeletedView to reads that include soft-deleted items.
+type sqliteDeletedFilter struct {
+	filter interface{}
+}
+
+// Adds a condition that excludes soft-deleted rows to a filter used in reads.
+func (c *SqlitePersistence) composeActiveFilter(filter interface{}) string {
+	if deleted, ok := filter.(*sqliteDeletedFilter); ok {
+		return c.composeReadFilter(deleted.filter, true)
+	}
+	flt, _ := filter.(string)
+	if !c.SoftDelete {
+		return flt
+	}
+	if flt == "" {
+		return c.composeNotDeleted()
+	}
+	return "(" + flt + ") AND " + c.composeNotDeleted()
+}
+
+// Composes a filter used in reads that excludes soft-deleted rows unless includeDeleted is set.
+func (c *SqlitePersistence) composeReadFilter(filter interface{}, includeDeleted bool) string {
+	if includeDeleted {
+		flt, _ := filter.(string)
+		return flt
+	}
+	return c.composeActiveFilter(filter)
+}
+
+// Composes an additional condition that protects soft-deleted rows from writes: AND "deleted_at" IS NULL
+func (c *SqlitePersistence) composeActiveCondition() string {
+	if !c.SoftDelete {
+		return ""
+	}
+	return " AND " + c.composeNotDeleted()
+}
+
+// Permanently removes items that were soft-deleted before a given time.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - olderThan         the time before which items were deleted.
+// Returns          a number of removed items or error.
+func (c *SqlitePersistence) Purge(correlationId string, olderThan time.Time) (count int64, err error) {
+	if !c.SoftDelete {
+		return 0, nil
+	}
+
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) +
+		" WHERE " + c.QuoteIdentifier(c.DeletedColumn) + " IS NOT NULL" +
+		" AND " + c.QuoteIdentifier(c.DeletedColumn) + "<?1"
+	qResult, qErr := c.Client.Exec(query, FormatSqliteTimestamp(olderThan))
+	if qErr != nil {
+		return 0, qErr
+	}
+
+	count, err = qResult.RowsAffected()
+	if count != 0 {
+		c.Logger.Trace(correlationId, "Purged %d items from %s", count, c.TableName)
+	}
+	return count, err
+}
+
+// Permanently removes items that were soft-deleted before a given time.
+// Removed items are recorded in the audit table and the outbox.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - olderThan         the time before which items were deleted.
+// Returns          a number of removed items or error.
+func (c *IdentifiableSqlitePersistence) Purge(correlationId string, olderThan time.Time) (count int64, err error) {
+	if !c.SoftDelete || !c.recordsChanges() {
+		return c.SqlitePersistence.Purge(correlationId, olderThan)
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	condition := c.QuoteIdentifier(c.DeletedColumn) + " IS NOT NULL" +
+		" AND " + c.QuoteIdentifier(c.DeletedColumn) + "<?1"
+	values := []interface{}{FormatSqliteTimestamp(olderThan)}
+	query := "DELETE FROM " + c.QuoteIdentifier(c.TableName) + " WHERE " + condition
+	count, err = c.deleteRows(tx, correlationId, AuditOperationPurge, condition, values, query, values)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if count != 0 {
+		c.Logger.Trace(correlationId, "Purged %d items from %s", count, c.TableName)
+	}
+	return count, nil
+}
+
+// Restores soft-deleted data items by their unique ids.
+// Restored items are stamped, get a new version and are recorded in the audit table and the outbox.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be restored.
+// Returns          a number of restored items or error.
+func (c *IdentifiableSqlitePersistence) Restore(correlationId string, ids []interface{}) (count int64, err error) {
+	if !c.SoftDelete || len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := c.Client.Begin()
+	if err != nil {
+		return 0, err
+	}
+	stamps := c.composeStamps(correlationId, false)
+
+	for _, chunk := range c.splitIds(ids) {
+		restored, err := c.restoreRows(tx, correlationId, chunk, stamps)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		count += restored
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if count != 0 {
+		c.Logger.Trace(correlationId, "Restored %d items in %s", count, c.TableName)
+	}
+	return count, nil
+}
+
+// Restores soft-deleted rows with given ids and records restored items in the audit table and the outbox.
+// Returns a number of restored items or error.
+func (c *IdentifiableSqlitePersistence) restoreRows(db sqlExecutor, correlationId string, ids []interface{},
+	stamps map[string]interface{}) (count int64, err error) {
+	condition := c.QuoteIdentifier(c.IdColumn) + " IN(" + c.GenerateParameters(ids) + ")" +
+		" AND " + c.QuoteIdentifier(c.DeletedColumn) + " IS NOT NULL"
+	deleted, err := c.readSnapshots(db, condition, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	values := append(make([]interface{}, 0, len(ids)+len(stamps)), ids...)
+	query := "UPDATE " + c.QuoteIdentifier(c.TableName) + " SET " + c.QuoteIdentifier(c.DeletedColumn) + "=NULL" +
+		c.composeStampsAssignment(stamps, &values) + c.composeVersionIncrement() + " WHERE " + condition
+	qResult, qErr := db.Exec(query, values...)
+	if qErr != nil {
+		return 0, qErr
+	}
+	count, err = qResult.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range deleted {
+		id := c.getObjectId(item)
+		restored, err := c.readOneById(db, id, false)
+		if err != nil {
+			return 0, err
+		}
+		if err = c.recordChange(db, correlationId, AuditOperationRestore, id, item, restored); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// Composes a statement that soft-deletes rows matching a condition with a timestamp bound to a given parameter.
+func (c *SqlitePersistence) composeSoftDelete(condition string, timestampIndex int) string {
+	return "UPDATE " + c.QuoteIdentifier(c.TableName) +
+		" SET " + c.QuoteIdentifier(c.DeletedColumn) + "=?" + strconv.Itoa(timestampIndex) +
+		" WHERE " + condition + " AND " + c.composeNotDeleted()
+}
+
+/*
+View of a persistence that includes soft-deleted items in reads.
+
+The view keeps a pointer to the persistence, so it always uses its current
+connection and configuration. Child classes pass filters composed by their helpers
+to the view methods, for instance c.WithDeleted().GetPageByFilter(correlationId, c.composeFilter(filter), paging, nil, nil).
+*/
+type SqliteDeletedView struct {
+	persistence *SqlitePersistence
+}
+
+// Gets a view of the persistence that includes soft-deleted items in reads.
+// Returns a persistence view.
+func (c *SqlitePersistence) WithDeleted() *SqliteDeletedView {
+	return &SqliteDeletedView{persistence: c}
+}
+
+// Gets a page of data items including soft-deleted ones, see SqlitePersistence.GetPageByFilter.
+func (c *SqliteDeletedView) GetPageByFilter(correlationId string, filter interface{}, paging *cdata.PagingParams,
+	sort interface{}, sel interface{}) (page *cdata.DataPage, err error) {
+	return c.persistence.GetPageByFilter(correlationId, &sqliteDeletedFilter{filter: filter}, paging, sort, sel)
+}
+
+// Gets a number of data items including soft-deleted ones, see SqlitePersistence.GetCountByFilter.
+func (c *SqliteDeletedView) GetCountByFilter(correlationId string, filter interface{}) (count int64, err error) {
+	return c.persistence.GetCountByFilter(correlationId, &sqliteDeletedFilter{filter: filter})
+}
+
+// Gets a list of data items including soft-deleted ones, see SqlitePersistence.GetListByFilter.
+func (c *SqliteDeletedView) GetListByFilter(correlationId string, filter interface{}, sort interface{}, sel interface{}) (items []interface{}, err error) {
+	return c.persistence.GetListByFilter(correlationId, &sqliteDeletedFilter{filter: filter}, sort, sel)
+}
+
+// Gets a random item including soft-deleted ones, see SqlitePersistence.GetOneRandom.
+func (c *SqliteDeletedView) GetOneRandom(correlationId string, filter interface{}) (item interface{}, err error) {
+	return c.persistence.GetOneRandom(correlationId, &sqliteDeletedFilter{filter: filter})
+}
+
+// Gets a random sample of items including soft-deleted ones, see SqlitePersistence.GetRandomSample.
+func (c *SqliteDeletedView) GetRandomSample(correlationId string, filter interface{}, size int) (items []interface{}, err error) {
+	return c.persistence.GetRandomSample(correlationId, &sqliteDeletedFilter{filter: filter}, size)
+}
+
+// Computes aggregates over items including soft-deleted ones, see SqlitePersistence.GetAggregatesByFilter.
+func (c *SqliteDeletedView) GetAggregatesByFilter(correlationId string, filter interface{}, groupBy []string,
+	aggregates []*SqliteAggregate, having []*SqliteAggregateCondition) (rows []*SqliteAggregateRow, err error) {
+	return c.persistence.GetAggregatesByFilter(correlationId, &sqliteDeletedFilter{filter: filter}, groupBy, aggregates, having)
+}
+
+// Gets distinct values of a field in items including soft-deleted ones, see SqlitePersistence.GetDistinctValues.
+func (c *SqliteDeletedView) GetDistinctValues(correlationId string, field string,
+	filter interface{}) (items []*SqliteFacetValue, err error) {
+	return c.persistence.GetDistinctValues(correlationId, field, &sqliteDeletedFilter{filter: filter})
+}
+
+// Gets facets of items including soft-deleted ones, see SqlitePersistence.GetFacets.
+func (c *SqliteDeletedView) GetFacets(correlationId string, filter interface{}, fields []string,
+	limit int) (facets map[string][]*SqliteFacetValue, err error) {
+	return c.persistence.GetFacets(correlationId, &sqliteDeletedFilter{filter: filter}, fields, limit)
+}
+
+// Searches items including soft-deleted ones by text, see SqlitePersistence.SearchByText.
+func (c *SqliteDeletedView) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *SqliteTextSearchPage, err error) {
+	return c.persistence.SearchByText(correlationId, query, &sqliteDeletedFilter{filter: filter}, paging)
+}
+
+/*
+View of an identifiable persistence that includes soft-deleted items in reads.
+*/
+type IdentifiableSqliteDeletedView struct {
+	*SqliteDeletedView
+	persistence *IdentifiableSqlitePersistence
+}
+
+// Gets a view of the persistence that includes soft-deleted items in reads.
+// Returns a persistence view.
+func (c *IdentifiableSqlitePersistence) WithDeleted() *IdentifiableSqliteDeletedView {
+	return &IdentifiableSqliteDeletedView{
+		SqliteDeletedView: c.SqlitePersistence.WithDeleted(),
+		persistence:       c,
+	}
+}
+
+// Gets a list of data items including soft-deleted ones, see IdentifiableSqlitePersistence.GetListByIds.
+func (c *IdentifiableSqliteDeletedView) GetListByIds(correlationId string, ids []interface{}) (items []interface{}, err error) {
+	return c.persistence.getListByIds(correlationId, ids, true)
+}
+
+// Gets a list of data items including soft-deleted ones in the order of the requested ids,
+// see IdentifiableSqlitePersistence.GetListByIdsInOrder.
+func (c *IdentifiableSqliteDeletedView) GetListByIdsInOrder(correlationId string, ids []interface{}) (items []interface{}, missingIds []interface{}, err error) {
+	return c.persistence.getListByIdsInOrder(correlationId, ids, true)
+}
+
+// Gets a data item by its unique id even if it is soft-deleted, see IdentifiableSqlitePersistence.GetOneById.
+func (c *IdentifiableSqliteDeletedView) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	return c.persistence.getOneById(correlationId, id, true)
+}
+
+// Gets a data item by its unique id as it was at a given time even if it was soft-deleted,
+// see IdentifiableSqlitePersistence.GetOneByIdAsOf.
+func (c *IdentifiableSqliteDeletedView) GetOneByIdAsOf(correlationId string, id interface{}, asOf time.Time) (item interface{}, err error) {
+	return c.persistence.getOneByIdAsOf(correlationId, id, asOf, true)
+}
+
+// Gets a list of data items including soft-deleted ones as they were at a given time,
+// see IdentifiableSqlitePersistence.GetListByFilterAsOf.
+func (c *IdentifiableSqliteDeletedView) GetListByFilterAsOf(correlationId string, filter interface{}, sort interface{}, sel interface{},
+	asOf time.Time) (items []interface{}, err error) {
+	return c.persistence.GetListByFilterAsOf(correlationId, &sqliteDeletedFilter{filter: filter}, sort, sel, asOf)
+}
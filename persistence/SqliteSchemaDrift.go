@@ -0,0 +1,476 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+)
+
+// Kinds of differences between declared and live table schemas
+const (
+	SchemaDriftMissingTable     = "missing_table"
+	SchemaDriftMissingColumn    = "missing_column"
+	SchemaDriftExtraColumn      = "extra_column"
+	SchemaDriftColumnType       = "column_type"
+	SchemaDriftColumnConstraint = "column_constraint"
+	SchemaDriftColumnDefault    = "column_default"
+	SchemaDriftMissingIndex     = "missing_index"
+	SchemaDriftExtraIndex       = "extra_index"
+	SchemaDriftIndexDefinition  = "index_definition"
+)
+
+// Describes a single difference between the schema declared by a persistence
+// and the schema of the live database table.
+type SqliteSchemaDrift struct {
+	// The kind of the difference (see SchemaDrift* constants).
+	Kind string
+	// The table name.
+	Table string
+	// The column name (for column differences).
+	Column string
+	// The index name (for index differences).
+	Index string
+	// The declared definition.
+	Expected string
+	// The live definition.
+	Actual string
+	// True if fixing the difference requires losing or rewriting data.
+	Destructive bool
+	// True if the difference was fixed by automatic migration.
+	Fixed bool
+}
+
+// Gets a human-readable description of the difference.
+func (c *SqliteSchemaDrift) String() string {
+	result := c.Kind + " in " + c.Table
+	if c.Column != "" {
+		result += " column " + c.Column
+	}
+	if c.Index != "" {
+		result += " index " + c.Index
+	}
+	if c.Expected != "" || c.Actual != "" {
+		result += ": expected '" + c.Expected + "', actual '" + c.Actual + "'"
+	}
+	return result
+}
+
+type schemaTable struct {
+	columns []*conn.SqliteColumnInfo
+	indexes []*conn.SqliteIndexInfo
+	// True if the table was derived from the prototype.
+	derived bool
+}
+
+// Compares the schema declared in DefineSchema (or derived from the prototype
+// if the table is not declared there) with the live table schema.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a list of found differences or error.
+func (c *SqlitePersistence) DetectSchemaDrift(correlationId string) ([]*SqliteSchemaDrift, error) {
+	declared, err := c.readDeclaredSchema(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	live, err := readSchemaTable(c.Connection, c.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return c.compareSchemas(declared, live), nil
+}
+
+// Detects schema differences and applies additive ones: creates a missing table,
+// adds missing columns with ALTER TABLE ADD COLUMN and creates missing indexes.
+// Other differences are reported as warnings, or as an error in strict mode.
+// It is called automatically on opening when auto_migrate option is set.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a list of found differences or error.
+func (c *SqlitePersistence) FixSchemaDrift(correlationId string) ([]*SqliteSchemaDrift, error) {
+	declared, err := c.readDeclaredSchema(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	live, err := readSchemaTable(c.Connection, c.TableName)
+	if err != nil {
+		return nil, err
+	}
+	drifts := c.compareSchemas(declared, live)
+
+	unresolved := make([]*SqliteSchemaDrift, 0)
+	for _, drift := range drifts {
+		statement := c.composeDriftFix(drift, declared)
+		if statement != "" {
+			if drift.Kind == SchemaDriftMissingTable && !declared.derived {
+				err = c.CreateSchema(correlationId)
+			} else {
+				_, err = c.Client.Exec(statement)
+			}
+			if err != nil {
+				c.Logger.Error(correlationId, err, "Failed to fix schema drift: %s", drift.String())
+				return drifts, err
+			}
+			drift.Fixed = true
+			c.Logger.Info(correlationId, "Fixed schema drift: %s", drift.String())
+			continue
+		}
+
+		c.Logger.Warn(correlationId, "Detected schema drift: %s", drift.String())
+		unresolved = append(unresolved, drift)
+	}
+
+	if c.StrictSchema && len(unresolved) > 0 {
+		messages := make([]string, len(unresolved))
+		for index, drift := range unresolved {
+			messages[index] = drift.String()
+		}
+		return drifts, cerr.NewInvalidStateError(correlationId, "SCHEMA_DRIFT",
+			"Schema of "+c.TableName+" differs from the declared one: "+strings.Join(messages, "; ")).
+			WithDetails("drifts", unresolved)
+	}
+
+	return drifts, nil
+}
+
+func (c *SqlitePersistence) composeDriftFix(drift *SqliteSchemaDrift, declared *schemaTable) string {
+	switch drift.Kind {
+	case SchemaDriftMissingTable:
+		return drift.Expected
+	case SchemaDriftMissingColumn:
+		for _, column := range declared.columns {
+			if column.Name != drift.Column {
+				continue
+			}
+			// SQLite cannot add primary keys and NOT NULL columns without defaults
+			if column.PrimaryKey > 0 || (column.NotNull && column.DefaultValue == nil) {
+				return ""
+			}
+			return "ALTER TABLE " + c.QuoteIdentifier(c.TableName) + " ADD COLUMN " + drift.Expected
+		}
+	case SchemaDriftMissingIndex:
+		return drift.Expected
+	}
+	return ""
+}
+
+func (c *SqlitePersistence) compareSchemas(declared *schemaTable, live *schemaTable) []*SqliteSchemaDrift {
+	drifts := make([]*SqliteSchemaDrift, 0)
+
+	if len(live.columns) == 0 {
+		if len(declared.columns) > 0 {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:     SchemaDriftMissingTable,
+				Table:    c.TableName,
+				Expected: c.composeCreateTable(declared),
+			})
+		}
+		return drifts
+	}
+
+	liveColumns := make(map[string]*conn.SqliteColumnInfo, 0)
+	for _, column := range live.columns {
+		liveColumns[strings.ToLower(column.Name)] = column
+	}
+	declaredColumns := make(map[string]*conn.SqliteColumnInfo, 0)
+	for _, column := range declared.columns {
+		declaredColumns[strings.ToLower(column.Name)] = column
+	}
+
+	for _, column := range declared.columns {
+		liveColumn, ok := liveColumns[strings.ToLower(column.Name)]
+		if !ok {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:     SchemaDriftMissingColumn,
+				Table:    c.TableName,
+				Column:   column.Name,
+				Expected: c.composeColumnDefinition(column),
+			})
+			continue
+		}
+		// Types of derived columns are only guesses
+		if declared.derived {
+			continue
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(column.Type), strings.TrimSpace(liveColumn.Type)) {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:        SchemaDriftColumnType,
+				Table:       c.TableName,
+				Column:      column.Name,
+				Expected:    column.Type,
+				Actual:      liveColumn.Type,
+				Destructive: true,
+			})
+		}
+		if column.NotNull != liveColumn.NotNull || (column.PrimaryKey > 0) != (liveColumn.PrimaryKey > 0) {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:        SchemaDriftColumnConstraint,
+				Table:       c.TableName,
+				Column:      column.Name,
+				Expected:    composeColumnConstraints(column),
+				Actual:      composeColumnConstraints(liveColumn),
+				Destructive: true,
+			})
+		}
+		expectedDefault, actualDefault := column.DefaultValue, liveColumn.DefaultValue
+		if (expectedDefault == nil) != (actualDefault == nil) || (expectedDefault != nil && *expectedDefault != *actualDefault) {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:     SchemaDriftColumnDefault,
+				Table:    c.TableName,
+				Column:   column.Name,
+				Expected: cconv.StringConverter.ToString(expectedDefault),
+				Actual:   cconv.StringConverter.ToString(actualDefault),
+			})
+		}
+	}
+
+	for _, column := range live.columns {
+		// Columns of JSON fields are added on opening, generated or stored for encoded documents
+		if c.isJsonFieldColumn(column.Name) {
+			continue
+		}
+		if _, ok := declaredColumns[strings.ToLower(column.Name)]; !ok {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:        SchemaDriftExtraColumn,
+				Table:       c.TableName,
+				Column:      column.Name,
+				Actual:      c.composeColumnDefinition(column),
+				Destructive: true,
+			})
+		}
+	}
+
+	liveIndexes := make(map[string]*conn.SqliteIndexInfo, 0)
+	for _, index := range live.indexes {
+		liveIndexes[strings.ToLower(index.Name)] = index
+	}
+	declaredIndexes := make(map[string]*conn.SqliteIndexInfo, 0)
+	for _, index := range declared.indexes {
+		declaredIndexes[strings.ToLower(index.Name)] = index
+	}
+
+	for _, index := range declared.indexes {
+		liveIndex, ok := liveIndexes[strings.ToLower(index.Name)]
+		if !ok {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:     SchemaDriftMissingIndex,
+				Table:    c.TableName,
+				Index:    index.Name,
+				Expected: index.Sql,
+			})
+			continue
+		}
+		if index.Unique != liveIndex.Unique || index.Partial != liveIndex.Partial ||
+			composeIndexColumns(index) != composeIndexColumns(liveIndex) {
+			drifts = append(drifts, &SqliteSchemaDrift{
+				Kind:        SchemaDriftIndexDefinition,
+				Table:       c.TableName,
+				Index:       index.Name,
+				Expected:    index.Sql,
+				Actual:      liveIndex.Sql,
+				Destructive: true,
+			})
+		}
+	}
+
+	if !declared.derived {
+		for _, index := range live.indexes {
+			if c.isJsonFieldIndex(index.Name) {
+				continue
+			}
+			if _, ok := declaredIndexes[strings.ToLower(index.Name)]; !ok {
+				drifts = append(drifts, &SqliteSchemaDrift{
+					Kind:   SchemaDriftExtraIndex,
+					Table:  c.TableName,
+					Index:  index.Name,
+					Actual: index.Sql,
+				})
+			}
+		}
+	}
+
+	return drifts
+}
+
+func (c *SqlitePersistence) composeColumnDefinition(column *conn.SqliteColumnInfo) string {
+	result := c.QuoteIdentifier(column.Name)
+	if column.Type != "" {
+		result += " " + column.Type
+	}
+	if column.NotNull {
+		result += " NOT NULL"
+	}
+	if column.DefaultValue != nil {
+		result += " DEFAULT " + *column.DefaultValue
+	}
+	return result
+}
+
+func composeColumnConstraints(column *conn.SqliteColumnInfo) string {
+	constraints := make([]string, 0)
+	if column.PrimaryKey > 0 {
+		constraints = append(constraints, "PRIMARY KEY")
+	}
+	if column.NotNull {
+		constraints = append(constraints, "NOT NULL")
+	}
+	return strings.Join(constraints, " ")
+}
+
+func (c *SqlitePersistence) composeCreateTable(table *schemaTable) string {
+	columns := make([]string, len(table.columns))
+	for index, column := range table.columns {
+		columns[index] = c.composeColumnDefinition(column)
+		if column.PrimaryKey > 0 {
+			columns[index] += " PRIMARY KEY"
+		}
+	}
+	return "CREATE TABLE IF NOT EXISTS " + c.QuoteIdentifier(c.TableName) + " (" + strings.Join(columns, ", ") + ")"
+}
+
+// Reads the declared schema by executing schema statements in a scratch in-memory database.
+func (c *SqlitePersistence) readDeclaredSchema(correlationId string) (*schemaTable, error) {
+	scratch := conn.NewSqliteConnection()
+	scratch.Configure(cconf.NewConfigParamsFromTuples("connection.database", ":memory:"))
+	err := scratch.Open(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.Close(correlationId)
+	// Every connection to :memory: gets its own database
+	scratch.GetConnection().SetMaxOpenConns(1)
+
+	for _, statement := range c.schemaStatements {
+		_, err = scratch.GetConnection().Exec(statement)
+		if err != nil {
+			// Statements may depend on objects owned by other persistences
+			c.Logger.Debug(correlationId, "Skipped schema statement in drift detection: %s", err.Error())
+		}
+	}
+
+	table, err := readSchemaTable(scratch, c.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(table.columns) > 0 {
+		return table, nil
+	}
+
+	return c.deriveSchemaFromPrototype(), nil
+}
+
+// Derives table columns from the prototype fields using their json names.
+func (c *SqlitePersistence) deriveSchemaFromPrototype() *schemaTable {
+	table := &schemaTable{
+		columns: make([]*conn.SqliteColumnInfo, 0),
+		indexes: make([]*conn.SqliteIndexInfo, 0),
+		derived: true,
+	}
+	if c.Prototype == nil {
+		return table
+	}
+
+	proto := c.Prototype
+	if proto.Kind() == reflect.Ptr {
+		proto = proto.Elem()
+	}
+	if proto.Kind() != reflect.Struct {
+		return table
+	}
+
+	for index := 0; index < proto.NumField(); index++ {
+		field := proto.Field(index)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		column := &conn.SqliteColumnInfo{
+			Position: len(table.columns),
+			Name:     name,
+			Type:     sqliteTypeOf(field.Type),
+		}
+		if strings.EqualFold(name, "id") {
+			column.PrimaryKey = 1
+		}
+		table.columns = append(table.columns, column)
+	}
+	return table
+}
+
+func sqliteTypeOf(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.String:
+		return "TEXT"
+	}
+	if typ.PkgPath() == "time" && typ.Name() == "Time" {
+		return "TEXT"
+	}
+	return "JSON"
+}
+
+func readSchemaTable(connection *conn.SqliteConnection, tableName string) (*schemaTable, error) {
+	table := &schemaTable{
+		columns: make([]*conn.SqliteColumnInfo, 0),
+		indexes: make([]*conn.SqliteIndexInfo, 0),
+	}
+
+	columns, err := connection.GetColumns("", tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		// Generated columns are not a part of the declared schema
+		if !column.Hidden {
+			table.columns = append(table.columns, column)
+		}
+	}
+
+	indexes, err := connection.GetIndexes("", tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		// Only explicitly created indexes can be compared and recreated
+		if index.Origin == "c" {
+			table.indexes = append(table.indexes, index)
+		}
+	}
+
+	return table, nil
+}
+
+func composeIndexColumns(index *conn.SqliteIndexInfo) string {
+	columns := make([]string, len(index.Columns))
+	for position, column := range index.Columns {
+		name := column.Name
+		if name == "" {
+			name = "<expr>"
+		}
+		if column.Desc {
+			name += " DESC"
+		}
+		columns[position] = strings.ToLower(name)
+	}
+	// The order of columns defines which queries the index serves
+	return strings.Join(columns, ",")
+}
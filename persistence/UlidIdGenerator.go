@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+/*
+Generates time-ordered ULIDs (https://github.com/ulid/spec).
+
+A ULID is a 26 characters Crockford base32 string with 48 bits of unix time
+in milliseconds and 80 bits of randomness. Ids generated within the same
+millisecond increment the random part, so they stay strictly increasing.
+*/
+type UlidIdGenerator struct {
+	lock     sync.Mutex
+	lastTime int64
+	entropy  [10]byte
+}
+
+// Creates a new instance of the generator.
+func NewUlidIdGenerator() *UlidIdGenerator {
+	return &UlidIdGenerator{}
+}
+
+// Generates a new ULID.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a generated id or error.
+func (c *UlidIdGenerator) NextId(correlationId string) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now().UnixMilli()
+	if now > c.lastTime {
+		c.lastTime = now
+		if _, err := rand.Read(c.entropy[:]); err != nil {
+			return nil, err
+		}
+	} else {
+		// Increment the random part as a big-endian number
+		index := len(c.entropy) - 1
+		for ; index >= 0; index-- {
+			c.entropy[index]++
+			if c.entropy[index] != 0 {
+				break
+			}
+		}
+		// Random part overflow borrows the next millisecond
+		if index < 0 {
+			c.lastTime++
+		}
+	}
+
+	var value [16]byte
+	timestamp := c.lastTime
+	for index := 5; index >= 0; index-- {
+		value[index] = byte(timestamp)
+		timestamp >>= 8
+	}
+	copy(value[6:], c.entropy[:])
+
+	// Encode 128 bits as 26 characters, the first character keeps only 3 bits
+	buf := make([]byte, 26)
+	var bits uint32
+	var count uint
+	position := 25
+	for index := 15; index >= 0; index-- {
+		bits |= uint32(value[index]) << count
+		count += 8
+		for count >= 5 && position >= 0 {
+			buf[position] = ulidAlphabet[bits&0x1f]
+			bits >>= 5
+			count -= 5
+			position--
+		}
+	}
+	if position >= 0 {
+		buf[position] = ulidAlphabet[bits&0x1f]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+/*
+Generates time-ordered UUIDs version 7 (RFC 9562).
+
+The first 48 bits keep unix time in milliseconds and the next 12 bits keep
+a counter, so ids generated by the same instance are strictly increasing
+and new rows are appended to the end of the primary key B-tree.
+*/
+type UuidV7IdGenerator struct {
+	lock     sync.Mutex
+	lastTime int64
+	counter  uint16
+}
+
+// Creates a new instance of the generator.
+func NewUuidV7IdGenerator() *UuidV7IdGenerator {
+	return &UuidV7IdGenerator{}
+}
+
+// Generates a new UUID in canonical 8-4-4-4-12 format.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns a generated id or error.
+func (c *UuidV7IdGenerator) NextId(correlationId string) (interface{}, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	now := time.Now().UnixMilli()
+	if now > c.lastTime {
+		c.lastTime = now
+		// Start from a random point to keep ids unpredictable
+		c.counter = (uint16(uuid[6])<<8 | uint16(uuid[7])) & 0x07ff
+	} else {
+		c.counter++
+		// Counter overflow borrows the next millisecond
+		if c.counter > 0x0fff {
+			c.lastTime++
+			c.counter = 0
+		}
+	}
+	timestamp, counter := c.lastTime, c.counter
+	c.lock.Unlock()
+
+	uuid[0] = byte(timestamp >> 40)
+	uuid[1] = byte(timestamp >> 32)
+	uuid[2] = byte(timestamp >> 24)
+	uuid[3] = byte(timestamp >> 16)
+	uuid[4] = byte(timestamp >> 8)
+	uuid[5] = byte(timestamp)
+	uuid[6] = 0x70 | byte(counter>>8)
+	uuid[7] = byte(counter)
+	uuid[8] = 0x80 | (uuid[8] & 0x3f)
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf), nil
+}
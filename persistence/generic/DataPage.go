@@ -0,0 +1,19 @@
+package generic
+
+/*
+Data transfer object that is used to pass results of paginated queries
+with typed data items.
+*/
+type DataPage[T any] struct {
+	// The total number of items when it was requested.
+	Total *int64 `json:"total"`
+	// The items of the retrieved page.
+	Data []T `json:"data"`
+}
+
+// Creates a new instance of data page.
+// - total     (optional) a total number of items.
+// - data      a list of items from the retrieved page.
+func NewDataPage[T any](total *int64, data []T) *DataPage[T] {
+	return &DataPage[T]{Total: total, Data: data}
+}
@@ -0,0 +1,117 @@
+package generic
+
+import (
+	"database/sql"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+)
+
+/*
+Abstract persistence component that stores data items of type T with unique ids of type K
+in SQLite JSON fields.
+
+It wraps persist.IdentifiableJsonSqlitePersistence and returns typed items and data pages.
+T can be a struct, a pointer to a struct or a map.
+
+### Example ###
+
+	type MySqlitePersistence struct {
+		generic.IdentifiableJsonSqlitePersistence[MyData, string]
+	}
+
+	func NewMySqlitePersistence() *MySqlitePersistence {
+		c := &MySqlitePersistence{}
+		c.IdentifiableJsonSqlitePersistence = *generic.InheritIdentifiableJsonSqlitePersistence[MyData, string](c, "mydata")
+		return c
+	}
+
+	func (c *MySqlitePersistence) DefineSchema() {
+		c.ClearSchema()
+		c.EnsureTable("", "")
+	}
+*/
+type IdentifiableJsonSqlitePersistence[T any, K any] struct {
+	IdentifiableSqlitePersistence[T, K]
+
+	// The wrapped untyped JSON persistence.
+	JsonPersistence *persist.IdentifiableJsonSqlitePersistence
+}
+
+// Creates a new instance of the persistence component.
+// - overrides  a references to child class that overrides virtual methods
+// - tableName  a table name.
+func InheritIdentifiableJsonSqlitePersistence[T any, K any](overrides persist.ISqlitePersistenceOverrides, tableName string) *IdentifiableJsonSqlitePersistence[T, K] {
+	jsonPersistence := persist.InheritIdentifiableJsonSqlitePersistence(overrides, prototypeOf[T](), tableName)
+	return &IdentifiableJsonSqlitePersistence[T, K]{
+		IdentifiableSqlitePersistence: IdentifiableSqlitePersistence[T, K]{
+			IdentifiableSqlitePersistence: &jsonPersistence.IdentifiableSqlitePersistence,
+		},
+		JsonPersistence: jsonPersistence,
+	}
+}
+
+// Adds DML statement to automatically create JSON table
+// - idType type of the id column (default: VARCHAR(32) or INTEGER when ids are assigned by SQLite)
+// - dataType type of the data column (default: JSON)
+func (c *IdentifiableJsonSqlitePersistence[T, K]) EnsureTable(idType string, dataType string) {
+	c.JsonPersistence.EnsureTable(idType, dataType)
+}
+
+// Adds a virtual generated column that extracts a field from JSON documents to create it on opening.
+// - path        a JSON path of the field like $.key.
+// - sqlType     (optional) SQL type of the column like TEXT or INTEGER.
+// - indexed     true to create an index on the column.
+// - unique      true to create a unique index on the column.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) EnsureJsonField(path string, sqlType string, indexed bool, unique bool) {
+	c.JsonPersistence.EnsureJsonField(path, sqlType, indexed, unique)
+}
+
+// Registers an upcaster that transforms documents of a given version to the next version.
+// - version     the version of documents transformed by the upcaster, starting from 1.
+// - upcaster    the function that transforms documents to the next version.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) RegisterUpcaster(version int, upcaster persist.SqliteUpcaster) {
+	c.JsonPersistence.RegisterUpcaster(version, upcaster)
+}
+
+// Converts object value from internal to public format.
+// - value     an object in internal format to convert.
+// Returns converted object in public format.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) ConvertToPublic(rows *sql.Rows) interface{} {
+	return c.JsonPersistence.ConvertToPublic(rows)
+}
+
+// Convert object value from public to internal format.
+// - value     an object in public format to convert.
+// Returns converted object in internal format.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) ConvertFromPublic(value interface{}) interface{} {
+	return c.JsonPersistence.ConvertFromPublic(value)
+}
+
+// Converts the given object from the public partial format.
+// - value     the object to convert from the public partial format.
+// Returns the initial object.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) ConvertFromPublicPartial(value interface{}) interface{} {
+	return c.JsonPersistence.ConvertFromPublicPartial(value)
+}
+
+// Updates only few selected fields in a data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be updated.
+// - data              a map with fields to be updated.
+// Returns           updated item or error.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) UpdatePartially(correlationId string, id K, data *cdata.AnyValueMap) (result T, err error) {
+	value, err := c.JsonPersistence.UpdatePartially(correlationId, id, data)
+	return toTyped[T](value), err
+}
+
+// Applies path-level patch operations to a JSON document in a single atomic update.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be patched.
+// - operations        patch operations to be applied in order.
+// Returns           patched item or error. Zero value of T is returned when the item doesn't exist.
+func (c *IdentifiableJsonSqlitePersistence[T, K]) PatchById(correlationId string, id K,
+	operations []*persist.SqliteJsonPatchOperation) (result T, err error) {
+	value, err := c.JsonPersistence.PatchById(correlationId, id, operations)
+	return toTyped[T](value), err
+}
@@ -0,0 +1,302 @@
+package generic
+
+import (
+	"time"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+)
+
+/*
+Abstract persistence component that stores data items of type T with unique ids of type K in SQLite.
+
+It wraps persist.IdentifiableSqlitePersistence and returns typed items and data pages,
+so child classes don't have to convert results and ids from interface{}.
+T can be a struct, a pointer to a struct or a map.
+
+### Example ###
+
+	type MySqlitePersistence struct {
+		generic.IdentifiableSqlitePersistence[MyData, string]
+	}
+
+	func NewMySqlitePersistence() *MySqlitePersistence {
+		c := &MySqlitePersistence{}
+		c.IdentifiableSqlitePersistence = *generic.InheritIdentifiableSqlitePersistence[MyData, string](c, "mydata")
+		return c
+	}
+
+	item, err := persistence.GetOneById("123", "1")
+	fmt.Println(item.Name) // Result: ABC
+*/
+type IdentifiableSqlitePersistence[T any, K any] struct {
+	*persist.IdentifiableSqlitePersistence
+}
+
+// Creates a new instance of the persistence component.
+// - overrides  a references to child class that overrides virtual methods
+// - tableName  a table name.
+func InheritIdentifiableSqlitePersistence[T any, K any](overrides persist.ISqlitePersistenceOverrides, tableName string) *IdentifiableSqlitePersistence[T, K] {
+	return &IdentifiableSqlitePersistence[T, K]{
+		IdentifiableSqlitePersistence: persist.InheritIdentifiableSqlitePersistence(overrides, prototypeOf[T](), tableName),
+	}
+}
+
+// Gets a page of data items retrieved by a given filter and sorted according to sort parameters.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// - paging            (optional) paging parameters
+// - sort              (optional) sorting JSON object
+// - select            (optional) projection JSON object
+// Returns           a typed data page or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetPageByFilter(correlationId string, filter interface{}, paging *cdata.PagingParams,
+	sort interface{}, sel interface{}) (page *DataPage[T], err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetPageByFilter(correlationId, filter, paging, sort, sel)
+	return toTypedPage[T](result), err
+}
+
+// Gets a list of data items retrieved by a given filter and sorted according to sort parameters.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - filter           (optional) a filter JSON object
+// - sort             (optional) sorting JSON object
+// - select           (optional) projection JSON object
+// Returns          a typed data list or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetListByFilter(correlationId string, filter interface{}, sort interface{}, sel interface{}) (items []T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetListByFilter(correlationId, filter, sort, sel)
+	return toTypedList[T](result), err
+}
+
+// Gets a random item from items that match to a given filter.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// Returns            a random item or error. Zero value of T is returned when nothing is found.
+func (c *IdentifiableSqlitePersistence[T, K]) GetOneRandom(correlationId string, filter interface{}) (item T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetOneRandom(correlationId, filter)
+	return toTyped[T](result), err
+}
+
+// Gets a random sample of distinct items that match to a given filter.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// - size              a maximum number of items in the sample.
+// Returns            a typed list of sampled items or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetRandomSample(correlationId string, filter interface{}, size int) (items []T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetRandomSample(correlationId, filter, size)
+	return toTypedList[T](result), err
+}
+
+// Gets a list of data items retrieved by given unique ids.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be retrieved
+// Returns          a typed data list or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetListByIds(correlationId string, ids []K) (items []T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetListByIds(correlationId, toUntypedIds(ids))
+	return toTypedList[T](result), err
+}
+
+// Gets a list of data items retrieved by given unique ids
+// in the order of the requested ids.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be retrieved
+// Returns          a typed data list, a list of ids that were not found or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetListByIdsInOrder(correlationId string, ids []K) (items []T, missingIds []K, err error) {
+	result, missing, err := c.IdentifiableSqlitePersistence.GetListByIdsInOrder(correlationId, toUntypedIds(ids))
+	missingIds = make([]K, len(missing))
+	for index, id := range missing {
+		missingIds[index] = id.(K)
+	}
+	return toTypedList[T](result), missingIds, err
+}
+
+// Gets a data item by its unique id.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be retrieved.
+// Returns           data item or error. Zero value of T is returned when nothing is found.
+func (c *IdentifiableSqlitePersistence[T, K]) GetOneById(correlationId string, id K) (item T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetOneById(correlationId, id)
+	return toTyped[T](result), err
+}
+
+// Gets a data item by its unique id as it was at a given time.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be retrieved.
+// - asOf              the time of the state to be retrieved.
+// Returns           a typed data item or error. Zero value of T is returned when the item didn't exist.
+func (c *IdentifiableSqlitePersistence[T, K]) GetOneByIdAsOf(correlationId string, id K, asOf time.Time) (item T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetOneByIdAsOf(correlationId, id, asOf)
+	return toTyped[T](result), err
+}
+
+// Gets a list of data items as they were at a given time, retrieved by a given filter.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - filter           (optional) a filter JSON object
+// - sort             (optional) sorting JSON object
+// - select           (optional) projection JSON object
+// - asOf             the time of the state to be retrieved.
+// Returns          a typed data list or error.
+func (c *IdentifiableSqlitePersistence[T, K]) GetListByFilterAsOf(correlationId string, filter interface{}, sort interface{}, sel interface{},
+	asOf time.Time) (items []T, err error) {
+	result, err := c.IdentifiableSqlitePersistence.GetListByFilterAsOf(correlationId, filter, sort, sel, asOf)
+	return toTypedList[T](result), err
+}
+
+// Searches data items by text in the full-text index and ranks them with bm25.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - query             an FTS5 query.
+// - filter            (optional) a filter JSON object
+// - paging            (optional) paging parameters
+// Returns           a page of typed items with scores, snippets and highlights or error.
+func (c *IdentifiableSqlitePersistence[T, K]) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *TextSearchPage[T], err error) {
+	result, err := c.IdentifiableSqlitePersistence.SearchByText(correlationId, query, filter, paging)
+	return toTypedTextSearchPage[T](result), err
+}
+
+// Creates a data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item              an item to be created.
+// Returns          (optional)  created item or error.
+func (c *IdentifiableSqlitePersistence[T, K]) Create(correlationId string, item T) (result T, err error) {
+	value, err := c.IdentifiableSqlitePersistence.Create(correlationId, item)
+	return toTyped[T](value), err
+}
+
+// Sets a data item. If the data item exists it updates it,
+// otherwise it create a new data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item              a item to be set.
+// Returns          (optional)  updated item or error.
+func (c *IdentifiableSqlitePersistence[T, K]) Set(correlationId string, item T) (result T, err error) {
+	value, err := c.IdentifiableSqlitePersistence.Set(correlationId, item)
+	return toTyped[T](value), err
+}
+
+// Updates a data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item              an item to be updated.
+// Returns          (optional)  updated item or error.
+func (c *IdentifiableSqlitePersistence[T, K]) Update(correlationId string, item T) (result T, err error) {
+	value, err := c.IdentifiableSqlitePersistence.Update(correlationId, item)
+	return toTyped[T](value), err
+}
+
+// Updates only few selected fields in a data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - id                an id of data item to be updated.
+// - data              a map with fields to be updated.
+// Returns           updated item or error.
+func (c *IdentifiableSqlitePersistence[T, K]) UpdatePartially(correlationId string, id K, data *cdata.AnyValueMap) (result T, err error) {
+	value, err := c.IdentifiableSqlitePersistence.UpdatePartially(correlationId, id, data)
+	return toTyped[T](value), err
+}
+
+// Deleted a data item by it's unique id.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - id                an id of the item to be deleted
+// Returns          (optional)  deleted item or error.
+func (c *IdentifiableSqlitePersistence[T, K]) DeleteById(correlationId string, id K) (result T, err error) {
+	value, err := c.IdentifiableSqlitePersistence.DeleteById(correlationId, id)
+	return toTyped[T](value), err
+}
+
+// Deletes multiple data items by their unique ids.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be deleted.
+// Returns          a number of deleted items or error.
+func (c *IdentifiableSqlitePersistence[T, K]) DeleteByIds(correlationId string, ids []K) (count int64, err error) {
+	return c.IdentifiableSqlitePersistence.DeleteByIds(correlationId, toUntypedIds(ids))
+}
+
+// Gets a view of the persistence that includes soft-deleted items in reads.
+// Returns a persistence view.
+func (c *IdentifiableSqlitePersistence[T, K]) WithDeleted() *IdentifiableSqliteDeletedView[T, K] {
+	return &IdentifiableSqliteDeletedView[T, K]{
+		IdentifiableSqliteDeletedView: c.IdentifiableSqlitePersistence.WithDeleted(),
+	}
+}
+
+// Restores soft-deleted data items by their unique ids.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - ids               ids of data items to be restored.
+// Returns          a number of restored items or error.
+func (c *IdentifiableSqlitePersistence[T, K]) Restore(correlationId string, ids []K) (count int64, err error) {
+	return c.IdentifiableSqlitePersistence.Restore(correlationId, toUntypedIds(ids))
+}
+
+/*
+View of a typed identifiable persistence that includes soft-deleted items in reads.
+It wraps persist.IdentifiableSqliteDeletedView and returns typed items and data pages.
+*/
+type IdentifiableSqliteDeletedView[T any, K any] struct {
+	*persist.IdentifiableSqliteDeletedView
+}
+
+// Gets a page of data items including soft-deleted ones, see IdentifiableSqlitePersistence.GetPageByFilter.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetPageByFilter(correlationId string, filter interface{}, paging *cdata.PagingParams,
+	sort interface{}, sel interface{}) (page *DataPage[T], err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetPageByFilter(correlationId, filter, paging, sort, sel)
+	return toTypedPage[T](result), err
+}
+
+// Gets a list of data items including soft-deleted ones, see IdentifiableSqlitePersistence.GetListByFilter.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetListByFilter(correlationId string, filter interface{}, sort interface{}, sel interface{}) (items []T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetListByFilter(correlationId, filter, sort, sel)
+	return toTypedList[T](result), err
+}
+
+// Gets a random item including soft-deleted ones, see IdentifiableSqlitePersistence.GetOneRandom.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetOneRandom(correlationId string, filter interface{}) (item T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetOneRandom(correlationId, filter)
+	return toTyped[T](result), err
+}
+
+// Gets a random sample of items including soft-deleted ones, see IdentifiableSqlitePersistence.GetRandomSample.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetRandomSample(correlationId string, filter interface{}, size int) (items []T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetRandomSample(correlationId, filter, size)
+	return toTypedList[T](result), err
+}
+
+// Gets a list of data items including soft-deleted ones, see IdentifiableSqlitePersistence.GetListByIds.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetListByIds(correlationId string, ids []K) (items []T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetListByIds(correlationId, toUntypedIds(ids))
+	return toTypedList[T](result), err
+}
+
+// Gets a list of data items including soft-deleted ones in the order of the requested ids,
+// see IdentifiableSqlitePersistence.GetListByIdsInOrder.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetListByIdsInOrder(correlationId string, ids []K) (items []T, missingIds []K, err error) {
+	result, missing, err := c.IdentifiableSqliteDeletedView.GetListByIdsInOrder(correlationId, toUntypedIds(ids))
+	missingIds = make([]K, len(missing))
+	for index, id := range missing {
+		missingIds[index] = id.(K)
+	}
+	return toTypedList[T](result), missingIds, err
+}
+
+// Gets a data item by its unique id even if it is soft-deleted, see IdentifiableSqlitePersistence.GetOneById.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetOneById(correlationId string, id K) (item T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetOneById(correlationId, id)
+	return toTyped[T](result), err
+}
+
+// Gets a data item by its unique id as it was at a given time even if it was soft-deleted,
+// see IdentifiableSqlitePersistence.GetOneByIdAsOf.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetOneByIdAsOf(correlationId string, id K, asOf time.Time) (item T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetOneByIdAsOf(correlationId, id, asOf)
+	return toTyped[T](result), err
+}
+
+// Gets a list of data items including soft-deleted ones as they were at a given time,
+// see IdentifiableSqlitePersistence.GetListByFilterAsOf.
+func (c *IdentifiableSqliteDeletedView[T, K]) GetListByFilterAsOf(correlationId string, filter interface{}, sort interface{}, sel interface{},
+	asOf time.Time) (items []T, err error) {
+	result, err := c.IdentifiableSqliteDeletedView.GetListByFilterAsOf(correlationId, filter, sort, sel, asOf)
+	return toTypedList[T](result), err
+}
+
+// Searches items including soft-deleted ones by text, see IdentifiableSqlitePersistence.SearchByText.
+func (c *IdentifiableSqliteDeletedView[T, K]) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *TextSearchPage[T], err error) {
+	result, err := c.IdentifiableSqliteDeletedView.SearchByText(correlationId, query, filter, paging)
+	return toTypedTextSearchPage[T](result), err
+}
@@ -0,0 +1,153 @@
+package generic
+
+import (
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+)
+
+/*
+Abstract persistence component that stores data items of type T in SQLite.
+
+It wraps persist.SqlitePersistence and returns typed items and data pages,
+so child classes don't have to convert results from interface{}.
+T can be a struct, a pointer to a struct or a map.
+
+### Example ###
+
+	type MySqlitePersistence struct {
+		generic.SqlitePersistence[MyData]
+	}
+
+	func NewMySqlitePersistence() *MySqlitePersistence {
+		c := &MySqlitePersistence{}
+		c.SqlitePersistence = *generic.InheritSqlitePersistence[MyData](c, "mydata")
+		return c
+	}
+
+	page, err := persistence.GetPageByFilter("123", "name='ABC'", nil, nil, nil)
+	fmt.Println(page.Data[0].Name) // Result: ABC
+*/
+type SqlitePersistence[T any] struct {
+	*persist.SqlitePersistence
+}
+
+// Creates a new instance of the persistence component.
+// - overrides  a references to child class that overrides virtual methods
+// - tableName  a table name.
+func InheritSqlitePersistence[T any](overrides persist.ISqlitePersistenceOverrides, tableName string) *SqlitePersistence[T] {
+	return &SqlitePersistence[T]{
+		SqlitePersistence: persist.InheritSqlitePersistence(overrides, prototypeOf[T](), tableName),
+	}
+}
+
+// Gets a page of data items retrieved by a given filter and sorted according to sort parameters.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// - paging            (optional) paging parameters
+// - sort              (optional) sorting JSON object
+// - select            (optional) projection JSON object
+// Returns           a typed data page or error.
+func (c *SqlitePersistence[T]) GetPageByFilter(correlationId string, filter interface{}, paging *cdata.PagingParams,
+	sort interface{}, sel interface{}) (page *DataPage[T], err error) {
+	result, err := c.SqlitePersistence.GetPageByFilter(correlationId, filter, paging, sort, sel)
+	return toTypedPage[T](result), err
+}
+
+// Gets a list of data items retrieved by a given filter and sorted according to sort parameters.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - filter           (optional) a filter JSON object
+// - sort             (optional) sorting JSON object
+// - select           (optional) projection JSON object
+// Returns          a typed data list or error.
+func (c *SqlitePersistence[T]) GetListByFilter(correlationId string, filter interface{}, sort interface{}, sel interface{}) (items []T, err error) {
+	result, err := c.SqlitePersistence.GetListByFilter(correlationId, filter, sort, sel)
+	return toTypedList[T](result), err
+}
+
+// Gets a random item from items that match to a given filter.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// Returns            a random item or error. Zero value of T is returned when nothing is found.
+func (c *SqlitePersistence[T]) GetOneRandom(correlationId string, filter interface{}) (item T, err error) {
+	result, err := c.SqlitePersistence.GetOneRandom(correlationId, filter)
+	return toTyped[T](result), err
+}
+
+// Gets a random sample of distinct items that match to a given filter.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - filter            (optional) a filter JSON object
+// - size              a maximum number of items in the sample.
+// Returns            a typed list of sampled items or error.
+func (c *SqlitePersistence[T]) GetRandomSample(correlationId string, filter interface{}, size int) (items []T, err error) {
+	result, err := c.SqlitePersistence.GetRandomSample(correlationId, filter, size)
+	return toTypedList[T](result), err
+}
+
+// Searches data items by text in the full-text index and ranks them with bm25.
+// - correlationId     (optional) transaction id to trace execution through call chain.
+// - query             an FTS5 query.
+// - filter            (optional) a filter JSON object
+// - paging            (optional) paging parameters
+// Returns           a page of typed items with scores, snippets and highlights or error.
+func (c *SqlitePersistence[T]) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *TextSearchPage[T], err error) {
+	result, err := c.SqlitePersistence.SearchByText(correlationId, query, filter, paging)
+	return toTypedTextSearchPage[T](result), err
+}
+
+// Creates a data item.
+// - correlationId    (optional) transaction id to trace execution through call chain.
+// - item              an item to be created.
+// Returns          (optional)  created item or error.
+func (c *SqlitePersistence[T]) Create(correlationId string, item T) (result T, err error) {
+	value, err := c.SqlitePersistence.Create(correlationId, item)
+	return toTyped[T](value), err
+}
+
+// Gets a view of the persistence that includes soft-deleted items in reads.
+// Returns a persistence view.
+func (c *SqlitePersistence[T]) WithDeleted() *SqliteDeletedView[T] {
+	return &SqliteDeletedView[T]{
+		SqliteDeletedView: c.SqlitePersistence.WithDeleted(),
+	}
+}
+
+/*
+View of a typed persistence that includes soft-deleted items in reads.
+It wraps persist.SqliteDeletedView and returns typed items and data pages.
+*/
+type SqliteDeletedView[T any] struct {
+	*persist.SqliteDeletedView
+}
+
+// Gets a page of data items including soft-deleted ones, see SqlitePersistence.GetPageByFilter.
+func (c *SqliteDeletedView[T]) GetPageByFilter(correlationId string, filter interface{}, paging *cdata.PagingParams,
+	sort interface{}, sel interface{}) (page *DataPage[T], err error) {
+	result, err := c.SqliteDeletedView.GetPageByFilter(correlationId, filter, paging, sort, sel)
+	return toTypedPage[T](result), err
+}
+
+// Gets a list of data items including soft-deleted ones, see SqlitePersistence.GetListByFilter.
+func (c *SqliteDeletedView[T]) GetListByFilter(correlationId string, filter interface{}, sort interface{}, sel interface{}) (items []T, err error) {
+	result, err := c.SqliteDeletedView.GetListByFilter(correlationId, filter, sort, sel)
+	return toTypedList[T](result), err
+}
+
+// Gets a random item including soft-deleted ones, see SqlitePersistence.GetOneRandom.
+func (c *SqliteDeletedView[T]) GetOneRandom(correlationId string, filter interface{}) (item T, err error) {
+	result, err := c.SqliteDeletedView.GetOneRandom(correlationId, filter)
+	return toTyped[T](result), err
+}
+
+// Gets a random sample of items including soft-deleted ones, see SqlitePersistence.GetRandomSample.
+func (c *SqliteDeletedView[T]) GetRandomSample(correlationId string, filter interface{}, size int) (items []T, err error) {
+	result, err := c.SqliteDeletedView.GetRandomSample(correlationId, filter, size)
+	return toTypedList[T](result), err
+}
+
+// Searches items including soft-deleted ones by text, see SqlitePersistence.SearchByText.
+func (c *SqliteDeletedView[T]) SearchByText(correlationId string, query string, filter interface{},
+	paging *cdata.PagingParams) (page *TextSearchPage[T], err error) {
+	result, err := c.SqliteDeletedView.SearchByText(correlationId, query, filter, paging)
+	return toTypedTextSearchPage[T](result), err
+}
@@ -0,0 +1,48 @@
+package generic
+
+import (
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+)
+
+/*
+Typed data item found by a full-text search.
+*/
+type TextSearchResult[T any] struct {
+	// The found data item.
+	Item T `json:"item"`
+	// The bm25 score of the item. Lower scores are better matches.
+	Score float64 `json:"score"`
+	// The fragment of the best matching field with matched terms highlighted.
+	Snippet string `json:"snippet"`
+	// The indexed fields with matched terms highlighted.
+	Highlights map[string]string `json:"highlights"`
+}
+
+/*
+Page of typed data items found by a full-text search in the order of their ranks.
+*/
+type TextSearchPage[T any] struct {
+	// The total number of found items when it was requested by paging parameters.
+	Total *int64 `json:"total"`
+	// The found items.
+	Data []*TextSearchResult[T] `json:"data"`
+}
+
+func toTypedTextSearchPage[T any](page *persist.SqliteTextSearchPage) *TextSearchPage[T] {
+	if page == nil {
+		return nil
+	}
+	result := &TextSearchPage[T]{
+		Total: page.Total,
+		Data:  make([]*TextSearchResult[T], len(page.Data)),
+	}
+	for index, item := range page.Data {
+		result.Data[index] = &TextSearchResult[T]{
+			Item:       toTyped[T](item.Item),
+			Score:      item.Score,
+			Snippet:    item.Snippet,
+			Highlights: item.Highlights,
+		}
+	}
+	return result
+}
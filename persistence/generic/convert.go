@@ -0,0 +1,62 @@
+package generic
+
+import (
+	"reflect"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+// Gets the prototype of data items with type T.
+func prototypeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Converts a data item returned by untyped persistence to type T.
+// Values and pointers to values are converted to each other.
+func toTyped[T any](value interface{}) T {
+	var result T
+	if value == nil {
+		return result
+	}
+	if item, ok := value.(T); ok {
+		return item
+	}
+
+	source := reflect.ValueOf(value)
+	target := prototypeOf[T]()
+	if source.Kind() == reflect.Ptr && !source.IsNil() && source.Elem().Type() == target {
+		return source.Elem().Interface().(T)
+	}
+	if target.Kind() == reflect.Ptr && source.Type() == target.Elem() {
+		pointer := reflect.New(source.Type())
+		pointer.Elem().Set(source)
+		return pointer.Interface().(T)
+	}
+	return result
+}
+
+// Converts a list of data items returned by untyped persistence to type T.
+func toTypedList[T any](values []interface{}) []T {
+	items := make([]T, len(values))
+	for index, value := range values {
+		items[index] = toTyped[T](value)
+	}
+	return items
+}
+
+// Converts a data page returned by untyped persistence to type T.
+func toTypedPage[T any](page *cdata.DataPage) *DataPage[T] {
+	if page == nil {
+		return nil
+	}
+	return NewDataPage(page.Total, toTypedList[T](page.Data))
+}
+
+// Converts a list of typed ids to the list accepted by untyped persistence.
+func toUntypedIds[K any](ids []K) []interface{} {
+	values := make([]interface{}, len(ids))
+	for index, id := range ids {
+		values[index] = id
+	}
+	return values
+}
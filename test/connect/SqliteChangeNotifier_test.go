@@ -0,0 +1,183 @@
+package test_connect
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveChange(t *testing.T, changes chan *conn.SqliteChangeEvent) *conn.SqliteChangeEvent {
+	t.Helper()
+	select {
+	case event := <-changes:
+		return event
+	case <-time.After(time.Second):
+		t.Error("Change was not delivered")
+		return nil
+	}
+}
+
+func TestSqliteChangeNotifier(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	connection := conn.NewSqliteConnection()
+	connection.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	))
+	_, err := connection.Subscribe("", "", "", func(event *conn.SqliteChangeEvent) {})
+	assert.NotNil(t, err)
+
+	connection = conn.NewSqliteConnection()
+	connection.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.notifications", true,
+	))
+	err = connection.Open("")
+	assert.Nil(t, err)
+	defer connection.Close("")
+
+	client := connection.GetConnection()
+	_, err = client.Exec("CREATE TABLE IF NOT EXISTS change_notifications (\"id\" INTEGER PRIMARY KEY, \"name\" TEXT)")
+	assert.Nil(t, err)
+	_, err = client.Exec("DELETE FROM change_notifications")
+	assert.Nil(t, err)
+
+	changes := make(chan *conn.SqliteChangeEvent, 10)
+	subscription, err := connection.SubscribeChannel("", "change_notifications", "", changes)
+	assert.Nil(t, err)
+	deletes := make(chan *conn.SqliteChangeEvent, 10)
+	_, err = connection.SubscribeChannel("", "change_notifications", conn.SqliteChangeDelete, deletes)
+	assert.Nil(t, err)
+
+	// Changes are delivered after commits
+	_, err = client.Exec("INSERT INTO change_notifications (\"id\", \"name\") VALUES (1, 'A')")
+	assert.Nil(t, err)
+	event := receiveChange(t, changes)
+	assert.Equal(t, conn.SqliteChangeInsert, event.Operation)
+	assert.Equal(t, "change_notifications", event.Table)
+	assert.Equal(t, int64(1), event.RowId)
+
+	// Changes of rolled back transactions are dropped
+	tx, err := client.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("INSERT INTO change_notifications (\"id\", \"name\") VALUES (2, 'B')")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Rollback())
+
+	tx, err = client.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("UPDATE change_notifications SET \"name\"='C' WHERE \"id\"=1")
+	assert.Nil(t, err)
+	_, err = tx.Exec("DELETE FROM change_notifications WHERE \"id\"=1")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	event = receiveChange(t, changes)
+	assert.Equal(t, conn.SqliteChangeUpdate, event.Operation)
+	assert.Equal(t, int64(1), event.RowId)
+	event = receiveChange(t, changes)
+	assert.Equal(t, conn.SqliteChangeDelete, event.Operation)
+	assert.Equal(t, int64(1), event.RowId)
+	event = receiveChange(t, deletes)
+	assert.Equal(t, conn.SqliteChangeDelete, event.Operation)
+
+	// Unsubscribed listeners receive nothing
+	subscription.Unsubscribe()
+	_, err = client.Exec("INSERT INTO change_notifications (\"id\", \"name\") VALUES (3, 'D')")
+	assert.Nil(t, err)
+	_, err = client.Exec("DELETE FROM change_notifications WHERE \"id\"=3")
+	assert.Nil(t, err)
+	receiveChange(t, deletes)
+
+	assert.Len(t, changes, 0)
+}
+
+func TestSqliteChangeNotifierUndoneChanges(t *testing.T) {
+	// Locks are taken by another connection, so the database is not shared with other tests
+	sqliteDatabase := filepath.Join(t.TempDir(), "notifications.db")
+
+	connection := conn.NewSqliteConnection()
+	connection.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase+"?_busy_timeout=10",
+		"options.notifications", true,
+	))
+	err := connection.Open("")
+	assert.Nil(t, err)
+	defer connection.Close("")
+
+	client := connection.GetConnection()
+	_, err = client.Exec("CREATE TABLE undone_changes (\"id\" INTEGER PRIMARY KEY, \"name\" TEXT NOT NULL)")
+	assert.Nil(t, err)
+
+	changes := make(chan *conn.SqliteChangeEvent, 10)
+	_, err = connection.SubscribeChannel("", "undone_changes", "", changes)
+	assert.Nil(t, err)
+
+	// Changes of failed statements and rolled back savepoints are dropped
+	tx, err := client.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("INSERT INTO undone_changes (\"id\", \"name\") VALUES (10, 'a'), (11, NULL)")
+	assert.NotNil(t, err)
+	_, err = tx.Exec("SAVEPOINT sp")
+	assert.Nil(t, err)
+	_, err = tx.Exec("INSERT INTO undone_changes (\"id\", \"name\") VALUES (20, 'b')")
+	assert.Nil(t, err)
+	_, err = tx.Exec("ROLLBACK TO sp")
+	assert.Nil(t, err)
+	_, err = tx.Exec("SAVEPOINT kept")
+	assert.Nil(t, err)
+	stmt, err := tx.Prepare("INSERT INTO undone_changes (\"id\", \"name\") VALUES (?1, ?2)")
+	assert.Nil(t, err)
+	_, err = stmt.Exec(30, "c")
+	assert.Nil(t, err)
+	stmt.Close()
+	_, err = tx.Exec("RELEASE kept")
+	assert.Nil(t, err)
+	_, err = tx.Exec("RELEASE sp")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	event := receiveChange(t, changes)
+	assert.Equal(t, conn.SqliteChangeInsert, event.Operation)
+	assert.Equal(t, int64(30), event.RowId)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, changes, 0)
+
+	// Changes are delivered only when COMMIT succeeds
+	reader, err := sql.Open("sqlite3", sqliteDatabase)
+	assert.Nil(t, err)
+	defer reader.Close()
+	readerTx, err := reader.Begin()
+	assert.Nil(t, err)
+	rows, err := readerTx.Query("SELECT \"id\" FROM undone_changes")
+	assert.Nil(t, err)
+	assert.True(t, rows.Next())
+
+	tx, err = client.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("INSERT INTO undone_changes (\"id\", \"name\") VALUES (40, 'd')")
+	assert.Nil(t, err)
+	assert.NotNil(t, tx.Commit())
+
+	rows.Close()
+	assert.Nil(t, readerTx.Rollback())
+
+	_, err = client.Exec("INSERT INTO undone_changes (\"id\", \"name\") VALUES (50, 'e')")
+	assert.Nil(t, err)
+	event = receiveChange(t, changes)
+	assert.Equal(t, int64(50), event.RowId)
+
+	var count int
+	err = client.QueryRow("SELECT COUNT(*) FROM undone_changes").Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}
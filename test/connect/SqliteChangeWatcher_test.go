@@ -0,0 +1,126 @@
+package test_connect
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveTableChange(t *testing.T, changes chan *conn.SqliteTableChangeEvent) *conn.SqliteTableChangeEvent {
+	t.Helper()
+	select {
+	case event := <-changes:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Error("Change was not detected")
+		return &conn.SqliteTableChangeEvent{}
+	}
+}
+
+func TestSqliteChangeWatcher(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	// The writer simulates another process with its own connection
+	writer := conn.NewSqliteConnection()
+	writer.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	))
+	err := writer.Open("")
+	assert.Nil(t, err)
+	defer writer.Close("")
+	client := writer.GetConnection()
+	for _, table := range []string{"watched_a", "watched_b"} {
+		_, err = client.Exec("CREATE TABLE IF NOT EXISTS " + table + " (\"id\" INTEGER PRIMARY KEY, \"name\" TEXT)")
+		assert.Nil(t, err)
+	}
+
+	t.Run("Tables", func(t *testing.T) {
+		watcher := conn.NewSqliteChangeWatcher()
+		watcher.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"options.interval", 20,
+			"options.tables", "watched_a, watched_b",
+		))
+		all := make(chan *conn.SqliteTableChangeEvent, 10)
+		watcher.Subscribe("", func(event *conn.SqliteTableChangeEvent) { all <- event })
+		tableA := make(chan *conn.SqliteTableChangeEvent, 10)
+		watcher.Subscribe("watched_a", func(event *conn.SqliteTableChangeEvent) { tableA <- event })
+
+		err := watcher.Open("")
+		assert.Nil(t, err)
+		defer watcher.Close("")
+
+		_, err = client.Exec("INSERT INTO watched_b (\"name\") VALUES ('B')")
+		assert.Nil(t, err)
+		assert.Equal(t, "watched_b", receiveTableChange(t, all).Table)
+
+		_, err = client.Exec("INSERT INTO watched_a (\"name\") VALUES ('A')")
+		assert.Nil(t, err)
+		event := receiveTableChange(t, tableA)
+		assert.Equal(t, "watched_a", event.Table)
+		assert.Greater(t, event.Version, int64(0))
+		assert.Equal(t, "watched_a", receiveTableChange(t, all).Table)
+		assert.Len(t, tableA, 0)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		watcher := conn.NewSqliteChangeWatcher()
+		watcher.Connection = writer
+		watcher.Interval = 20
+		changes := make(chan *conn.SqliteTableChangeEvent, 10)
+		subscription := watcher.Subscribe("", func(event *conn.SqliteTableChangeEvent) { changes <- event })
+
+		err := watcher.Open("")
+		assert.Nil(t, err)
+		defer watcher.Close("")
+
+		_, err = client.Exec("DELETE FROM watched_a WHERE \"id\">0")
+		assert.Nil(t, err)
+		assert.Equal(t, "", receiveTableChange(t, changes).Table)
+
+		subscription.Unsubscribe()
+		_, err = client.Exec("DELETE FROM watched_b WHERE \"id\">0")
+		assert.Nil(t, err)
+		time.Sleep(100 * time.Millisecond)
+		assert.Len(t, changes, 0)
+	})
+
+	t.Run("SingleConnectionPool", func(t *testing.T) {
+		shared := conn.NewSqliteConnection()
+		shared.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+		))
+		err := shared.Open("")
+		assert.Nil(t, err)
+		defer shared.Close("")
+		shared.GetConnection().SetMaxOpenConns(1)
+
+		watcher := conn.NewSqliteChangeWatcher()
+		watcher.Connection = shared
+		watcher.Interval = 20
+		changes := make(chan *conn.SqliteTableChangeEvent, 10)
+		watcher.Subscribe("", func(event *conn.SqliteTableChangeEvent) { changes <- event })
+
+		err = watcher.Open("")
+		assert.Nil(t, err)
+		defer watcher.Close("")
+
+		// The watcher doesn't hold the only connection of the pool
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, err = shared.GetConnection().ExecContext(ctx, "INSERT INTO watched_a (\"name\") VALUES ('C')")
+		assert.Nil(t, err)
+		assert.Equal(t, "", receiveTableChange(t, changes).Table)
+	})
+
+	// The shared connection stays opened
+	assert.True(t, writer.IsOpen())
+}
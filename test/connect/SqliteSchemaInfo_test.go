@@ -0,0 +1,98 @@
+package test_connect
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteSchemaInfo(t *testing.T) {
+	var connection *conn.SqliteConnection
+
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	)
+
+	connection = conn.NewSqliteConnection()
+	connection.Configure(dbConfig)
+	err := connection.Open("")
+	assert.Nil(t, err)
+	defer connection.Close("")
+
+	_, err = connection.GetConnection().Exec(`
+		DROP VIEW IF EXISTS schema_children_v;
+		DROP TABLE IF EXISTS schema_children;
+		DROP TABLE IF EXISTS schema_parents;
+		CREATE TABLE schema_parents (id TEXT PRIMARY KEY, name TEXT);
+		CREATE TABLE schema_children (
+			id INTEGER PRIMARY KEY,
+			parent_id TEXT NOT NULL REFERENCES schema_parents(id) ON DELETE CASCADE,
+			name TEXT DEFAULT 'unknown',
+			upper_name TEXT GENERATED ALWAYS AS (UPPER(name)) VIRTUAL
+		);
+		CREATE UNIQUE INDEX schema_children_name ON schema_children (parent_id, name DESC) WHERE name IS NOT NULL;
+		CREATE TRIGGER schema_children_touch AFTER UPDATE ON schema_children BEGIN SELECT 1; END;
+		CREATE VIEW schema_children_v AS SELECT id, name FROM schema_children;
+	`)
+	assert.Nil(t, err)
+
+	tables, err := connection.GetTables("")
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	assert.Contains(t, names, "schema_parents")
+	assert.Contains(t, names, "schema_children")
+
+	views, err := connection.GetViews("")
+	assert.Nil(t, err)
+	names = make([]string, 0)
+	for _, view := range views {
+		names = append(names, view.Name)
+	}
+	assert.Contains(t, names, "schema_children_v")
+
+	columns, err := connection.GetColumns("", "schema_children")
+	assert.Nil(t, err)
+	assert.Len(t, columns, 4)
+	assert.Equal(t, "id", columns[0].Name)
+	assert.Equal(t, "INTEGER", columns[0].Type)
+	assert.Equal(t, 1, columns[0].PrimaryKey)
+	assert.True(t, columns[1].NotNull)
+	assert.NotNil(t, columns[2].DefaultValue)
+	assert.Equal(t, "'unknown'", *columns[2].DefaultValue)
+	assert.True(t, columns[3].Generated)
+
+	indexes, err := connection.GetIndexes("", "schema_children")
+	assert.Nil(t, err)
+	assert.Len(t, indexes, 1)
+	assert.Equal(t, "schema_children_name", indexes[0].Name)
+	assert.True(t, indexes[0].Unique)
+	assert.True(t, indexes[0].Partial)
+	assert.Equal(t, "name IS NOT NULL", indexes[0].Predicate)
+	assert.Len(t, indexes[0].Columns, 2)
+	assert.Equal(t, "parent_id", indexes[0].Columns[0].Name)
+	assert.True(t, indexes[0].Columns[1].Desc)
+
+	triggers, err := connection.GetTriggers("", "schema_children")
+	assert.Nil(t, err)
+	assert.Len(t, triggers, 1)
+	assert.Equal(t, "schema_children_touch", triggers[0].Name)
+
+	keys, err := connection.GetForeignKeys("", "schema_children")
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "schema_parents", keys[0].Table)
+	assert.Equal(t, []string{"parent_id"}, keys[0].From)
+	assert.Equal(t, []string{"id"}, keys[0].To)
+	assert.Equal(t, "CASCADE", keys[0].OnDelete)
+}
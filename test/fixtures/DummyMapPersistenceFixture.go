@@ -170,11 +170,12 @@ func (c *DummyMapPersistenceFixture) TestBatchOperations(t *testing.T) {
 	assert.Len(t, items, 2)
 
 	// Delete batch
-	err = c.persistence.DeleteByIds("", []string{dummy1["id"].(string), dummy2["id"].(string)})
+	count, err := c.persistence.DeleteByIds("", []string{dummy1["id"].(string), dummy2["id"].(string)})
 	if err != nil {
 		t.Errorf("DeleteByIds method error %v", err)
 	}
 	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
 
 	// Read empty batch
 	items, err = c.persistence.GetListByIds("", []string{dummy1["id"].(string), dummy2["id"].(string)})
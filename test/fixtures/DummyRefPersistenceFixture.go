@@ -169,11 +169,12 @@ func (c *DummyRefPersistenceFixture) TestBatchOperations(t *testing.T) {
 	assert.Len(t, items, 2)
 
 	// Delete batch
-	err = c.persistence.DeleteByIds("", []string{dummy1.Id, dummy2.Id})
+	count, err := c.persistence.DeleteByIds("", []string{dummy1.Id, dummy2.Id})
 	if err != nil {
 		t.Errorf("DeleteByIds method error %v", err)
 	}
 	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
 
 	// Read empty batch
 	items, err = c.persistence.GetListByIds("", []string{dummy1.Id, dummy2.Id})
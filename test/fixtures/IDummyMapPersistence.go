@@ -12,6 +12,6 @@ type IDummyMapPersistence interface {
 	Set(correlationId string, item map[string]interface{}) (result map[string]interface{}, err error)
 	UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (item map[string]interface{}, err error)
 	DeleteById(correlationId string, id string) (item map[string]interface{}, err error)
-	DeleteByIds(correlationId string, ids []string) (err error)
+	DeleteByIds(correlationId string, ids []string) (count int64, err error)
 	GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error)
 }
@@ -12,6 +12,6 @@ type IDummyRefPersistence interface {
 	Set(correlationId string, item *Dummy) (result *Dummy, err error)
 	UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (item *Dummy, err error)
 	DeleteById(correlationId string, id string) (item *Dummy, err error)
-	DeleteByIds(correlationId string, ids []string) (err error)
+	DeleteByIds(correlationId string, ids []string) (count int64, err error)
 	GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error)
 }
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyAggregatesSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyJsonPatchSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_aggregates",
+	))
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	for _, item := range []map[string]interface{}{
+		{"id": "1", "category": "books", "price": 10, "qty": 1},
+		{"id": "2", "category": "books", "price": 20, "qty": 3},
+		{"id": "3", "category": "games", "price": 50.5, "qty": 1},
+		{"id": "4", "category": "toys", "price": 5, "qty": 2},
+		{"id": "5", "category": "toys", "price": 15, "qty": 2},
+		{"id": "6", "category": "toys", "price": 25, "qty": 2},
+	} {
+		_, err = persistence.Create("", item)
+		assert.Nil(t, err)
+	}
+
+	aggregates := []*persist.SqliteAggregate{
+		{Function: persist.AggregateCount},
+		{Function: persist.AggregateSum, Field: "$.price"},
+		{Function: persist.AggregateAvg, Field: "$.price", Alias: "avg_price"},
+		{Function: persist.AggregateMin, Field: "$.price"},
+		{Function: persist.AggregateMax, Field: "$.price"},
+		{Function: persist.AggregateCountDistinct, Field: "$.qty"},
+	}
+	rows, err := persistence.GetAggregatesByFilter("", "", []string{"$.category"}, aggregates, nil)
+	assert.Nil(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "books", rows[0].Groups.GetAsString("$.category"))
+	assert.Equal(t, int64(2), rows[0].Values.GetAsLong("count"))
+	assert.Equal(t, int64(30), rows[0].Values.GetAsLong("sum_price"))
+	assert.Equal(t, 15.0, rows[0].Values.GetAsDouble("avg_price"))
+	assert.Equal(t, int64(10), rows[0].Values.GetAsLong("min_price"))
+	assert.Equal(t, int64(20), rows[0].Values.GetAsLong("max_price"))
+	assert.Equal(t, int64(2), rows[0].Values.GetAsLong("count_distinct_qty"))
+	assert.Equal(t, 50.5, rows[1].Values.GetAsDouble("sum_price"))
+	assert.Equal(t, "toys", rows[2].Groups.GetAsString("$.category"))
+	assert.Equal(t, int64(1), rows[2].Values.GetAsLong("count_distinct_qty"))
+
+	// Conditions filter groups
+	rows, err = persistence.GetAggregatesByFilter("", "JSON_EXTRACT(data, '$.qty') > 1", []string{"$.category", "$.qty"},
+		[]*persist.SqliteAggregate{{Function: persist.AggregateCount}, {Function: persist.AggregateSum, Field: "$.price"}},
+		[]*persist.SqliteAggregateCondition{{Alias: "sum_price", Operator: ">=", Value: 40}})
+	assert.Nil(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "toys", rows[0].Groups.GetAsString("$.category"))
+	assert.Equal(t, int64(2), rows[0].Groups.GetAsLong("$.qty"))
+	assert.Equal(t, int64(3), rows[0].Values.GetAsLong("count"))
+
+	// Aggregates without groups cover all items
+	rows, err = persistence.GetAggregatesByFilter("", "", nil,
+		[]*persist.SqliteAggregate{{Function: persist.AggregateCountDistinct, Field: "id"}}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, int64(6), rows[0].Values.GetAsLong("count_distinct_id"))
+
+	_, err = persistence.GetAggregatesByFilter("", "", nil,
+		[]*persist.SqliteAggregate{{Function: "median", Field: "$.price"}}, nil)
+	assert.Equal(t, "INVALID_AGGREGATE", err.(*cerr.ApplicationError).Code)
+	_, err = persistence.GetAggregatesByFilter("", "", nil, aggregates,
+		[]*persist.SqliteAggregateCondition{{Alias: "count", Operator: "; DROP", Value: 1}})
+	assert.Equal(t, "INVALID_AGGREGATE", err.(*cerr.ApplicationError).Code)
+}
+
+func TestDummyAggregatesCodecSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyCodecSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_aggregates_codec",
+		"options.codec", "gzip",
+	))
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	for _, key := range []string{"Key 1", "Key 2", "Key 3"} {
+		_, err = persistence.Create("", &tf.Dummy{Key: key, Content: "Content"})
+		assert.Nil(t, err)
+	}
+
+	// Declared paths are read from their columns
+	rows, err := persistence.GetAggregatesByFilter("", "", []string{"$.key"},
+		[]*persist.SqliteAggregate{{Function: persist.AggregateCount}, {Function: persist.AggregateMax, Field: "$.key"}}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Key 1", rows[0].Groups.GetAsString("$.key"))
+	assert.Equal(t, int64(1), rows[0].Values.GetAsLong("count"))
+	assert.Equal(t, "Key 3", rows[2].Values.GetAsString("max_key"))
+
+	// Other paths can't be read from encoded documents
+	_, err = persistence.GetAggregatesByFilter("", "", []string{"$.content"},
+		[]*persist.SqliteAggregate{{Function: persist.AggregateCount}}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_FIELD", err.(*cerr.ApplicationError).Code)
+}
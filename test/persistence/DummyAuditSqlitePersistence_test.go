@@ -0,0 +1,126 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeAuditSnapshot(t *testing.T, snapshot json.RawMessage) *tf.Dummy {
+	if snapshot == nil {
+		return nil
+	}
+	dummy := &tf.Dummy{}
+	assert.Nil(t, json.Unmarshal(snapshot, dummy))
+	return dummy
+}
+
+func TestDummyAuditSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummySqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_audited",
+		"options.audit", true,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+	assert.Equal(t, "dummies_audited_audit", persistence.AuditTableName)
+	_, err = persistence.Client.Exec("DELETE FROM dummies_audited_audit")
+	assert.Nil(t, err)
+
+	dummy, err := persistence.Create("123", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	dummy.Content = "Content 2"
+	_, err = persistence.Update("123", dummy)
+	assert.Nil(t, err)
+	_, err = persistence.UpdatePartially("123", dummy.Id, cdata.NewAnyValueMapFromTuples("content", "Content 3"))
+	assert.Nil(t, err)
+	dummy.Content = "Content 4"
+	_, err = persistence.Set("456", dummy)
+	assert.Nil(t, err)
+	_, err = persistence.DeleteById("456", dummy.Id)
+	assert.Nil(t, err)
+
+	history, err := persistence.GetHistoryById("", dummy.Id)
+	assert.Nil(t, err)
+	assert.Len(t, history, 5)
+
+	operations := []string{
+		persist.AuditOperationCreate,
+		persist.AuditOperationUpdate,
+		persist.AuditOperationUpdatePartially,
+		persist.AuditOperationSet,
+		persist.AuditOperationDelete,
+	}
+	contents := []string{"", "Content 1", "Content 2", "Content 3", "Content 4", ""}
+	for index, entry := range history {
+		assert.Equal(t, operations[index], entry.Operation)
+		assert.Equal(t, dummy.Id, entry.EntityId)
+		assert.False(t, entry.Time.IsZero())
+
+		before := decodeAuditSnapshot(t, entry.Before)
+		after := decodeAuditSnapshot(t, entry.After)
+		if contents[index] == "" {
+			assert.Nil(t, before)
+		} else {
+			assert.Equal(t, contents[index], before.Content)
+		}
+		if contents[index+1] == "" {
+			assert.Nil(t, after)
+		} else {
+			assert.Equal(t, contents[index+1], after.Content)
+		}
+	}
+	assert.Equal(t, "123", history[0].CorrelationId)
+	assert.Equal(t, "456", history[4].CorrelationId)
+
+	// Deletes of many items record every deleted item
+	dummy1, _ := persistence.Create("", tf.Dummy{Key: "Key 2", Content: "Content 1"})
+	dummy2, _ := persistence.Create("", tf.Dummy{Key: "Key 3", Content: "Content 2"})
+	dummy3, _ := persistence.Create("", tf.Dummy{Key: "Key 4", Content: "Content 3"})
+
+	count, err := persistence.DeleteByIds("", []string{dummy1.Id, dummy2.Id, "unknown"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	err = persistence.DeleteByFilter("", "\"key\"='Key 4'")
+	assert.Nil(t, err)
+
+	for _, deleted := range []tf.Dummy{dummy1, dummy2, dummy3} {
+		history, err = persistence.GetHistoryById("", deleted.Id)
+		assert.Nil(t, err)
+		assert.Len(t, history, 2)
+		assert.Equal(t, persist.AuditOperationDelete, history[1].Operation)
+		assert.Equal(t, deleted, *decodeAuditSnapshot(t, history[1].Before))
+		assert.Nil(t, history[1].After)
+	}
+
+	history, err = persistence.GetHistoryById("", "unknown")
+	assert.Nil(t, err)
+	assert.Len(t, history, 0)
+
+	// Changes are rolled back when the audit entry cannot be written
+	_, err = persistence.Client.Exec("DROP TABLE dummies_audited_audit")
+	assert.Nil(t, err)
+
+	_, err = persistence.Create("", tf.Dummy{Id: "rollback", Key: "Key 5", Content: "Content 5"})
+	assert.NotNil(t, err)
+
+	item, err := persistence.GetOneById("", "rollback")
+	assert.Nil(t, err)
+	assert.Equal(t, "", item.Id)
+}
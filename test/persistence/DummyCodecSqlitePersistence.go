@@ -0,0 +1,22 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyCodecSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[*tf.Dummy, string]
+}
+
+func NewDummyCodecSqlitePersistence() *DummyCodecSqlitePersistence {
+	c := &DummyCodecSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[*tf.Dummy, string](c, "dummies_codec")
+	return c
+}
+
+func (c *DummyCodecSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+	c.EnsureJsonField("$.key", "TEXT", true, true)
+}
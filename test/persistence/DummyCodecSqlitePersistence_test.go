@@ -0,0 +1,160 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// Stores JSON documents reversed in BLOBs
+type testReversedCodec struct{}
+
+func (c *testReversedCodec) Encode(doc interface{}) (interface{}, error) {
+	buf, err := json.Marshal(doc)
+	return reverseBytes(buf), err
+}
+
+func (c *testReversedCodec) Decode(data interface{}, target interface{}) error {
+	buf, ok := data.([]byte)
+	if !ok {
+		return errors.New("document is not a BLOB")
+	}
+	return json.Unmarshal(reverseBytes(buf), target)
+}
+
+func (c *testReversedCodec) IsJson() bool {
+	return false
+}
+
+func reverseBytes(buf []byte) []byte {
+	result := make([]byte, len(buf))
+	for index, value := range buf {
+		result[len(buf)-index-1] = value
+	}
+	return result
+}
+
+func TestDummyCodecSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	for _, codec := range []string{"gzip", "gob", "custom"} {
+		t.Run(codec, func(t *testing.T) {
+			table := "dummies_codec_" + codec
+			persistence := NewDummyCodecSqlitePersistence()
+			config := cconf.NewConfigParamsFromTuples(
+				"connection.database", sqliteDatabase,
+				"table", table,
+			)
+			if codec == "custom" {
+				persistence.DocumentCodec = &testReversedCodec{}
+			} else {
+				config.SetAsObject("options.codec", codec)
+			}
+			persistence.Configure(config)
+
+			err := persistence.Open("")
+			assert.Nil(t, err)
+			defer persistence.Close("")
+			persistence.Clear("")
+
+			dummy1, err := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Content 1"})
+			assert.Nil(t, err)
+			dummy2, err := persistence.Create("", &tf.Dummy{Key: "Key 2", Content: "Content 2"})
+			assert.Nil(t, err)
+
+			var dataType string
+			err = persistence.Client.QueryRow("SELECT TYPEOF(data) FROM "+table+" WHERE id=?1", dummy1.Id).Scan(&dataType)
+			assert.Nil(t, err)
+			assert.Equal(t, "blob", dataType)
+
+			item, err := persistence.GetOneById("", dummy1.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, dummy1, item)
+
+			// Extracted fields stay queryable and unique
+			items, err := persistence.GetListByFilter("", "\"key\"='Key 2'", nil, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []*tf.Dummy{dummy2}, items)
+			_, err = persistence.Create("", &tf.Dummy{Key: "Key 1"})
+			assert.NotNil(t, err)
+
+			item, err = persistence.UpdatePartially("", dummy1.Id, cdata.NewAnyValueMapFromTuples("key", "Key 3"))
+			assert.Nil(t, err)
+			assert.Equal(t, &tf.Dummy{Id: dummy1.Id, Key: "Key 3", Content: "Content 1"}, item)
+			items, err = persistence.GetListByFilter("", "\"key\"='Key 3'", nil, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []*tf.Dummy{item}, items)
+
+			dummy2.Content = "Content 3"
+			item, err = persistence.Update("", dummy2)
+			assert.Nil(t, err)
+			assert.Equal(t, dummy2, item)
+
+			_, err = persistence.PatchById("", dummy1.Id, []*persist.SqliteJsonPatchOperation{
+				{Op: persist.JsonPatchSet, Path: "$.content", Value: "Content 4"},
+			})
+			assert.NotNil(t, err)
+			assert.Equal(t, "NOT_SUPPORTED", err.(*cerr.ApplicationError).Code)
+		})
+	}
+}
+
+func TestDummyCodecSqlitePersistenceStrictSchema(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_codec_strict",
+		"options.codec", "gzip",
+		"options.auto_migrate", true,
+		"options.strict_schema", true,
+	)
+
+	persistence := NewDummyCodecSqlitePersistence()
+	persistence.Configure(config)
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	// Stored columns of JSON fields are not reported as extra columns
+	persistence = NewDummyCodecSqlitePersistence()
+	persistence.Configure(config)
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	drifts, err := persistence.DetectSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 0)
+}
+
+func TestGzipDocumentCodec(t *testing.T) {
+	codec := persist.NewGzipDocumentCodec(9)
+	doc := map[string]interface{}{"content": string(bytes.Repeat([]byte("abc"), 100))}
+	data, err := codec.Encode(doc)
+	assert.Nil(t, err)
+	assert.Less(t, len(data.([]byte)), 100)
+
+	result := map[string]interface{}{}
+	assert.Nil(t, codec.Decode(data, &result))
+	assert.Equal(t, doc, result)
+
+	// Uncompressed documents are still read
+	result = map[string]interface{}{}
+	assert.Nil(t, codec.Decode(`{"content":"abc"}`, &result))
+	assert.Equal(t, "abc", result["content"])
+}
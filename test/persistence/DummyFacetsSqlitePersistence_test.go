@@ -0,0 +1,114 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyFacetsSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyJsonPatchSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_facets",
+	))
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	for _, item := range []map[string]interface{}{
+		{"id": "1", "key": "a", "status": "new"},
+		{"id": "2", "key": "a", "status": "done"},
+		{"id": "3", "key": "b", "status": "done"},
+		{"id": "4", "key": "c", "status": "done"},
+		{"id": "5", "key": "c", "status": "new"},
+		{"id": "6", "key": "c"},
+	} {
+		_, err = persistence.Create("", item)
+		assert.Nil(t, err)
+	}
+
+	values, err := persistence.GetDistinctValues("", "$.status", "")
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, "done", values[0].Value)
+	assert.Equal(t, int64(3), values[0].Count)
+	assert.Equal(t, "new", values[1].Value)
+	assert.Equal(t, int64(2), values[1].Count)
+
+	values, err = persistence.GetDistinctValues("", "id", "JSON_EXTRACT(data, '$.key')='c'")
+	assert.Nil(t, err)
+	assert.Len(t, values, 3)
+	assert.Equal(t, "4", values[0].Value)
+
+	facets, err := persistence.GetFacets("", "", []string{"$.key", "$.status"}, 2)
+	assert.Nil(t, err)
+	assert.Len(t, facets, 2)
+	assert.Len(t, facets["$.key"], 2)
+	assert.Equal(t, "c", facets["$.key"][0].Value)
+	assert.Equal(t, int64(3), facets["$.key"][0].Count)
+	assert.Equal(t, "a", facets["$.key"][1].Value)
+	assert.Equal(t, int64(2), facets["$.key"][1].Count)
+	assert.Len(t, facets["$.status"], 2)
+	assert.Equal(t, "done", facets["$.status"][0].Value)
+
+	// Filters apply to all facets
+	facets, err = persistence.GetFacets("", "JSON_EXTRACT(data, '$.status')='new'", []string{"$.key", "id"}, 0)
+	assert.Nil(t, err)
+	assert.Len(t, facets["$.key"], 2)
+	assert.Len(t, facets["id"], 2)
+	assert.Equal(t, int64(1), facets["id"][0].Count)
+
+	_, err = persistence.GetFacets("", "", nil, 0)
+	assert.Equal(t, "INVALID_FACET", err.(*cerr.ApplicationError).Code)
+}
+
+func TestDummyFacetsCodecSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyCodecSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_facets_codec",
+		"options.codec", "gzip",
+	))
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	for _, key := range []string{"Key 1", "Key 2"} {
+		_, err = persistence.Create("", &tf.Dummy{Key: key, Content: "Content"})
+		assert.Nil(t, err)
+	}
+
+	// Declared paths are read from their columns
+	values, err := persistence.GetDistinctValues("", "$.key", "")
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, "Key 1", values[0].Value)
+
+	facets, err := persistence.GetFacets("", "", []string{"$.key"}, 0)
+	assert.Nil(t, err)
+	assert.Len(t, facets["$.key"], 2)
+	assert.Equal(t, int64(1), facets["$.key"][0].Count)
+
+	// Other paths can't be read from encoded documents
+	_, err = persistence.GetDistinctValues("", "$.content", "")
+	assert.Equal(t, "INVALID_FIELD", err.(*cerr.ApplicationError).Code)
+	_, err = persistence.GetFacets("", "", []string{"$.key", "$.content"}, 0)
+	assert.Equal(t, "INVALID_FIELD", err.(*cerr.ApplicationError).Code)
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyFullTextSqlitePersistence struct {
+	gpersist.IdentifiableSqlitePersistence[tf.Dummy, string]
+	tokenizer string
+	fields    []string
+}
+
+func NewDummyFullTextSqlitePersistence(tokenizer string) *DummyFullTextSqlitePersistence {
+	c := &DummyFullTextSqlitePersistence{tokenizer: tokenizer, fields: []string{"key", "content"}}
+	c.IdentifiableSqlitePersistence = *gpersist.InheritIdentifiableSqlitePersistence[tf.Dummy, string](c, "dummies_fts")
+	return c
+}
+
+func (c *DummyFullTextSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50), \"content\" TEXT)")
+	c.EnsureFullTextIndex(c.fields, c.tokenizer)
+}
+
+type DummyFullTextJsonSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[*tf.Dummy, string]
+}
+
+func NewDummyFullTextJsonSqlitePersistence() *DummyFullTextJsonSqlitePersistence {
+	c := &DummyFullTextJsonSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[*tf.Dummy, string](c, "dummies_fts_json")
+	return c
+}
+
+func (c *DummyFullTextJsonSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+	c.EnsureFullTextIndex([]string{"$.content"}, "")
+}
+
+type DummyFullTextCodecSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[*tf.Dummy, string]
+}
+
+func NewDummyFullTextCodecSqlitePersistence() *DummyFullTextCodecSqlitePersistence {
+	c := &DummyFullTextCodecSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[*tf.Dummy, string](c, "dummies_fts_codec")
+	return c
+}
+
+func (c *DummyFullTextCodecSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+	c.EnsureJsonField("$.content", "TEXT", false, false)
+	c.EnsureFullTextIndex([]string{"$.content"}, "")
+}
@@ -0,0 +1,194 @@
+//go:build sqlite_fts5
+// +build sqlite_fts5
+
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyFullTextSqlitePersistence(t *testing.T) {
+	// FTS5 tables are kept in a separate database,
+	// so the shared one stays usable by builds without FTS5
+	sqliteDatabase := filepath.Join(t.TempDir(), "fts.db")
+
+	t.Run("Columns", func(t *testing.T) {
+		persistence := NewDummyFullTextSqlitePersistence("porter unicode61")
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy1, _ := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "SQLite keeps data in a single file"})
+		dummy2, _ := persistence.Create("", tf.Dummy{Key: "Key 2", Content: "Searching files is fast, searching many files is faster"})
+		dummy3, _ := persistence.Create("", tf.Dummy{Key: "Key 3", Content: "Nothing to see here"})
+
+		page, err := persistence.SearchByText("", "files", nil, cdata.NewPagingParams(0, 10, true))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), *page.Total)
+		assert.Len(t, page.Data, 2)
+		// More matches give a better (lower) score
+		assert.Equal(t, dummy2, page.Data[0].Item)
+		assert.Equal(t, dummy1, page.Data[1].Item)
+		assert.Less(t, page.Data[0].Score, page.Data[1].Score)
+		assert.Contains(t, page.Data[1].Snippet, "<b>file</b>")
+		assert.Equal(t, "SQLite keeps data in a single <b>file</b>", page.Data[1].Highlights["content"])
+		assert.Equal(t, "Key 1", page.Data[1].Highlights["key"])
+
+		page, err = persistence.SearchByText("", "files", "\"key\"='Key 1'", nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, dummy1, page.Data[0].Item)
+
+		// Index follows updates and deletes
+		dummy3.Content = "A file appeared here"
+		_, err = persistence.Update("", dummy3)
+		assert.Nil(t, err)
+		_, err = persistence.DeleteById("", dummy2.Id)
+		assert.Nil(t, err)
+
+		page, err = persistence.SearchByText("", "file", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 2)
+
+		page, err = persistence.SearchByText("", "nothing", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 0)
+
+		_, err = persistence.SearchByText("", "\"unbalanced", nil, nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+	})
+
+	t.Run("Trigram", func(t *testing.T) {
+		persistence := NewDummyFullTextSqlitePersistence("trigram")
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_fts_trigram",
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy, _ := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "Substring search"})
+		persistence.Create("", tf.Dummy{Key: "Key 2", Content: "Something else"})
+
+		page, err := persistence.SearchByText("", "bstr", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, dummy, page.Data[0].Item)
+		assert.Equal(t, "Su<b>bstr</b>ing search", page.Data[0].Highlights["content"])
+	})
+
+	t.Run("ChangedFields", func(t *testing.T) {
+		persistence := NewDummyFullTextSqlitePersistence("")
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_fts_fields",
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		persistence.Clear("")
+		dummy, _ := persistence.Create("", tf.Dummy{Key: "Alpha", Content: "Indexed content"})
+		persistence.Close("")
+
+		// The index is rebuilt over existing rows when fields are changed
+		changed := NewDummyFullTextSqlitePersistence("")
+		changed.fields = []string{"content"}
+		changed.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_fts_fields",
+		))
+
+		err = changed.Open("")
+		assert.Nil(t, err)
+		defer changed.Close("")
+
+		page, err := changed.SearchByText("", "alpha", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 0)
+
+		page, err = changed.SearchByText("", "indexed", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, dummy, page.Data[0].Item)
+		assert.Equal(t, map[string]string{"content": "<b>Indexed</b> content"}, page.Data[0].Highlights)
+
+		// Triggers write the new columns
+		dummy.Content = "Updated text"
+		_, err = changed.Update("", dummy)
+		assert.Nil(t, err)
+		changed.Create("", tf.Dummy{Key: "Beta", Content: "Another text"})
+
+		page, err = changed.SearchByText("", "text", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 2)
+	})
+
+	t.Run("Json", func(t *testing.T) {
+		persistence := NewDummyFullTextJsonSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy, _ := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Documents inside JSON"})
+		persistence.Create("", &tf.Dummy{Key: "Key 2", Content: "Plain text"})
+
+		page, err := persistence.SearchByText("", "json", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, dummy, page.Data[0].Item)
+		assert.Equal(t, "Documents inside <b>JSON</b>", page.Data[0].Highlights["$.content"])
+	})
+
+	t.Run("Codec", func(t *testing.T) {
+		persistence := NewDummyFullTextCodecSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"options.codec", "gzip",
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy, _ := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Compressed documents"})
+		persistence.Create("", &tf.Dummy{Key: "Key 2", Content: "Plain text"})
+
+		page, err := persistence.SearchByText("", "compressed", nil, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, dummy, page.Data[0].Item)
+
+		// Undeclared paths can't be indexed in encoded documents
+		undeclared := NewDummyFullTextJsonSqlitePersistence()
+		undeclared.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_fts_codec_undeclared",
+			"options.codec", "gzip",
+		))
+		err = undeclared.Open("")
+		assert.NotNil(t, err)
+		assert.Equal(t, "INVALID_FIELD", err.(*cerr.ApplicationError).Code)
+	})
+}
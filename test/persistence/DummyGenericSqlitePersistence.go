@@ -0,0 +1,49 @@
+package test
+
+import (
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyGenericSqlitePersistence struct {
+	gpersist.IdentifiableSqlitePersistence[tf.Dummy, string]
+}
+
+func NewDummyGenericSqlitePersistence() *DummyGenericSqlitePersistence {
+	c := &DummyGenericSqlitePersistence{}
+	c.IdentifiableSqlitePersistence = *gpersist.InheritIdentifiableSqlitePersistence[tf.Dummy, string](c, "dummies_generic")
+	return c
+}
+
+func (c *DummyGenericSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50), \"content\" TEXT)")
+	c.EnsureIndex(c.TableName+"_key", map[string]string{"key": "1"}, map[string]string{"unique": "true"})
+}
+
+func (c *DummyGenericSqlitePersistence) composeFilter(filter *cdata.FilterParams) string {
+	if filter == nil {
+		filter = cdata.NewEmptyFilterParams()
+	}
+
+	key := filter.GetAsNullableString("Key")
+	filterObj := ""
+	if key != nil && *key != "" {
+		filterObj += "key='" + *key + "'"
+	}
+	return filterObj
+}
+
+func (c *DummyGenericSqlitePersistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams) (page *tf.DummyPage, err error) {
+	tempPage, err := c.IdentifiableSqlitePersistence.GetPageByFilter(correlationId, c.composeFilter(filter), paging, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	total := int64(len(tempPage.Data))
+	return tf.NewDummyPage(&total, tempPage.Data), nil
+}
+
+func (c *DummyGenericSqlitePersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
+	return c.IdentifiableSqlitePersistence.GetCountByFilter(correlationId, c.composeFilter(filter))
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyGenericSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	)
+
+	t.Run("Value", func(t *testing.T) {
+		persistence := NewDummyGenericSqlitePersistence()
+		fixture := tf.NewDummyPersistenceFixture(persistence)
+		persistence.Configure(dbConfig)
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		persistence.Clear("")
+		t.Run("CRUD", fixture.TestCrudOperations)
+		persistence.Clear("")
+		t.Run("Batch", fixture.TestBatchOperations)
+
+		item, err := persistence.GetOneById("", "unknown")
+		assert.Nil(t, err)
+		assert.Equal(t, tf.Dummy{}, item)
+	})
+
+	t.Run("PointerJson", func(t *testing.T) {
+		persistence := NewDummyRefGenericJsonSqlitePersistence()
+		fixture := tf.NewDummyRefPersistenceFixture(persistence)
+		persistence.Configure(dbConfig)
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		persistence.Clear("")
+		t.Run("CRUD", fixture.TestCrudOperations)
+		persistence.Clear("")
+		t.Run("Batch", fixture.TestBatchOperations)
+
+		item, err := persistence.GetOneById("", "unknown")
+		assert.Nil(t, err)
+		assert.Nil(t, item)
+	})
+}
@@ -0,0 +1,268 @@
+package test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyIdSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	t.Run("IdColumn", func(t *testing.T) {
+		persistence := NewDummyJsonSqlitePersistence()
+		fixture := tf.NewDummyPersistenceFixture(persistence)
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_json_id",
+			"options.id_column", "dummy_id",
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		columns, err := persistence.Connection.GetColumns("", "dummies_json_id")
+		assert.Nil(t, err)
+		assert.Equal(t, "dummy_id", columns[0].Name)
+
+		persistence.Clear("")
+		t.Run("CRUD", fixture.TestCrudOperations)
+		persistence.Clear("")
+		t.Run("Batch", fixture.TestBatchOperations)
+	})
+
+	t.Run("AutoIncrement", func(t *testing.T) {
+		persistence := NewDummyJsonSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_json_autoinc",
+			"options.id_autoincrement", true,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy1, err := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", dummy1.Id)
+
+		dummy2, err := persistence.Set("", tf.Dummy{Key: "Key 2", Content: "Content 2"})
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", dummy2.Id)
+		assert.NotEqual(t, dummy1.Id, dummy2.Id)
+
+		result, err := persistence.GetOneById("", dummy1.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, dummy1, result)
+
+		dummy2.Content = "Updated Content 2"
+		result, err = persistence.Set("", dummy2)
+		assert.Nil(t, err)
+		assert.Equal(t, dummy2, result)
+	})
+
+	t.Run("IdGenerator", func(t *testing.T) {
+		generators := map[string]string{
+			"uuid7":     `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+			"ulid":      `^[0-9A-HJKMNP-TV-Z]{26}$`,
+			"snowflake": `^[0-9]+$`,
+			"sequence":  `^ORD-[0-9]{6}$`,
+		}
+		for generator, pattern := range generators {
+			persistence := NewDummySqlitePersistence()
+			persistence.Configure(cconf.NewConfigParamsFromTuples(
+				"connection.database", sqliteDatabase,
+				"table", "dummies_id_gen",
+				"options.id_generator", generator,
+				"options.id_prefix", "ORD-",
+			))
+
+			err := persistence.Open("")
+			assert.Nil(t, err)
+			persistence.Clear("")
+
+			dummy1, err := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+			assert.Nil(t, err)
+			assert.Regexp(t, pattern, dummy1.Id)
+
+			dummy2, err := persistence.Set("", tf.Dummy{Key: "Key 2", Content: "Content 2"})
+			assert.Nil(t, err)
+			assert.Regexp(t, pattern, dummy2.Id)
+
+			// Generated ids are ordered by time of creation
+			assert.Less(t, dummy1.Id, dummy2.Id)
+
+			persistence.Close("")
+		}
+
+		persistence := NewDummySqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_id_gen",
+			"options.id_generator", "unknown",
+		))
+		err := persistence.Open("")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("LargeIdLists", func(t *testing.T) {
+		persistence := NewDummyGenericSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_id_chunks",
+			"options.max_ids_per_query", 3,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		ids := make([]string, 0)
+		for index := 0; index < 7; index++ {
+			dummy, err := persistence.Create("", tf.Dummy{Key: "Key " + strconv.Itoa(index), Content: "Content"})
+			assert.Nil(t, err)
+			ids = append([]string{dummy.Id}, ids...)
+		}
+
+		items, err := persistence.GetListByIds("", ids)
+		assert.Nil(t, err)
+		assert.Len(t, items, 7)
+
+		items, missingIds, err := persistence.GetListByIdsInOrder("", append(ids, "unknown"))
+		assert.Nil(t, err)
+		assert.Len(t, items, 7)
+		for index, item := range items {
+			assert.Equal(t, ids[index], item.Id)
+		}
+		assert.Equal(t, []string{"unknown"}, missingIds)
+
+		items, err = persistence.GetListByIds("", []string{})
+		assert.Nil(t, err)
+		assert.Len(t, items, 0)
+
+		// Without the limit ids are queried at once
+		persistence.MaxIdsPerQuery = 0
+		items, err = persistence.GetListByIds("", []string{})
+		assert.Nil(t, err)
+		assert.Len(t, items, 0)
+		items, err = persistence.GetListByIds("", ids)
+		assert.Nil(t, err)
+		assert.Len(t, items, 7)
+
+		count, err := persistence.DeleteByIds("", append(ids, "unknown"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(7), count)
+	})
+
+	t.Run("CompositeKey", func(t *testing.T) {
+		persistence := NewDummyMemberSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		_, err = persistence.Create("", DummyMember{GroupId: "group1", UserId: "user1", Role: "owner"})
+		assert.Nil(t, err)
+		_, err = persistence.Set("", DummyMember{GroupId: "group1", UserId: "user2", Role: "reader"})
+		assert.Nil(t, err)
+		_, err = persistence.Set("", DummyMember{GroupId: "group2", UserId: "user1", Role: "reader"})
+		assert.Nil(t, err)
+
+		item, err := persistence.GetOneByKey("", []interface{}{"group1", "user2"})
+		assert.Nil(t, err)
+		assert.Equal(t, DummyMember{GroupId: "group1", UserId: "user2", Role: "reader"}, item)
+
+		_, err = persistence.GetOneByKey("", []interface{}{"group1"})
+		assert.NotNil(t, err)
+
+		item, err = persistence.Update("", DummyMember{GroupId: "group1", UserId: "user2", Role: "writer"})
+		assert.Nil(t, err)
+		assert.Equal(t, "writer", item.(DummyMember).Role)
+
+		item, err = persistence.UpdatePartially("", []interface{}{"group2", "user1"},
+			cdata.NewAnyValueMapFromTuples("role", "admin"))
+		assert.Nil(t, err)
+		assert.Equal(t, "admin", item.(DummyMember).Role)
+
+		items, err := persistence.GetListByKeys("", [][]interface{}{{"group1", "user1"}, {"group2", "user1"}, {"group3", "user1"}})
+		assert.Nil(t, err)
+		assert.Len(t, items, 2)
+
+		item, err = persistence.DeleteByKey("", []interface{}{"group1", "user1"})
+		assert.Nil(t, err)
+		assert.Equal(t, "owner", item.(DummyMember).Role)
+
+		deleted, err := persistence.DeleteByKeys("", [][]interface{}{{"group1", "user2"}, {"group2", "user1"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		count, err := persistence.GetCountByFilter("", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("CompositeKeySoftDelete", func(t *testing.T) {
+		persistence := NewDummyMemberSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummy_members_soft",
+			"options.soft_delete", true,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		persistence.Set("", DummyMember{GroupId: "group1", UserId: "user1", Role: "owner"})
+		persistence.Set("", DummyMember{GroupId: "group1", UserId: "user2", Role: "reader"})
+		persistence.Set("", DummyMember{GroupId: "group2", UserId: "user1", Role: "reader"})
+
+		item, err := persistence.DeleteByKey("", []interface{}{"group1", "user1"})
+		assert.Nil(t, err)
+		assert.Equal(t, "owner", item.(DummyMember).Role)
+		deleted, err := persistence.DeleteByKeys("", [][]interface{}{{"group1", "user1"}, {"group1", "user2"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		// Deleted items are hidden from reads and protected from updates
+		item, err = persistence.GetOneByKey("", []interface{}{"group1", "user1"})
+		assert.Nil(t, err)
+		assert.Nil(t, item)
+		items, err := persistence.GetListByKeys("", [][]interface{}{{"group1", "user1"}, {"group1", "user2"}, {"group2", "user1"}})
+		assert.Nil(t, err)
+		assert.Len(t, items, 1)
+		item, err = persistence.Update("", DummyMember{GroupId: "group1", UserId: "user2", Role: "writer"})
+		assert.Nil(t, err)
+		assert.Nil(t, item)
+
+		count, err := persistence.WithDeleted().GetCountByFilter("", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// Setting a deleted item restores it
+		item, err = persistence.Set("", DummyMember{GroupId: "group1", UserId: "user1", Role: "admin"})
+		assert.Nil(t, err)
+		assert.Equal(t, "admin", item.(DummyMember).Role)
+
+		count, err = persistence.GetCountByFilter("", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+}
@@ -0,0 +1,23 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyJsonFieldsSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[*tf.Dummy, string]
+}
+
+func NewDummyJsonFieldsSqlitePersistence() *DummyJsonFieldsSqlitePersistence {
+	c := &DummyJsonFieldsSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[*tf.Dummy, string](c, "dummies_json_fields")
+	return c
+}
+
+func (c *DummyJsonFieldsSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+	c.EnsureJsonField("$.key", "TEXT", true, true)
+	c.EnsureJsonField("$.content", "TEXT", false, false)
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyJsonFieldsSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyJsonFieldsSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.auto_migrate", true,
+		"options.strict_schema", true,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	dummy1, _ := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Content 1"})
+	dummy2, _ := persistence.Create("", &tf.Dummy{Key: "Key 2", Content: "Content 2"})
+
+	// Generated columns keep unique keys
+	_, err = persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Content 3"})
+	assert.NotNil(t, err)
+
+	items, err := persistence.GetListByFilter("", "\"key\"='Key 2'", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []*tf.Dummy{dummy2}, items)
+
+	items, err = persistence.GetListByFilter("", "", "\"content\" DESC", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []*tf.Dummy{dummy2, dummy1}, items)
+
+	items, err = persistence.GetListByFilter("", "", "\"key\"", "\"id\", \"key\"")
+	assert.Nil(t, err)
+	assert.Equal(t, []*tf.Dummy{{Id: dummy1.Id, Key: "Key 1"}, {Id: dummy2.Id, Key: "Key 2"}}, items)
+
+	// Filters on field names use the index
+	rows, err := persistence.Client.Query("EXPLAIN QUERY PLAN SELECT * FROM dummies_json_fields WHERE \"key\"='Key 1'")
+	assert.Nil(t, err)
+	plan := ""
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		rows.Scan(&id, &parent, &unused, &detail)
+		plan += detail
+	}
+	rows.Close()
+	assert.True(t, strings.Contains(plan, "dummies_json_fields_key"), plan)
+
+	// Reopening keeps existing columns
+	persistence.Close("")
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	item, err := persistence.GetOneById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy1, item)
+}
@@ -0,0 +1,20 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+)
+
+type DummyJsonPatchSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[map[string]interface{}, string]
+}
+
+func NewDummyJsonPatchSqlitePersistence() *DummyJsonPatchSqlitePersistence {
+	c := &DummyJsonPatchSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[map[string]interface{}, string](c, "dummies_json_patch")
+	return c
+}
+
+func (c *DummyJsonPatchSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+}
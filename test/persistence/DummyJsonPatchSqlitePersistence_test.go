@@ -0,0 +1,97 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyJsonPatchSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyJsonPatchSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	_, err = persistence.Create("", map[string]interface{}{
+		"id":    "1",
+		"key":   "Key 1",
+		"count": 1,
+		"tags":  []interface{}{"a"},
+		"a":     map[string]interface{}{"b": "x", "c": "y"},
+	})
+	assert.Nil(t, err)
+
+	item, err := persistence.PatchById("", "1", []*persist.SqliteJsonPatchOperation{
+		{Op: persist.JsonPatchSet, Path: "$.a.b", Value: "z"},
+		{Op: persist.JsonPatchRemove, Path: "$.a.c"},
+		{Op: persist.JsonPatchIncrement, Path: "$.count", Value: 2},
+		{Op: persist.JsonPatchIncrement, Path: "$.visits"},
+		{Op: persist.JsonPatchArrayAppend, Path: "$.tags", Value: "b"},
+		{Op: persist.JsonPatchArrayAppend, Path: "$.labels", Value: map[string]interface{}{"name": "new"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"b": "z"}, item["a"])
+	assert.Equal(t, float64(3), item["count"])
+	assert.Equal(t, float64(1), item["visits"])
+	assert.Equal(t, []interface{}{"a", "b"}, item["tags"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "new"}}, item["labels"])
+
+	stored, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, item, stored)
+
+	// RFC 6902 documents
+	operations, err := persist.ParseJsonPatch("", []byte(`[
+		{"op": "test", "path": "/key", "value": "Key 1"},
+		{"op": "add", "path": "/tags/-", "value": "c"},
+		{"op": "replace", "path": "/tags/0", "value": "A"},
+		{"op": "move", "from": "/a/b", "path": "/moved"},
+		{"op": "copy", "from": "/key", "path": "/a~1b"}
+	]`))
+	assert.Nil(t, err)
+	item, err = persistence.PatchById("", "1", operations)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"A", "b", "c"}, item["tags"])
+	assert.Equal(t, "z", item["moved"])
+	assert.Equal(t, map[string]interface{}{}, item["a"])
+	assert.Equal(t, "Key 1", item["a/b"])
+
+	// Failed tests don't change documents
+	operations, err = persist.ParseJsonPatch("", []byte(`[
+		{"op": "remove", "path": "/moved"},
+		{"op": "test", "path": "/key", "value": "Key 2"}
+	]`))
+	assert.Nil(t, err)
+	_, err = persistence.PatchById("", "1", operations)
+	assert.NotNil(t, err)
+	assert.Equal(t, "PATCH_TEST_FAILED", err.(*cerr.ApplicationError).Code)
+	stored, err = persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "z", stored["moved"])
+
+	// Missing items are not patched
+	item, err = persistence.PatchById("", "2", operations)
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	_, err = persist.ParseJsonPatch("", []byte(`[{"op": "add", "path": "/tags/1", "value": "x"}]`))
+	assert.Equal(t, "UNSUPPORTED_PATCH", err.(*cerr.ApplicationError).Code)
+	_, err = persist.ParseJsonPatch("", []byte(`[{"op": "merge", "path": "/tags"}]`))
+	assert.Equal(t, "INVALID_PATCH", err.(*cerr.ApplicationError).Code)
+	_, err = persistence.PatchById("", "1", []*persist.SqliteJsonPatchOperation{{Op: persist.JsonPatchSet, Path: "a.b"}})
+	assert.Equal(t, "INVALID_PATCH", err.(*cerr.ApplicationError).Code)
+}
@@ -136,7 +136,7 @@ func (c *DummyJsonSqlitePersistence) DeleteById(correlationId string, id string)
 	return item, err
 }
 
-func (c *DummyJsonSqlitePersistence) DeleteByIds(correlationId string, ids []string) (err error) {
+func (c *DummyJsonSqlitePersistence) DeleteByIds(correlationId string, ids []string) (count int64, err error) {
 	convIds := make([]interface{}, len(ids))
 	for i, v := range ids {
 		convIds[i] = v
@@ -0,0 +1,20 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+)
+
+type DummyJsonViewSqlitePersistence[T any] struct {
+	gpersist.IdentifiableJsonSqlitePersistence[T, string]
+}
+
+func NewDummyJsonViewSqlitePersistence[T any]() *DummyJsonViewSqlitePersistence[T] {
+	c := &DummyJsonViewSqlitePersistence[T]{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[T, string](c, "dummies_json_view")
+	return c
+}
+
+func (c *DummyJsonViewSqlitePersistence[T]) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+}
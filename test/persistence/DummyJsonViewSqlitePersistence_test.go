@@ -0,0 +1,100 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyViewBase struct {
+	Key string `json:"key"`
+}
+
+type dummyViewV1 struct {
+	Id string `json:"id"`
+	dummyViewBase
+	Count   int       `json:"count"`
+	Price   float64   `json:"price"`
+	Active  bool      `json:"active"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	Secret  string    `json:"-"`
+}
+
+type dummyViewV2 struct {
+	Id string `json:"id"`
+	dummyViewBase
+	Count   int       `json:"count"`
+	Price   float64   `json:"price"`
+	Active  bool      `json:"active"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	Owner   *string   `json:"owner"`
+}
+
+func TestDummyJsonViewSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.json_view", true,
+	)
+
+	persistence := NewDummyJsonViewSqlitePersistence[dummyViewV1]()
+	persistence.Configure(config)
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	persistence.Clear("")
+
+	_, err = persistence.Create("", dummyViewV1{
+		Id:            "1",
+		dummyViewBase: dummyViewBase{Key: "Key 1"},
+		Count:         3,
+		Price:         1.5,
+		Active:        true,
+		Tags:          []string{"a", "b"},
+		Created:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+
+	columns, err := persistence.Connection.GetColumns("", "dummies_json_view_v")
+	assert.Nil(t, err)
+	names := []string{}
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	assert.Equal(t, []string{"id", "key", "count", "price", "active", "tags", "created"}, names)
+
+	var key, tags, created, countType, priceType string
+	var count, active int
+	var price float64
+	err = persistence.Client.QueryRow("SELECT \"key\", \"count\", TYPEOF(\"count\"), price, TYPEOF(price), active, tags, created "+
+		"FROM dummies_json_view_v WHERE id='1'").Scan(&key, &count, &countType, &price, &priceType, &active, &tags, &created)
+	assert.Nil(t, err)
+	assert.Equal(t, "Key 1", key)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "integer", countType)
+	assert.Equal(t, 1.5, price)
+	assert.Equal(t, "real", priceType)
+	assert.Equal(t, 1, active)
+	assert.Equal(t, `["a","b"]`, tags)
+	assert.Equal(t, "2024-01-02T03:04:05Z", created)
+	persistence.Close("")
+
+	// Changed prototypes recreate the view
+	persistence2 := NewDummyJsonViewSqlitePersistence[dummyViewV2]()
+	persistence2.Configure(config)
+	err = persistence2.Open("")
+	assert.Nil(t, err)
+	defer persistence2.Close("")
+
+	var owner *string
+	err = persistence2.Client.QueryRow("SELECT owner FROM dummies_json_view_v WHERE id='1'").Scan(&owner)
+	assert.Nil(t, err)
+	assert.Nil(t, owner)
+}
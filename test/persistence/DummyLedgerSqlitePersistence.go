@@ -0,0 +1,40 @@
+package test
+
+import (
+	"reflect"
+
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyLedgerSqlitePersistence struct {
+	persist.LedgerSqlitePersistence
+}
+
+func NewDummyLedgerSqlitePersistence() *DummyLedgerSqlitePersistence {
+	c := &DummyLedgerSqlitePersistence{}
+	c.LedgerSqlitePersistence = *persist.InheritLedgerSqlitePersistence(c, reflect.TypeOf(tf.Dummy{}), "dummies_ledger")
+	return c
+}
+
+func (c *DummyLedgerSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable()
+}
+
+func (c *DummyLedgerSqlitePersistence) Append(correlationId string, item tf.Dummy) (result tf.Dummy, err error) {
+	value, err := c.LedgerSqlitePersistence.Append(correlationId, item)
+	if value != nil {
+		result, _ = value.(tf.Dummy)
+	}
+	return result, err
+}
+
+func (c *DummyLedgerSqlitePersistence) GetListByFilter(correlationId string, filter string) (items []tf.Dummy, err error) {
+	result, err := c.LedgerSqlitePersistence.GetListByFilter(correlationId, filter, "\"seq\"", nil)
+	items = make([]tf.Dummy, len(result))
+	for i, v := range result {
+		items[i], _ = v.(tf.Dummy)
+	}
+	return items, err
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyLedgerSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	// Ledgers cannot be cleared, so the table is dropped before the test
+	db, err := sql.Open("sqlite3", sqliteDatabase)
+	assert.Nil(t, err)
+	_, err = db.Exec("DROP TABLE IF EXISTS dummies_ledger")
+	assert.Nil(t, err)
+	db.Close()
+
+	persistence := NewDummyLedgerSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	))
+
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	result, err := persistence.Verify("")
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(0), result.Entries)
+
+	dummy1, err := persistence.Append("", tf.Dummy{Id: "1", Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Content 1", dummy1.Content)
+	persistence.Append("", tf.Dummy{Id: "2", Key: "Key 2", Content: "Content 2"})
+	persistence.Append("", tf.Dummy{Id: "3", Key: "Key 3", Content: "<Content 3>"})
+
+	items, err := persistence.GetListByFilter("", "")
+	assert.Nil(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, "<Content 3>", items[2].Content)
+
+	result, err = persistence.Verify("")
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Entries)
+	assert.Len(t, result.LastHash, 64)
+
+	// Entries cannot be changed or deleted
+	_, err = persistence.Client.Exec("UPDATE dummies_ledger SET data='{}' WHERE seq=2")
+	assert.NotNil(t, err)
+	_, err = persistence.Client.Exec("DELETE FROM dummies_ledger WHERE seq=2")
+	assert.NotNil(t, err)
+	assert.NotNil(t, persistence.DeleteByFilter("", ""))
+	assert.NotNil(t, persistence.Clear(""))
+
+	// Changes made around the triggers break the chain
+	_, err = persistence.Client.Exec("DROP TRIGGER dummies_ledger_reject_update")
+	assert.Nil(t, err)
+	_, err = persistence.Client.Exec("UPDATE dummies_ledger SET data=REPLACE(data, 'Content 2', 'Content X') WHERE seq=2")
+	assert.Nil(t, err)
+
+	result, err = persistence.Verify("")
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(1), result.Entries)
+	assert.Equal(t, int64(2), result.BrokenSeq)
+	assert.NotEmpty(t, result.Reason)
+}
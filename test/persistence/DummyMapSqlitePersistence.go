@@ -99,7 +99,7 @@ func (c *DummyMapSqlitePersistence) DeleteById(correlationId string, id string)
 	return item, err
 }
 
-func (c *DummyMapSqlitePersistence) DeleteByIds(correlationId string, ids []string) (err error) {
+func (c *DummyMapSqlitePersistence) DeleteByIds(correlationId string, ids []string) (count int64, err error) {
 	convIds := make([]interface{}, len(ids))
 	for i, v := range ids {
 		convIds[i] = v
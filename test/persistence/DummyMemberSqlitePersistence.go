@@ -0,0 +1,33 @@
+package test
+
+import (
+	"reflect"
+
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+)
+
+type DummyMember struct {
+	GroupId string `json:"group_id"`
+	UserId  string `json:"user_id"`
+	Role    string `json:"role"`
+}
+
+type DummyMemberSqlitePersistence struct {
+	persist.CompositeKeySqlitePersistence
+}
+
+func NewDummyMemberSqlitePersistence() *DummyMemberSqlitePersistence {
+	proto := reflect.TypeOf(DummyMember{})
+	c := &DummyMemberSqlitePersistence{}
+	c.CompositeKeySqlitePersistence = *persist.InheritCompositeKeySqlitePersistence(c, proto, "dummy_members", "group_id", "user_id")
+	return c
+}
+
+func (c *DummyMemberSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	columns := "\"group_id\" TEXT, \"user_id\" TEXT, \"role\" TEXT"
+	if c.SoftDelete {
+		columns += ", \"" + c.DeletedColumn + "\" TEXT"
+	}
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (" + columns + ", PRIMARY KEY (\"group_id\", \"user_id\"))")
+}
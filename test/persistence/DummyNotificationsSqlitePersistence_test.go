@@ -0,0 +1,73 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	conn "github.com/pip-services3-go/pip-services3-sqlite-go/connect"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyChange struct {
+	operation string
+	item      interface{}
+}
+
+func TestDummyNotificationsSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyGenericSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_notified",
+		"options.notifications", true,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	changes := make(chan dummyChange, 10)
+	subscription, err := persistence.Subscribe("", "", func(event *conn.SqliteChangeEvent, item interface{}) {
+		changes <- dummyChange{operation: event.Operation, item: item}
+	})
+	assert.Nil(t, err)
+	defer subscription.Unsubscribe()
+
+	receive := func() dummyChange {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(time.Second):
+			t.Error("Change was not delivered")
+			return dummyChange{}
+		}
+	}
+
+	dummy, err := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	change := receive()
+	assert.Equal(t, conn.SqliteChangeInsert, change.operation)
+	assert.Equal(t, dummy, change.item)
+
+	dummy.Content = "Content 2"
+	_, err = persistence.Update("", dummy)
+	assert.Nil(t, err)
+	change = receive()
+	assert.Equal(t, conn.SqliteChangeUpdate, change.operation)
+	assert.Equal(t, dummy, change.item)
+
+	// Deleted items cannot be read anymore
+	_, err = persistence.DeleteById("", dummy.Id)
+	assert.Nil(t, err)
+	change = receive()
+	assert.Equal(t, conn.SqliteChangeDelete, change.operation)
+	assert.Nil(t, change.item)
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOutboxPublisher struct {
+	lock     sync.Mutex
+	messages []*persist.SqliteOutboxMessage
+	failures int
+}
+
+func (c *testOutboxPublisher) Publish(correlationId string, message *persist.SqliteOutboxMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("broker is not available")
+	}
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *testOutboxPublisher) getMessages() []*persist.SqliteOutboxMessage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*persist.SqliteOutboxMessage{}, c.messages...)
+}
+
+func TestDummyOutboxSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	clock := &testClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	publisher := &testOutboxPublisher{}
+	outbox := persist.NewSqliteOutbox()
+	outbox.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "outbox_dummies",
+		"options.interval", 0,
+		"options.retry_timeout", 1000,
+		"options.retention", 60000,
+	))
+	outbox.Publisher = publisher
+	outbox.Clock = clock
+	err := outbox.Open("")
+	assert.Nil(t, err)
+	defer outbox.Close("")
+	_, err = outbox.Client.Exec("DELETE FROM outbox_dummies")
+	assert.Nil(t, err)
+
+	persistence := NewDummySqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_outbox",
+	))
+	persistence.Outbox = outbox
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	dummy, err := persistence.Create("123", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	dummy.Content = "Content 2"
+	_, err = persistence.Update("123", dummy)
+	assert.Nil(t, err)
+	_, err = persistence.DeleteById("456", dummy.Id)
+	assert.Nil(t, err)
+
+	// Failed writes don't produce events
+	_, err = persistence.Create("789", tf.Dummy{Id: "1", Key: "Key 2"})
+	assert.Nil(t, err)
+	_, err = persistence.Create("789", tf.Dummy{Id: "2", Key: "Key 2"})
+	assert.NotNil(t, err)
+
+	pending, err := outbox.GetPendingMessages("")
+	assert.Nil(t, err)
+	assert.Len(t, pending, 4)
+	assert.Equal(t, "dummies_outbox.create", pending[0].Type)
+	assert.Equal(t, dummy.Id, pending[0].Key)
+	assert.Equal(t, "123", pending[0].CorrelationId)
+	assert.Equal(t, "dummies_outbox.update", pending[1].Type)
+	assert.Equal(t, "dummies_outbox.delete", pending[2].Type)
+	assert.Equal(t, "456", pending[2].CorrelationId)
+	assert.Equal(t, "dummies_outbox.create", pending[3].Type)
+	assert.Equal(t, "1", pending[3].Key)
+
+	deleted := &tf.Dummy{}
+	assert.Nil(t, json.Unmarshal(pending[2].Payload, deleted))
+	assert.Equal(t, "Content 2", deleted.Content)
+
+	// Failed message blocks following messages until the retry
+	publisher.failures = 1
+	count, err := outbox.Relay("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	pending, err = outbox.GetPendingMessages("")
+	assert.Nil(t, err)
+	assert.Len(t, pending, 4)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "broker is not available", pending[0].LastError)
+
+	count, err = outbox.Relay("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	clock.now = clock.now.Add(time.Second)
+	count, err = outbox.Relay("")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+
+	messages := publisher.getMessages()
+	assert.Len(t, messages, 4)
+	for index, message := range messages {
+		assert.Equal(t, pending[index].Id, message.Id)
+	}
+
+	pending, err = outbox.GetPendingMessages("")
+	assert.Nil(t, err)
+	assert.Len(t, pending, 0)
+
+	// Delivered messages are kept for the retention period
+	removed, err := outbox.Cleanup("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), removed)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	removed, err = outbox.Cleanup("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), removed)
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyRandomSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyJsonPatchSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_random",
+		"options.random_seed", 42,
+	))
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	// Empty tables have nothing to pick
+	item, err := persistence.GetOneRandom("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+	items, err := persistence.GetRandomSample("", "", 3)
+	assert.Nil(t, err)
+	assert.Len(t, items, 0)
+
+	for index := 1; index <= 20; index++ {
+		_, err = persistence.Create("", map[string]interface{}{
+			"id": strconv.Itoa(index), "key": "key " + strconv.Itoa(index%2),
+		})
+		assert.Nil(t, err)
+	}
+	// Gaps in rowids
+	persistence.DeleteByFilter("", "JSON_EXTRACT(data, '$.id') IN ('3', '4', '5', '6', '7')")
+
+	item, err = persistence.GetOneRandom("", "JSON_EXTRACT(data, '$.key')='key 1'")
+	assert.Nil(t, err)
+	assert.NotNil(t, item)
+	assert.Equal(t, "key 1", item["key"])
+
+	items, err = persistence.GetRandomSample("", "", 5)
+	assert.Nil(t, err)
+	assert.Len(t, items, 5)
+	ids := make(map[interface{}]bool)
+	for _, item := range items {
+		ids[item["id"]] = true
+	}
+	assert.Len(t, ids, 5)
+
+	// The same seed gives the same sample
+	persistence.SetRandomSeed(7)
+	first, err := persistence.GetRandomSample("", "JSON_EXTRACT(data, '$.key')='key 0'", 4)
+	assert.Nil(t, err)
+	persistence.SetRandomSeed(7)
+	second, err := persistence.GetRandomSample("", "JSON_EXTRACT(data, '$.key')='key 0'", 4)
+	assert.Nil(t, err)
+	assert.Len(t, first, 4)
+	assert.Equal(t, first, second)
+	for _, item := range first {
+		assert.Equal(t, "key 0", item["key"])
+	}
+
+	// Dense samples are completed from all matching items
+	items, err = persistence.GetRandomSample("", "", 14)
+	assert.Nil(t, err)
+	assert.Len(t, items, 14)
+
+	items, err = persistence.GetRandomSample("", "", 100)
+	assert.Nil(t, err)
+	assert.Len(t, items, 15)
+}
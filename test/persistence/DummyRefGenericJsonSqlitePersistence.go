@@ -0,0 +1,49 @@
+package test
+
+import (
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+)
+
+type DummyRefGenericJsonSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[*tf.Dummy, string]
+}
+
+func NewDummyRefGenericJsonSqlitePersistence() *DummyRefGenericJsonSqlitePersistence {
+	c := &DummyRefGenericJsonSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[*tf.Dummy, string](c, "dummies_generic_json")
+	return c
+}
+
+func (c *DummyRefGenericJsonSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+	c.EnsureIndex(c.TableName+"_json_key", map[string]string{"JSON_EXTRACT(data, '$.key')": "1"}, map[string]string{"unique": "true"})
+}
+
+func (c *DummyRefGenericJsonSqlitePersistence) composeFilter(filter *cdata.FilterParams) string {
+	if filter == nil {
+		filter = cdata.NewEmptyFilterParams()
+	}
+
+	key := filter.GetAsNullableString("Key")
+	filterObj := ""
+	if key != nil && *key != "" {
+		filterObj += "JSON_EXTRACT(data, '$.key')='" + *key + "'"
+	}
+	return filterObj
+}
+
+func (c *DummyRefGenericJsonSqlitePersistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams) (page *tf.DummyRefPage, err error) {
+	tempPage, err := c.IdentifiableJsonSqlitePersistence.GetPageByFilter(correlationId, c.composeFilter(filter), paging, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	total := int64(len(tempPage.Data))
+	return tf.NewDummyRefPage(&total, tempPage.Data), nil
+}
+
+func (c *DummyRefGenericJsonSqlitePersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
+	return c.IdentifiableJsonSqlitePersistence.GetCountByFilter(correlationId, c.composeFilter(filter))
+}
@@ -91,7 +91,7 @@ func (c *DummyRefSqlitePersistence) DeleteById(correlationId string, id string)
 	return item, err
 }
 
-func (c *DummyRefSqlitePersistence) DeleteByIds(correlationId string, ids []string) (err error) {
+func (c *DummyRefSqlitePersistence) DeleteByIds(correlationId string, ids []string) (count int64, err error) {
 	convIds := make([]interface{}, len(ids))
 	for i, v := range ids {
 		convIds[i] = v
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummySoftDeleteSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyRefGenericJsonSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_soft_json",
+		"options.soft_delete", true,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	dummy1, _ := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Content 1"})
+	dummy2, _ := persistence.Create("", &tf.Dummy{Key: "Key 2", Content: "Content 2"})
+	dummy3, _ := persistence.Create("", &tf.Dummy{Key: "Key 3", Content: "Content 3"})
+
+	// Deleted items are hidden from reads
+	deleted, err := persistence.DeleteById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy1, deleted)
+
+	item, err := persistence.GetOneById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	item, err = persistence.WithDeleted().GetOneById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy1, item)
+
+	count, err := persistence.GetCountByFilter("", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = persistence.DeleteByIds("", []string{dummy1.Id, dummy2.Id, dummy3.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	items, err := persistence.WithDeleted().GetListByIds("", []string{dummy1.Id, dummy2.Id, dummy3.Id})
+	assert.Nil(t, err)
+	assert.Len(t, items, 3)
+
+	// Deleted items can be restored
+	count, err = persistence.Restore("", []string{dummy1.Id, dummy2.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = persistence.GetCountByFilter("", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Purge removes only items deleted before the given time
+	count, err = persistence.Purge("", time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	count, err = persistence.Purge("", time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	item, err = persistence.WithDeleted().GetOneById("", dummy3.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, item)
+
+	// Filtered deletes are soft as well
+	err = persistence.DeleteByFilter("", "")
+	assert.Nil(t, err)
+
+	count, err = persistence.WithDeleted().GetCountByFilter("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = persistence.GetCountByFilter("", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Deletions are stamped by the clock of the persistence
+	clock := &testClock{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	persistence.Clock = clock
+	dummy4, _ := persistence.Create("", &tf.Dummy{Key: "Key 4", Content: "Content 4"})
+	persistence.Create("", &tf.Dummy{Key: "Key 5", Content: "Content 5"})
+	_, err = persistence.DeleteById("", dummy4.Id)
+	assert.Nil(t, err)
+	clock.now = clock.now.Add(time.Hour)
+	err = persistence.DeleteByFilter("", "")
+	assert.Nil(t, err)
+
+	var deletedAt string
+	err = persistence.Client.QueryRow("SELECT deleted_at FROM dummies_soft_json WHERE id=?1", dummy4.Id).Scan(&deletedAt)
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-01-02T03:04:05.000Z", deletedAt)
+
+	count, err = persistence.Purge("", clock.now)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = persistence.Purge("", clock.now.Add(time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Views follow the persistence when it is reopened
+	view := persistence.WithDeleted()
+	dummy6, _ := persistence.Create("", &tf.Dummy{Key: "Key 6", Content: "Content 6"})
+	_, err = persistence.DeleteById("", dummy6.Id)
+	assert.Nil(t, err)
+
+	err = persistence.Close("")
+	assert.Nil(t, err)
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	item, err = view.GetOneById("", dummy6.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, dummy6.Id, item.Id)
+
+	count, err = view.GetCountByFilter("", persistence.composeFilter(cdata.NewFilterParamsFromTuples("Key", "Key 6")))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDummySoftDeleteRecordedSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	for _, table := range []string{"dummies_soft_stamped", "dummies_soft_stamped_data"} {
+		t.Run(table, func(t *testing.T) {
+			clock := &testClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+			persistence := NewDummyStampedJsonSqlitePersistence()
+			persistence.Configure(cconf.NewConfigParamsFromTuples(
+				"connection.database", sqliteDatabase,
+				"table", table,
+				"options.soft_delete", true,
+				"options.timestamps", true,
+				"options.actor_stamps", true,
+				"options.stamps_in_data", table == "dummies_soft_stamped_data",
+				"options.version_column", "version",
+				"options.audit", true,
+			))
+			persistence.Clock = clock
+			persistence.ActorProvider = persist.NewCorrelationActorProvider(":")
+			err := persistence.Open("")
+			assert.Nil(t, err)
+			defer persistence.Close("")
+			persistence.Clear("")
+			_, err = persistence.Client.Exec("DELETE FROM " + table + "_audit")
+			assert.Nil(t, err)
+
+			dummy, err := persistence.Create("alice:1", DummyStamped{Key: "Key 1", Content: "Content 1"})
+			assert.Nil(t, err)
+			_, err = persistence.DeleteById("alice:2", dummy.Id)
+			assert.Nil(t, err)
+
+			// Restored items are stamped, versioned and audited
+			clock.now = clock.now.Add(time.Hour)
+			count, err := persistence.Restore("bob:3", []string{dummy.Id})
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), count)
+
+			restored, err := persistence.GetOneById("", dummy.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, "2024-01-02T03:04:05.000Z", restored.CreatedAt)
+			assert.Equal(t, "alice", restored.CreatedBy)
+			assert.Equal(t, "2024-01-02T04:04:05.000Z", restored.UpdatedAt)
+			assert.Equal(t, "bob", restored.UpdatedBy)
+
+			var version int64
+			err = persistence.Client.QueryRow("SELECT version FROM "+table+" WHERE id=?1", dummy.Id).Scan(&version)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), version)
+
+			// Purged items are audited
+			_, err = persistence.DeleteById("carol:4", dummy.Id)
+			assert.Nil(t, err)
+			count, err = persistence.Purge("carol:5", clock.now.Add(time.Second))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), count)
+
+			history, err := persistence.GetHistoryById("", dummy.Id)
+			assert.Nil(t, err)
+			operations := []string{
+				persist.AuditOperationCreate,
+				persist.AuditOperationDelete,
+				persist.AuditOperationRestore,
+				persist.AuditOperationDelete,
+				persist.AuditOperationPurge,
+			}
+			assert.Len(t, history, len(operations))
+			for index, entry := range history {
+				if index < len(operations) {
+					assert.Equal(t, operations[index], entry.Operation)
+				}
+			}
+			if len(history) == len(operations) {
+				assert.NotNil(t, history[2].Before)
+				assert.Contains(t, string(history[2].After), "2024-01-02T04:04:05.000Z")
+				assert.Nil(t, history[4].After)
+				assert.Equal(t, "carol:5", history[4].CorrelationId)
+			}
+		})
+	}
+}
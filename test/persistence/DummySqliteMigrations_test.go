@@ -0,0 +1,114 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummySqliteMigrations(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_migrations",
+	)
+
+	newPersistence := func() *DummySqlitePersistence {
+		persistence := NewDummySqlitePersistence()
+		persistence.Configure(dbConfig)
+		return persistence
+	}
+
+	// Start from the clean state
+	persistence := newPersistence()
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	_, err = persistence.Client.Exec("DROP TABLE IF EXISTS dummies_migrations")
+	assert.Nil(t, err)
+	_, err = persistence.Client.Exec("DELETE FROM schema_migrations WHERE table_name='dummies_migrations'")
+	if err != nil {
+		// Bookkeeping table may not exist yet
+		assert.Contains(t, err.Error(), "no such table")
+	}
+	persistence.Close("")
+
+	migrations := fstest.MapFS{
+		"migrations/0001_add_tags.up.sql":   {Data: []byte("ALTER TABLE dummies_migrations ADD COLUMN tags TEXT")},
+		"migrations/0001_add_tags.down.sql": {Data: []byte("ALTER TABLE dummies_migrations DROP COLUMN tags")},
+	}
+
+	// Apply migrations on opening
+	persistence = newPersistence()
+	err = persistence.LoadMigrations(migrations, "migrations")
+	assert.Nil(t, err)
+	persistence.EnsureMigration(2, "index tags",
+		"CREATE INDEX dummies_migrations_tags ON dummies_migrations (tags)",
+		"DROP INDEX dummies_migrations_tags")
+
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	version, err := persistence.GetSchemaVersion("")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, version)
+
+	_, err = persistence.Client.Exec("UPDATE dummies_migrations SET tags='abc'")
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	// Reopening does not apply migrations twice
+	persistence = newPersistence()
+	persistence.LoadMigrations(migrations, "migrations")
+	persistence.EnsureMigration(2, "index tags",
+		"CREATE INDEX dummies_migrations_tags ON dummies_migrations (tags)",
+		"DROP INDEX dummies_migrations_tags")
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	// Modified migrations are rejected
+	persistence = newPersistence()
+	persistence.LoadMigrations(migrations, "migrations")
+	persistence.EnsureMigration(2, "index tags",
+		"CREATE INDEX dummies_migrations_tags ON dummies_migrations (tags, key)", "")
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "MIGRATION_CHECKSUM_MISMATCH", err.(*cerr.ApplicationError).Code)
+	assert.False(t, persistence.Connection.IsOpen())
+
+	// Newer schema is rejected
+	persistence = newPersistence()
+	persistence.LoadMigrations(migrations, "migrations")
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "SCHEMA_TOO_NEW", err.(*cerr.ApplicationError).Code)
+	assert.False(t, persistence.Connection.IsOpen())
+
+	// Revert migrations
+	persistence = newPersistence()
+	persistence.LoadMigrations(migrations, "migrations")
+	persistence.EnsureMigration(2, "index tags",
+		"CREATE INDEX dummies_migrations_tags ON dummies_migrations (tags)",
+		"DROP INDEX dummies_migrations_tags")
+	err = persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	err = persistence.MigrateDown("", 0)
+	assert.Nil(t, err)
+
+	version, err = persistence.GetSchemaVersion("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, version)
+
+	_, err = persistence.Client.Exec("UPDATE dummies_migrations SET tags='abc'")
+	assert.NotNil(t, err)
+}
@@ -96,7 +96,7 @@ func (c *DummySqlitePersistence) DeleteById(correlationId string, id string) (it
 	return item, err
 }
 
-func (c *DummySqlitePersistence) DeleteByIds(correlationId string, ids []string) (err error) {
+func (c *DummySqlitePersistence) DeleteByIds(correlationId string, ids []string) (count int64, err error) {
 	convIds := make([]interface{}, len(ids))
 	for i, v := range ids {
 		convIds[i] = v
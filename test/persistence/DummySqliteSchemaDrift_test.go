@@ -0,0 +1,136 @@
+package test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// Declares an index over several columns in a fixed order
+type dummyIndexOrderSqlitePersistence struct {
+	persist.IdentifiableSqlitePersistence
+}
+
+func newDummyIndexOrderSqlitePersistence() *dummyIndexOrderSqlitePersistence {
+	c := &dummyIndexOrderSqlitePersistence{}
+	c.IdentifiableSqlitePersistence = *persist.InheritIdentifiableSqlitePersistence(c, reflect.TypeOf(tf.Dummy{}), "dummies_drift_order")
+	return c
+}
+
+func (c *dummyIndexOrderSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50), \"content\" TEXT)")
+	c.EnsureSchema("CREATE INDEX IF NOT EXISTS \"" + c.TableName + "_key\" ON \"" + c.TableName + "\" (\"key\", \"content\")")
+}
+
+func TestDummySqliteSchemaDrift(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_drift",
+		"options.auto_migrate", true,
+	)
+
+	// Prepare a stale table
+	persistence := NewDummySqlitePersistence()
+	persistence.Configure(dbConfig)
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	_, err = persistence.Client.Exec("DROP TABLE IF EXISTS dummies_drift")
+	assert.Nil(t, err)
+	_, err = persistence.Client.Exec("CREATE TABLE dummies_drift (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50))")
+	assert.Nil(t, err)
+	persistence.Close("")
+
+	// Additive differences are fixed on opening
+	persistence = NewDummySqlitePersistence()
+	persistence.Configure(dbConfig)
+	err = persistence.Open("")
+	assert.Nil(t, err)
+
+	drifts, err := persistence.DetectSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 0)
+
+	_, err = persistence.Client.Exec("ALTER TABLE dummies_drift ADD COLUMN legacy TEXT")
+	assert.Nil(t, err)
+
+	drifts, err = persistence.DetectSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 1)
+	assert.Equal(t, persist.SchemaDriftExtraColumn, drifts[0].Kind)
+	assert.Equal(t, "legacy", drifts[0].Column)
+	assert.True(t, drifts[0].Destructive)
+	persistence.Close("")
+
+	// Destructive differences fail opening in strict mode
+	persistence = NewDummySqlitePersistence()
+	persistence.Configure(dbConfig.Override(cconf.NewConfigParamsFromTuples("options.strict_schema", true)))
+	err = persistence.Open("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "SCHEMA_DRIFT", err.(*cerr.ApplicationError).Code)
+
+	// Tables without declared schema are derived from the prototype
+	derived := NewDummyRefSqlitePersistence()
+	derived.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"table", "dummies_drift_derived",
+		"options.auto_migrate", true,
+	))
+	err = derived.Open("")
+	assert.Nil(t, err)
+	defer derived.Close("")
+
+	_, err = derived.Client.Exec("DROP TABLE IF EXISTS dummies_drift_derived")
+	assert.Nil(t, err)
+	_, err = derived.Client.Exec("CREATE TABLE dummies_drift_derived (\"id\" TEXT PRIMARY KEY)")
+	assert.Nil(t, err)
+
+	drifts, err = derived.FixSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 2)
+	for _, drift := range drifts {
+		assert.Equal(t, persist.SchemaDriftMissingColumn, drift.Kind)
+		assert.True(t, drift.Fixed)
+	}
+
+	_, err = derived.Client.Exec("INSERT INTO dummies_drift_derived (\"id\", \"key\", \"content\") VALUES ('1', 'Key 1', 'Content 1')")
+	assert.Nil(t, err)
+
+	// Indexes are compared in the order of their columns
+	ordered := newDummyIndexOrderSqlitePersistence()
+	ordered.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	))
+	err = ordered.Open("")
+	assert.Nil(t, err)
+	defer ordered.Close("")
+
+	_, err = ordered.Client.Exec("DROP INDEX IF EXISTS dummies_drift_order_key")
+	assert.Nil(t, err)
+	_, err = ordered.Client.Exec("CREATE INDEX dummies_drift_order_key ON dummies_drift_order (\"key\", \"content\")")
+	assert.Nil(t, err)
+	drifts, err = ordered.DetectSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 0)
+
+	_, err = ordered.Client.Exec("DROP INDEX dummies_drift_order_key")
+	assert.Nil(t, err)
+	_, err = ordered.Client.Exec("CREATE INDEX dummies_drift_order_key ON dummies_drift_order (\"content\", \"key\")")
+	assert.Nil(t, err)
+
+	drifts, err = ordered.DetectSchemaDrift("")
+	assert.Nil(t, err)
+	assert.Len(t, drifts, 1)
+	assert.Equal(t, persist.SchemaDriftIndexDefinition, drifts[0].Kind)
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+)
+
+type DummyStamped struct {
+	Id        string `json:"id"`
+	Key       string `json:"key"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	UpdatedAt string `json:"updated_at"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+type DummyStampedSqlitePersistence struct {
+	gpersist.IdentifiableSqlitePersistence[DummyStamped, string]
+}
+
+func NewDummyStampedSqlitePersistence() *DummyStampedSqlitePersistence {
+	c := &DummyStampedSqlitePersistence{}
+	c.IdentifiableSqlitePersistence = *gpersist.InheritIdentifiableSqlitePersistence[DummyStamped, string](c, "dummies_stamped")
+	return c
+}
+
+func (c *DummyStampedSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50), \"content\" TEXT," +
+		" \"created_at\" TEXT, \"created_by\" TEXT, \"updated_at\" TEXT, \"updated_by\" TEXT)")
+}
+
+type DummyStampedJsonSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[DummyStamped, string]
+}
+
+func NewDummyStampedJsonSqlitePersistence() *DummyStampedJsonSqlitePersistence {
+	c := &DummyStampedJsonSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[DummyStamped, string](c, "dummies_stamped_json")
+	return c
+}
+
+func (c *DummyStampedJsonSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+}
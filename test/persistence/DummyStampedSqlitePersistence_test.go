@@ -0,0 +1,135 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+type iDummyStampedPersistence interface {
+	Clear(correlationId string) error
+	Create(correlationId string, item DummyStamped) (DummyStamped, error)
+	Update(correlationId string, item DummyStamped) (DummyStamped, error)
+	Set(correlationId string, item DummyStamped) (DummyStamped, error)
+	UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (DummyStamped, error)
+}
+
+func testStamps(t *testing.T, persistence iDummyStampedPersistence, clock *testClock) {
+	persistence.Clear("")
+
+	clock.now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dummy, err := persistence.Create("alice:1", DummyStamped{Key: "Key 1", Content: "Content 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05.000Z", dummy.CreatedAt)
+	assert.Equal(t, "alice", dummy.CreatedBy)
+	assert.Equal(t, "2024-01-02T03:04:05.000Z", dummy.UpdatedAt)
+	assert.Equal(t, "alice", dummy.UpdatedBy)
+
+	// Creation stamps are kept on updates even when clients send them
+	clock.now = clock.now.Add(time.Hour)
+	dummy.Content = "Content 2"
+	dummy.CreatedAt = ""
+	dummy.CreatedBy = "mallory"
+	dummy, err = persistence.Update("bob:2", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05.000Z", dummy.CreatedAt)
+	assert.Equal(t, "alice", dummy.CreatedBy)
+	assert.Equal(t, "2024-01-02T04:04:05.000Z", dummy.UpdatedAt)
+	assert.Equal(t, "bob", dummy.UpdatedBy)
+
+	clock.now = clock.now.Add(time.Hour)
+	dummy, err = persistence.UpdatePartially("carol:3", dummy.Id, cdata.NewAnyValueMapFromTuples("content", "Content 3"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Content 3", dummy.Content)
+	assert.Equal(t, "alice", dummy.CreatedBy)
+	assert.Equal(t, "2024-01-02T05:04:05.000Z", dummy.UpdatedAt)
+	assert.Equal(t, "carol", dummy.UpdatedBy)
+
+	clock.now = clock.now.Add(time.Hour)
+	dummy, err = persistence.Set("dave:4", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05.000Z", dummy.CreatedAt)
+	assert.Equal(t, "alice", dummy.CreatedBy)
+	assert.Equal(t, "2024-01-02T06:04:05.000Z", dummy.UpdatedAt)
+	assert.Equal(t, "dave", dummy.UpdatedBy)
+}
+
+func TestDummyStampedSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.timestamps", true,
+		"options.actor_stamps", true,
+	)
+
+	t.Run("Columns", func(t *testing.T) {
+		clock := &testClock{}
+		persistence := NewDummyStampedSqlitePersistence()
+		persistence.Configure(dbConfig)
+		persistence.Clock = clock
+		persistence.ActorProvider = persist.NewCorrelationActorProvider(":")
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		testStamps(t, persistence, clock)
+	})
+
+	t.Run("JsonColumns", func(t *testing.T) {
+		clock := &testClock{}
+		persistence := NewDummyStampedJsonSqlitePersistence()
+		persistence.Configure(dbConfig.Override(cconf.NewConfigParamsFromTuples(
+			"table", "dummies_stamped_json_columns",
+		)))
+		persistence.Clock = clock
+		persistence.ActorProvider = persist.NewCorrelationActorProvider(":")
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		testStamps(t, persistence, clock)
+
+		var data string
+		err = persistence.Client.QueryRow("SELECT data FROM dummies_stamped_json_columns").Scan(&data)
+		assert.Nil(t, err)
+		assert.NotContains(t, data, "created_at")
+	})
+
+	t.Run("JsonData", func(t *testing.T) {
+		clock := &testClock{}
+		persistence := NewDummyStampedJsonSqlitePersistence()
+		persistence.Configure(dbConfig.Override(cconf.NewConfigParamsFromTuples(
+			"table", "dummies_stamped_json_data",
+			"options.stamps_in_data", true,
+		)))
+		persistence.Clock = clock
+		persistence.ActorProvider = persist.NewCorrelationActorProvider(":")
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		testStamps(t, persistence, clock)
+
+		var data string
+		err = persistence.Client.QueryRow("SELECT data FROM dummies_stamped_json_data").Scan(&data)
+		assert.Nil(t, err)
+		assert.Contains(t, data, "created_at")
+	})
+}
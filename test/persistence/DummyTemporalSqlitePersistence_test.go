@@ -0,0 +1,133 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	tf "github.com/pip-services3-go/pip-services3-sqlite-go/test/fixtures"
+	"github.com/stretchr/testify/assert"
+)
+
+// Waits for a moment between changes, so they get different timestamps.
+func nextMoment() time.Time {
+	time.Sleep(10 * time.Millisecond)
+	moment := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	return moment
+}
+
+func TestDummyTemporalSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	t.Run("Columns", func(t *testing.T) {
+		persistence := NewDummyGenericSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_temporal",
+			"options.temporal", true,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+		assert.Equal(t, "dummies_temporal_history", persistence.TemporalTableName)
+
+		moment0 := nextMoment()
+		dummy1, _ := persistence.Create("", tf.Dummy{Key: "Key 1", Content: "Content 1"})
+		moment1 := nextMoment()
+		dummy1.Content = "Content 2"
+		persistence.Update("", dummy1)
+		moment2 := nextMoment()
+		dummy2, _ := persistence.Create("", tf.Dummy{Key: "Key 2", Content: "Content 3"})
+		moment3 := nextMoment()
+		persistence.DeleteById("", dummy1.Id)
+		moment4 := nextMoment()
+
+		item, err := persistence.GetOneByIdAsOf("", dummy1.Id, moment0)
+		assert.Nil(t, err)
+		assert.Equal(t, tf.Dummy{}, item)
+
+		item, err = persistence.GetOneByIdAsOf("", dummy1.Id, moment1)
+		assert.Nil(t, err)
+		assert.Equal(t, "Content 1", item.Content)
+
+		item, err = persistence.GetOneByIdAsOf("", dummy1.Id, moment2)
+		assert.Nil(t, err)
+		assert.Equal(t, "Content 2", item.Content)
+
+		item, err = persistence.GetOneByIdAsOf("", dummy1.Id, moment4)
+		assert.Nil(t, err)
+		assert.Equal(t, tf.Dummy{}, item)
+
+		items, err := persistence.GetListByFilterAsOf("", "", "\"key\"", nil, moment3)
+		assert.Nil(t, err)
+		assert.Equal(t, []tf.Dummy{dummy1, dummy2}, items)
+
+		items, err = persistence.GetListByFilterAsOf("", "\"key\"='Key 2'", nil, nil, moment3)
+		assert.Nil(t, err)
+		assert.Equal(t, []tf.Dummy{dummy2}, items)
+
+		items, err = persistence.GetListByFilterAsOf("", "", nil, nil, moment4)
+		assert.Nil(t, err)
+		assert.Equal(t, []tf.Dummy{dummy2}, items)
+
+		// Versions valid now are kept
+		count, err := persistence.PruneVersions("", time.Now())
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, count, int64(2))
+
+		item, err = persistence.GetOneByIdAsOf("", dummy1.Id, moment2)
+		assert.Nil(t, err)
+		assert.Equal(t, tf.Dummy{}, item)
+
+		item, err = persistence.GetOneByIdAsOf("", dummy2.Id, time.Now())
+		assert.Nil(t, err)
+		assert.Equal(t, dummy2, item)
+	})
+
+	t.Run("Json", func(t *testing.T) {
+		persistence := NewDummyRefGenericJsonSqlitePersistence()
+		persistence.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.database", sqliteDatabase,
+			"table", "dummies_temporal_json",
+			"options.temporal", true,
+			"options.soft_delete", true,
+		))
+
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+		persistence.Clear("")
+
+		dummy, _ := persistence.Create("", &tf.Dummy{Key: "Key 1", Content: "Content 1"})
+		moment1 := nextMoment()
+		dummy.Content = "Content 2"
+		persistence.Set("", dummy)
+		moment2 := nextMoment()
+		persistence.DeleteById("", dummy.Id)
+		moment3 := nextMoment()
+
+		item, err := persistence.GetOneByIdAsOf("", dummy.Id, moment1)
+		assert.Nil(t, err)
+		assert.Equal(t, "Content 1", item.Content)
+
+		item, err = persistence.GetOneByIdAsOf("", dummy.Id, moment2)
+		assert.Nil(t, err)
+		assert.Equal(t, "Content 2", item.Content)
+
+		// Soft-deleted versions are hidden unless deleted items are requested
+		item, err = persistence.GetOneByIdAsOf("", dummy.Id, moment3)
+		assert.Nil(t, err)
+		assert.Nil(t, item)
+
+		item, err = persistence.WithDeleted().GetOneByIdAsOf("", dummy.Id, moment3)
+		assert.Nil(t, err)
+		assert.Equal(t, "Content 2", item.Content)
+	})
+}
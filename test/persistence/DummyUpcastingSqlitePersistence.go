@@ -0,0 +1,43 @@
+package test
+
+import (
+	"errors"
+	"strings"
+
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+)
+
+// Version 1 keeps full names, version 2 splits them, version 3 adds tags.
+type DummyUpcastingSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[map[string]interface{}, string]
+}
+
+func NewDummyUpcastingSqlitePersistence() *DummyUpcastingSqlitePersistence {
+	c := &DummyUpcastingSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[map[string]interface{}, string](c, "dummies_upcasting")
+	c.RegisterUpcaster(1, func(doc map[string]interface{}) (map[string]interface{}, error) {
+		name, ok := doc["name"].(string)
+		if !ok {
+			return nil, errors.New("name is missing")
+		}
+		parts := strings.SplitN(name, " ", 2)
+		doc["first_name"] = parts[0]
+		if len(parts) > 1 {
+			doc["last_name"] = parts[1]
+		}
+		delete(doc, "name")
+		return doc, nil
+	})
+	c.RegisterUpcaster(2, func(doc map[string]interface{}) (map[string]interface{}, error) {
+		if _, ok := doc["tags"]; !ok {
+			doc["tags"] = []interface{}{}
+		}
+		return doc, nil
+	})
+	return c
+}
+
+func (c *DummyUpcastingSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+}
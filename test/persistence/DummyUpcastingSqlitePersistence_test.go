@@ -0,0 +1,109 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyUpcastingSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyUpcastingSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.auto_migrate", true,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+	assert.Equal(t, 3, persistence.DocVersion())
+
+	// Documents written before versioning and by older versions
+	_, err = persistence.Client.Exec("INSERT INTO dummies_upcasting (id, data, doc_version) VALUES " +
+		"('1', '{\"id\":\"1\",\"name\":\"John Smith\"}', NULL), " +
+		"('2', '{\"id\":\"2\",\"first_name\":\"Jane\",\"last_name\":\"Doe\",\"tags\":[\"admin\"]}', 2), " +
+		"('3', '{\"id\":\"3\",\"name\":\"Bob Brown\"}', 1)")
+	assert.Nil(t, err)
+
+	item, err := persistence.GetOneById("", "1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "1", "first_name": "John", "last_name": "Smith", "tags": []interface{}{}}, item)
+	item, err = persistence.GetOneById("", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"admin"}, item["tags"])
+
+	// Partial updates upgrade documents first
+	item, err = persistence.UpdatePartially("", "3", cdata.NewAnyValueMapFromTuples("last_name", "Green"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "3", "first_name": "Bob", "last_name": "Green", "tags": []interface{}{}}, item)
+
+	// New documents are written in the current version
+	_, err = persistence.Create("", map[string]interface{}{"id": "4", "first_name": "Ann", "tags": []interface{}{}})
+	assert.Nil(t, err)
+
+	count, err := persistence.UpgradeDocuments("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	var outdated int
+	err = persistence.Client.QueryRow("SELECT COUNT(*) FROM dummies_upcasting WHERE doc_version IS NOT 3").Scan(&outdated)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, outdated)
+
+	var data string
+	err = persistence.Client.QueryRow("SELECT data FROM dummies_upcasting WHERE id='1'").Scan(&data)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":"1","first_name":"John","last_name":"Smith","tags":[]}`, data)
+
+	count, err = persistence.UpgradeDocuments("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Failed upcasts are reported and leave documents unchanged
+	_, err = persistence.Client.Exec("INSERT INTO dummies_upcasting (id, data, doc_version) VALUES ('5', '{\"id\":\"5\"}', 1)")
+	assert.Nil(t, err)
+	_, err = persistence.UpgradeDocuments("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "UPCAST_FAILED", err.(*cerr.ApplicationError).Code)
+	_, err = persistence.DeleteById("", "5")
+	assert.Nil(t, err)
+}
+
+func TestDummyUpcastingSqlitePersistenceUpgradeJob(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	persistence := NewDummyUpcastingSqlitePersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+		"options.auto_migrate", true,
+		"options.upgrade_interval", 20,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+	persistence.Clear("")
+
+	_, err = persistence.Client.Exec("INSERT INTO dummies_upcasting (id, data) VALUES ('1', '{\"id\":\"1\",\"name\":\"John Smith\"}')")
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		var version *int
+		err := persistence.Client.QueryRow("SELECT doc_version FROM dummies_upcasting WHERE id='1'").Scan(&version)
+		return err == nil && version != nil && *version == 3
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	gpersist "github.com/pip-services3-go/pip-services3-sqlite-go/persistence/generic"
+)
+
+type DummyVersioned struct {
+	Id      string `json:"id"`
+	Key     string `json:"key"`
+	Content string `json:"content"`
+	Version int64  `json:"version"`
+}
+
+type DummyVersionedSqlitePersistence struct {
+	gpersist.IdentifiableSqlitePersistence[DummyVersioned, string]
+}
+
+func NewDummyVersionedSqlitePersistence() *DummyVersionedSqlitePersistence {
+	c := &DummyVersionedSqlitePersistence{}
+	c.IdentifiableSqlitePersistence = *gpersist.InheritIdentifiableSqlitePersistence[DummyVersioned, string](c, "dummies_versioned")
+	return c
+}
+
+func (c *DummyVersionedSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureSchema("CREATE TABLE \"" + c.TableName + "\" (\"id\" VARCHAR(32) PRIMARY KEY, \"key\" VARCHAR(50), \"content\" TEXT, \"version\" INTEGER NOT NULL DEFAULT 1)")
+}
+
+type DummyVersionedJsonSqlitePersistence struct {
+	gpersist.IdentifiableJsonSqlitePersistence[DummyVersioned, string]
+}
+
+func NewDummyVersionedJsonSqlitePersistence() *DummyVersionedJsonSqlitePersistence {
+	c := &DummyVersionedJsonSqlitePersistence{}
+	c.IdentifiableJsonSqlitePersistence = *gpersist.InheritIdentifiableJsonSqlitePersistence[DummyVersioned, string](c, "dummies_versioned_json")
+	return c
+}
+
+func (c *DummyVersionedJsonSqlitePersistence) DefineSchema() {
+	c.ClearSchema()
+	c.EnsureTable("", "")
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type iDummyVersionedPersistence interface {
+	Clear(correlationId string) error
+	Create(correlationId string, item DummyVersioned) (DummyVersioned, error)
+	Update(correlationId string, item DummyVersioned) (DummyVersioned, error)
+	Set(correlationId string, item DummyVersioned) (DummyVersioned, error)
+	UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (DummyVersioned, error)
+}
+
+func testOptimisticLocking(t *testing.T, persistence iDummyVersionedPersistence) {
+	persistence.Clear("")
+
+	dummy, err := persistence.Create("", DummyVersioned{Key: "Key 1", Content: "Content 1", Version: 7})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), dummy.Version)
+
+	// Update with the current version increments it
+	dummy.Content = "Content 2"
+	updated, err := persistence.Update("", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.Equal(t, "Content 2", updated.Content)
+
+	// Update with a stale version fails
+	dummy.Content = "Content 3"
+	_, err = persistence.Update("", dummy)
+	assert.NotNil(t, err)
+	appErr, ok := err.(*cerr.ApplicationError)
+	assert.True(t, ok)
+	assert.Equal(t, "VERSION_CONFLICT", appErr.Code)
+	assert.Equal(t, cerr.Conflict, appErr.Category)
+	assert.Equal(t, int64(2), appErr.Details["version"])
+
+	_, err = persistence.Set("", dummy)
+	assert.NotNil(t, err)
+
+	_, err = persistence.UpdatePartially("", dummy.Id, cdata.NewAnyValueMapFromTuples("content", "Content 3", "version", 1))
+	assert.NotNil(t, err)
+
+	// Set and partial update with the current version succeed
+	updated.Content = "Content 4"
+	updated, err = persistence.Set("", updated)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), updated.Version)
+
+	updated, err = persistence.UpdatePartially("", dummy.Id, cdata.NewAnyValueMapFromTuples("content", "Content 5", "version", 3))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), updated.Version)
+	assert.Equal(t, "Content 5", updated.Content)
+
+	// Missing items are not reported as conflicts
+	dummy.Id = "unknown"
+	updated, err = persistence.Update("", dummy)
+	assert.Nil(t, err)
+	assert.Equal(t, DummyVersioned{}, updated)
+}
+
+func TestDummyVersionedSqlitePersistence(t *testing.T) {
+	sqliteDatabase := os.Getenv("SQLITE_DB")
+	if sqliteDatabase == "" {
+		sqliteDatabase = "../../data/test.db"
+	}
+
+	dbConfig := cconf.NewConfigParamsFromTuples(
+		"connection.database", sqliteDatabase,
+	)
+
+	t.Run("Columns", func(t *testing.T) {
+		persistence := NewDummyVersionedSqlitePersistence()
+		persistence.Configure(dbConfig)
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		assert.Equal(t, "version", persistence.VersionColumn)
+		testOptimisticLocking(t, persistence)
+	})
+
+	t.Run("Json", func(t *testing.T) {
+		persistence := NewDummyVersionedJsonSqlitePersistence()
+		persistence.Configure(dbConfig)
+		err := persistence.Open("")
+		assert.Nil(t, err)
+		defer persistence.Close("")
+
+		testOptimisticLocking(t, persistence)
+
+		// The version is stored outside of the data column
+		var data string
+		err = persistence.Client.QueryRow("SELECT data FROM dummies_versioned_json").Scan(&data)
+		assert.Nil(t, err)
+		assert.NotContains(t, data, "version")
+	})
+}